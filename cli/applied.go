@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubectl/pkg/cmd/get"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+const (
+	appliedNotUpdated = "not-updated"
+	appliedInSync     = "in-sync"
+	appliedOutdated   = "outdated"
+	appliedOtherVPA   = "other-vpa"
+)
+
+// appliedRow represents the requests of a container of a pod
+// compared with the requests of the pod template and with the
+// recommendation of the VPA.
+type appliedRow struct {
+	Namespace      string              `json:"namespace"`
+	VPA            string              `json:"vpa"`
+	Pod            string              `json:"pod"`
+	Container      string              `json:"container"`
+	Status         string              `json:"status"`
+	UpdatedBy      string              `json:"updatedBy,omitempty"`
+	Updates        []string            `json:"updates,omitempty"`
+	Template       corev1.ResourceList `json:"template"`
+	Live           corev1.ResourceList `json:"live"`
+	Recommendation corev1.ResourceList `json:"recommendation,omitempty"`
+}
+
+// newAppliedRows returns a row for each container of the pods that
+// the VPA has a recommendation for. The template function returns
+// the requests of a container declared in the controller's template.
+// The recommendation of the rows is of the given type, but the status
+// is always computed from the target, which the admission controller
+// applies to the pods.
+func newAppliedRows(
+	v *vpav1.VerticalPodAutoscaler,
+	pods []*corev1.Pod,
+	template func(name string) vpa.ResourceQuantities,
+	rt vpa.RecommendationType,
+) []appliedRow {
+	recs := make(map[string]vpa.ResourceQuantities)
+	targets := make(map[string]vpa.ResourceQuantities)
+	if v.Status.Recommendation != nil {
+		for _, cr := range v.Status.Recommendation.ContainerRecommendations {
+			recs[cr.ContainerName] = vpa.ContainerRecommendations(cr, rt)
+			targets[cr.ContainerName] = vpa.ContainerRecommendations(cr, vpa.RecommendationTarget)
+		}
+	}
+	var rows []appliedRow
+
+	for _, pod := range pods {
+		pu, err := vpa.ParsePodUpdates(pod)
+		if err != nil {
+			klog.Warningf("couldn't decode VPA annotations of pod %s/%s: %s", pod.Namespace, pod.Name, err)
+		}
+		for _, c := range pod.Spec.Containers {
+			rec, ok := recs[c.Name]
+			if !ok {
+				continue
+			}
+			live := vpa.ResourceQuantities{
+				CPU:    c.Resources.Requests.Cpu(),
+				Memory: c.Resources.Requests.Memory(),
+			}
+			row := appliedRow{
+				Namespace:      v.Namespace,
+				VPA:            v.Name,
+				Pod:            pod.Name,
+				Container:      c.Name,
+				Status:         appliedNotUpdated,
+				Template:       toResourceList(template(c.Name)),
+				Live:           toResourceList(live),
+				Recommendation: toResourceList(rec),
+			}
+			if pu != nil {
+				if updates, ok := pu.Containers[c.Name]; ok && len(updates) != 0 {
+					row.UpdatedBy = pu.VPA
+					row.Updates = updates
+
+					switch {
+					case pu.VPA != v.Name:
+						row.Status = appliedOtherVPA
+					case quantityMatches(live.CPU, targets[c.Name].CPU) && quantityMatches(live.Memory, targets[c.Name].Memory):
+						row.Status = appliedInSync
+					default:
+						row.Status = appliedOutdated
+					}
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Pod != rows[j].Pod {
+			return rows[i].Pod < rows[j].Pod
+		}
+		return rows[i].Container < rows[j].Container
+	})
+	return rows
+}
+
+// quantityMatches returns whether the live quantity equals the
+// recommended quantity, if the VPA recommends one.
+func quantityMatches(live, rec *resource.Quantity) bool {
+	return rec == nil || compareQuantities(live, rec) == 0
+}
+
+// appliedOptions represents the options of the applied command.
+type appliedOptions struct {
+	*CommandOptions
+
+	Output string
+}
+
+// newAppliedCmd returns a new command that compares the requests
+// of the pods with their template and the VPA recommendations.
+func newAppliedCmd(co *CommandOptions, f cmdutil.Factory) *cobra.Command {
+	ao := &appliedOptions{CommandOptions: co}
+
+	cmd := &cobra.Command{
+		Use:   "applied [NAME...] [options]",
+		Short: "Compare the template requests, the live pod requests and the recommendations",
+		Long: `Compare the template requests, the live pod requests and the recommendations.
+
+In Auto, Recreate and Initial modes, the VPA admission controller rewrites
+the requests of the pods it admits, so the requests declared by the workload
+template don't match the requests of its pods. For each container of the
+pods of the targets, this command shows the request of the template, the
+request of the pod, and the recommendation of the VPA. The vpaUpdates and
+vpaObservedContainers annotations of the pods are decoded to report whether
+a pod was updated, and by which VPA.
+
+The status of a container is one of:
+  not-updated  the pod wasn't updated by the admission controller
+  in-sync      the pod requests match the current target recommendation
+  outdated     the pod was updated with a previous target recommendation
+  other-vpa    the pod was updated by another VPA
+
+The status is always computed from the target recommendation, which is the
+one applied by the admission controller, whatever the recommendation type
+shown in the Rec columns.`,
+		Args:                  cobra.ArbitraryArgs,
+		DisableFlagsInUseLine: true,
+		Run:                   ao.Run,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, tc string) ([]string, cobra.ShellCompDirective) {
+			comps := get.CompGetResource(f, cmd, vpaPlural, tc)
+			return comps, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVarP(&ao.Output, flagOutput, flagOutputShorthand, ao.Output,
+		"Output format. One of 'json'")
+
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (ao *appliedOptions) Run(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(ao.Complete(c, args))
+	cmdutil.CheckErr(ao.Validate(c, args))
+	cmdutil.CheckErr(ao.Execute())
+}
+
+// Validate ensure that required options to run the
+// command are set and valid.
+func (ao *appliedOptions) Validate(c *cobra.Command, args []string) error {
+	switch ao.Output {
+	case "", jsonOutput:
+	default:
+		return fmt.Errorf("unsupported output format: %s", ao.Output)
+	}
+	return ao.CommandOptions.Validate(c, args)
+}
+
+// Execute runs the command.
+func (ao *appliedOptions) Execute() error {
+	ctx := context.Background()
+
+	vpas, err := ao.listVPAs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(vpas) == 0 {
+		ao.printNoResources()
+		return nil
+	}
+	rows := make([]appliedRow, 0)
+
+	for _, v := range vpas {
+		if v.Spec.TargetRef == nil {
+			klog.V(4).Infof("vpa %s/%s has no target", v.Namespace, v.Name)
+			continue
+		}
+		tc, err := vpa.NewTargetController(ctx, ao.Client, v.Spec.TargetRef, v.Namespace, ao.Flags.RequestsSource)
+		if err != nil {
+			klog.V(4).Infof("couldn't get target for vpa %s/%s", v.Namespace, v.Name)
+			continue
+		}
+		rows = append(rows, newAppliedRows(v, tc.Pods(), tc.GetTemplateContainerRequests, ao.Flags.RecommendationType)...)
+	}
+	if ao.Output == jsonOutput {
+		enc := json.NewEncoder(ao.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+	if len(rows) == 0 {
+		fmt.Fprintln(ao.Out, "No pods found.")
+		return nil
+	}
+	return printAppliedRows(ao.Out, rows, ao.Flags)
+}
+
+func printAppliedRows(w io.Writer, rows []appliedRow, flags *Flags) error {
+	tw := newKubectlTableWriter(w)
+
+	if !flags.NoHeaders {
+		tw.SetHeader([]string{
+			hdrNamespace, hdrName, "Pod", "Container",
+			"CPU Template", "CPU Live", "CPU Rec",
+			"Memory Template", "Memory Live", "Memory Rec",
+			"Status", "Updates",
+		})
+	}
+	for _, r := range rows {
+		updates := tableUnsetCell
+		if len(r.Updates) != 0 {
+			updates = strings.Join(r.Updates, ", ")
+			if r.Status == appliedOtherVPA {
+				updates = fmt.Sprintf("%s (by %s)", updates, r.UpdatedBy)
+			}
+		}
+		tw.Append([]string{
+			r.Namespace,
+			r.VPA,
+			r.Pod,
+			r.Container,
+			formatQuantity(quantityOf(r.Template, corev1.ResourceCPU)),
+			formatQuantity(quantityOf(r.Live, corev1.ResourceCPU)),
+			formatQuantity(quantityOf(r.Recommendation, corev1.ResourceCPU)),
+			formatQuantity(quantityOf(r.Template, corev1.ResourceMemory)),
+			formatQuantity(quantityOf(r.Live, corev1.ResourceMemory)),
+			formatMemoryRecommendation(quantityOf(r.Recommendation, corev1.ResourceMemory), quantityOf(r.Live, corev1.ResourceMemory)),
+			formatAppliedStatus(r.Status, flags),
+			updates,
+		})
+	}
+	tw.Render()
+
+	return nil
+}
+
+func formatAppliedStatus(status string, flags *Flags) string {
+	if termenv.EnvNoColor() || flags.NoColors {
+		return status
+	}
+	p := termenv.ColorProfile()
+	s := termenv.String(status)
+
+	switch status {
+	case appliedInSync:
+		s = s.Foreground(p.Color("#A8CC8C"))
+	case appliedOutdated:
+		s = s.Foreground(p.Color("#DBAB79"))
+	case appliedOtherVPA:
+		s = s.Foreground(p.Color("#E88388"))
+	}
+	return s.String()
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func TestNewAppliedRows(t *testing.T) {
+	resources := func(cpu, mem string) corev1.ResourceList {
+		return corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(mem),
+		}
+	}
+	newPod := func(name, updates, cpu, mem string) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "app", Resources: corev1.ResourceRequirements{Requests: resources(cpu, mem)}},
+					{Name: "ignored"},
+				},
+			},
+		}
+		if updates != "" {
+			p.Annotations = map[string]string{"vpaUpdates": updates}
+		}
+		return p
+	}
+	v := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "my-vpa"},
+		Status: vpav1.VerticalPodAutoscalerStatus{
+			Recommendation: &vpav1.RecommendedPodResources{
+				ContainerRecommendations: []vpav1.RecommendedContainerResources{
+					{ContainerName: "app", Target: resources("250m", "256Mi"), UpperBound: resources("1", "1Gi")},
+				},
+			},
+		},
+	}
+	pods := []*corev1.Pod{
+		newPod("d", "Pod resources updated by other: container 0: cpu request", "250m", "256Mi"),
+		newPod("c", "Pod resources updated by my-vpa: container 0: cpu request, memory request", "200m", "256Mi"),
+		newPod("b", "Pod resources updated by my-vpa: container 0: cpu request, memory request", "250m", "256Mi"),
+		newPod("a", "", "100m", "128Mi"),
+	}
+	template := func(string) vpa.ResourceQuantities {
+		return vpa.ResourceQuantities{CPU: resource.NewMilliQuantity(100, resource.DecimalSI)}
+	}
+	rows := newAppliedRows(v, pods, template, vpa.RecommendationTarget)
+
+	// The status is computed from the target recommendation,
+	// whatever the type of the recommendation shown.
+	upper := newAppliedRows(v, pods, template, vpa.RecommendationUpperBound)
+	for i := range upper {
+		if upper[i].Status != rows[i].Status {
+			t.Errorf("row %d: got status %s with the upper bound, want %s", i, upper[i].Status, rows[i].Status)
+		}
+	}
+	if got := quantityOf(upper[0].Recommendation, corev1.ResourceCPU); got == nil || got.String() != "1" {
+		t.Errorf("got recommendation %v, want the upper bound", got)
+	}
+
+	want := []struct{ pod, status string }{
+		{"a", appliedNotUpdated},
+		{"b", appliedInSync},
+		{"c", appliedOutdated},
+		{"d", appliedOtherVPA},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		if rows[i].Pod != w.pod || rows[i].Status != w.status {
+			t.Errorf("row %d: got %s/%s, want %s/%s", i, rows[i].Pod, rows[i].Status, w.pod, w.status)
+		}
+	}
+	if rows[1].UpdatedBy != "my-vpa" || len(rows[1].Updates) != 2 {
+		t.Errorf("unexpected updates: %s %v", rows[1].UpdatedBy, rows[1].Updates)
+	}
+}
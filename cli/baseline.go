@@ -0,0 +1,355 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubectl/pkg/cmd/get"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	baselineDateLayout       = "2006-01-02"
+	defaultBaselineTolerance = 10.0
+)
+
+// baselineState represents the state of a table row
+// regarding the entries of a baseline file.
+type baselineState int
+
+const (
+	baselineNone     baselineState = iota // no matching entry
+	baselineAccepted                      // the difference is accepted
+	baselineExpired                       // the matching entry has expired
+	baselineDrifted                       // the difference drifted from the entry
+)
+
+// String implements the fmt.Stringer interface.
+func (bs baselineState) String() string {
+	switch bs {
+	case baselineAccepted:
+		return "accepted"
+	case baselineExpired:
+		return "expired"
+	case baselineDrifted:
+		return "drifted"
+	}
+	return ""
+}
+
+// baseline represents a file of known and accepted differences
+// between the recommendations of VPA resources and the requests
+// of their target's pods.
+type baseline struct {
+	// Tolerance is the maximum drift, in percentage points,
+	// between the recorded and the current differences for
+	// an entry to remain accepted.
+	Tolerance *float64        `json:"tolerance,omitempty"`
+	Entries   []baselineEntry `json:"entries"`
+}
+
+// baselineEntry represents the accepted differences of a VPA
+// resource, or one of its containers if the name is set.
+type baselineEntry struct {
+	Namespace     string                          `json:"namespace"`
+	Name          string                          `json:"name"`
+	Container     string                          `json:"container,omitempty"`
+	Differences   map[corev1.ResourceName]float64 `json:"differences,omitempty"`
+	Expires       *baselineDate                   `json:"expires,omitempty"`
+	Justification string                          `json:"justification,omitempty"`
+}
+
+// baselineDate is a date that can be decoded from
+// either a full RFC 3339 timestamp or a date only.
+type baselineDate struct {
+	time.Time
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (bd baselineDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bd.Format(baselineDateLayout))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (bd *baselineDate) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	t, err := parseBaselineDate(s)
+	if err != nil {
+		return err
+	}
+	bd.Time = t
+	return nil
+}
+
+func parseBaselineDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(baselineDateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, must be formatted as YYYY-MM-DD or RFC 3339", s)
+	}
+	// A date-only value expires at the end of the day.
+	return t.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+// loadBaseline reads and decodes the baseline file at path.
+func loadBaseline(path string) (*baseline, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read baseline file: %w", err)
+	}
+	bl := &baseline{}
+	if err := yaml.UnmarshalStrict(b, bl); err != nil {
+		return nil, fmt.Errorf("couldn't decode baseline file %s: %w", path, err)
+	}
+	for i, e := range bl.Entries {
+		if e.Namespace == "" || e.Name == "" {
+			return nil, fmt.Errorf("baseline entry #%d must have a namespace and a name", i)
+		}
+	}
+	return bl, nil
+}
+
+func (bl *baseline) tolerance() float64 {
+	if bl.Tolerance != nil {
+		return *bl.Tolerance
+	}
+	return defaultBaselineTolerance
+}
+
+func (bl *baseline) lookup(namespace, name, container string) *baselineEntry {
+	for i, e := range bl.Entries {
+		if e.Namespace == namespace && e.Name == name && e.Container == container {
+			return &bl.Entries[i]
+		}
+	}
+	return nil
+}
+
+// state returns the state of a row that matches the entry.
+// The differences of the named resources are compared, if
+// both the entry and the row have one.
+func (e *baselineEntry) state(row *tableRow, names []corev1.ResourceName, now time.Time, tolerance float64) baselineState {
+	if e.Expires != nil && now.After(e.Expires.Time) {
+		return baselineExpired
+	}
+	for _, name := range names {
+		recorded, ok := e.Differences[name]
+		current := row.Differences[name]
+		if !ok || current == nil {
+			continue
+		}
+		if math.Abs(*current-recorded) > tolerance {
+			return baselineDrifted
+		}
+	}
+	return baselineAccepted
+}
+
+func (e *baselineEntry) String() string {
+	s := fmt.Sprintf("%s/%s", e.Namespace, e.Name)
+	if e.Container != "" {
+		s = fmt.Sprintf("%s (container %s)", s, e.Container)
+	}
+	return s
+}
+
+// Apply sets the baseline state of the rows of the table, from
+// the differences of the named resources. The accepted rows are
+// removed from the returned table, unless keep is true. The
+// container rows of a VPA that have no entry of their own are
+// accepted along with their parent row, and have no state
+// otherwise. An accepted row is kept if one of its container
+// rows isn't accepted.
+func (bl *baseline) Apply(t table, names []corev1.ResourceName, now time.Time, keep bool) table {
+	tol := bl.tolerance()
+	ret := make(table, 0, len(t))
+
+	for _, row := range t {
+		if e := bl.lookup(row.Namespace, row.Name, ""); e != nil {
+			row.Baseline = e.state(row, names, now, tol)
+			warnBaselineState(e, row.Baseline)
+		}
+		children := make([]*tableRow, 0, len(row.Children))
+		unaccepted := false
+
+		for _, child := range row.Children {
+			if e := bl.lookup(row.Namespace, row.Name, child.ContainerName); e != nil {
+				child.Baseline = e.state(child, names, now, tol)
+				warnBaselineState(e, child.Baseline)
+			} else if row.Baseline == baselineAccepted {
+				child.Baseline = baselineAccepted
+			} else {
+				child.Baseline = baselineNone
+			}
+			if child.Baseline != baselineAccepted {
+				unaccepted = true
+			}
+			if child.Baseline != baselineAccepted || keep {
+				children = append(children, child)
+			}
+		}
+		row.Children = children
+
+		if row.Baseline != baselineAccepted || unaccepted || keep {
+			ret = append(ret, row)
+		}
+	}
+	return ret
+}
+
+func warnBaselineState(e *baselineEntry, state baselineState) {
+	switch state {
+	case baselineExpired:
+		klog.Warningf("baseline entry for %s expired on %s", e, e.Expires.Format(baselineDateLayout))
+	case baselineDrifted:
+		klog.Warningf("baseline entry for %s drifted from the recorded differences", e)
+	}
+}
+
+// withoutBaselined returns a copy of the table
+// without the rows accepted by a baseline.
+func (t table) withoutBaselined() table {
+	ret := make(table, 0, len(t))
+	for _, row := range t {
+		if row.Baseline != baselineAccepted {
+			ret = append(ret, row)
+		}
+	}
+	return ret
+}
+
+// newBaseline returns a baseline that records the current
+// differences of the named resources of all rows of the tables.
+func newBaseline(tables []table, names []corev1.ResourceName, expires *baselineDate, justification string) *baseline {
+	bl := &baseline{}
+
+	for _, t := range tables {
+		for _, row := range t {
+			bl.Entries = append(bl.Entries, baselineEntry{
+				Namespace:     row.Namespace,
+				Name:          row.Name,
+				Differences:   baselineDifferences(row, names),
+				Expires:       expires,
+				Justification: justification,
+			})
+			for _, child := range row.Children {
+				bl.Entries = append(bl.Entries, baselineEntry{
+					Namespace:     row.Namespace,
+					Name:          row.Name,
+					Container:     child.ContainerName,
+					Differences:   baselineDifferences(child, names),
+					Expires:       expires,
+					Justification: justification,
+				})
+			}
+		}
+	}
+	return bl
+}
+
+// baselineDifferences returns the differences of the
+// named resources of the row, if it has one.
+func baselineDifferences(row *tableRow, names []corev1.ResourceName) map[corev1.ResourceName]float64 {
+	var ret map[corev1.ResourceName]float64
+
+	for _, name := range names {
+		d := row.Differences[name]
+		if d == nil {
+			continue
+		}
+		if ret == nil {
+			ret = make(map[corev1.ResourceName]float64)
+		}
+		ret[name] = *d
+	}
+	return ret
+}
+
+// baselineOptions represents the options of the baseline command.
+type baselineOptions struct {
+	*CommandOptions
+
+	Expires       string
+	Justification string
+}
+
+// newBaselineCmd returns a new command that generates
+// a baseline file from the current state of the VPAs.
+func newBaselineCmd(co *CommandOptions, f cmdutil.Factory) *cobra.Command {
+	bo := &baselineOptions{CommandOptions: co}
+
+	cmd := &cobra.Command{
+		Use:                   "baseline [NAME...] [options]",
+		Short:                 "Generate a baseline file of accepted differences from the current state",
+		Args:                  cobra.ArbitraryArgs,
+		DisableFlagsInUseLine: true,
+		Run:                   bo.Run,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, tc string) ([]string, cobra.ShellCompDirective) {
+			comps := get.CompGetResource(f, cmd, vpaPlural, tc)
+			return comps, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	flags := cmd.Flags()
+
+	flags.StringVar(&bo.Expires, "expires", bo.Expires,
+		"Expiry date of the generated entries, formatted as YYYY-MM-DD")
+	flags.StringVar(&bo.Justification, "justification", bo.Justification,
+		"Justification of the accepted differences recorded with the generated entries")
+	flags.BoolVarP(&co.Flags.ShowContainers, flagShowContainers, flagShowContainersShorthand, co.Flags.ShowContainers,
+		"Record an entry for each container of the VPA resources")
+	flags.StringSliceVar(&co.Flags.Resources, flagResources, co.Flags.Resources,
+		"Comma-separated list of the resources whose differences are recorded")
+
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (bo *baselineOptions) Run(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(bo.Complete(c, args))
+	cmdutil.CheckErr(bo.Validate(c, args))
+	cmdutil.CheckErr(bo.Execute())
+}
+
+// Execute runs the command.
+func (bo *baselineOptions) Execute() error {
+	var expires *baselineDate
+
+	if s := strings.TrimSpace(bo.Expires); s != "" {
+		t, err := parseBaselineDate(s)
+		if err != nil {
+			return err
+		}
+		expires = &baselineDate{Time: t}
+	}
+	vpas, err := bo.listVPAs(context.Background())
+	if err != nil {
+		return err
+	}
+	if len(vpas) == 0 {
+		bo.printNoResources()
+		return nil
+	}
+	bl := newBaseline(bo.newTables(vpas), bo.Flags.resourceNames(), expires, bo.Justification)
+
+	b, err := yaml.Marshal(bl)
+	if err != nil {
+		return err
+	}
+	_, err = bo.Out.Write(b)
+
+	return err
+}
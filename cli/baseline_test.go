@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/yaml"
+)
+
+func TestBaselineApply(t *testing.T) {
+	const data = `
+tolerance: 5
+entries:
+  - namespace: athens
+    name: zeus
+    differences:
+      cpu: 150
+    justification: JVM warm-up
+  - namespace: athens
+    name: athena
+    expires: 2022-01-01
+  - namespace: sparta
+    name: hera
+    differences:
+      memory: 20
+  - namespace: sparta
+    name: hermes
+    container: sandals
+    differences:
+      cpu: 300
+  - namespace: delphi
+    name: pythia
+    differences:
+      cpu: 40
+  - namespace: delphi
+    name: pythia
+    container: oracle
+    differences:
+      cpu: 10
+  - namespace: ephesus
+    name: artemis
+    differences:
+      ephemeral-storage: 10
+`
+	bl := &baseline{}
+	if err := yaml.UnmarshalStrict([]byte(data), bl); err != nil {
+		t.Fatal(err)
+	}
+	cpu := func(d float64) map[corev1.ResourceName]*float64 {
+		return map[corev1.ResourceName]*float64{corev1.ResourceCPU: pointer.Float64(d)}
+	}
+	memory := func(d float64) map[corev1.ResourceName]*float64 {
+		return map[corev1.ResourceName]*float64{corev1.ResourceMemory: pointer.Float64(d)}
+	}
+	tbl := table{
+		{Name: "zeus", Namespace: "athens", Differences: cpu(152.5)},
+		{Name: "athena", Namespace: "athens", Differences: cpu(50)},
+		{Name: "hera", Namespace: "sparta", Differences: memory(-40)},
+		{
+			Name:      "hermes",
+			Namespace: "sparta",
+			Children: []*tableRow{
+				{Name: "├─ helmet", ContainerName: "helmet", Differences: cpu(12)},
+				{Name: "└─ sandals", ContainerName: "sandals", Differences: cpu(299)},
+			},
+		},
+		{Name: "apollo", Namespace: "olympia", Differences: cpu(10)},
+		{
+			Name:        "pythia",
+			Namespace:   "delphi",
+			Differences: cpu(41),
+			Children: []*tableRow{
+				{Name: "├─ tripod", ContainerName: "tripod", Differences: cpu(5)},
+				{Name: "└─ oracle", ContainerName: "oracle", Differences: cpu(60)},
+			},
+		},
+		{
+			Name:        "artemis",
+			Namespace:   "ephesus",
+			Differences: map[corev1.ResourceName]*float64{corev1.ResourceEphemeralStorage: pointer.Float64(50)},
+		},
+	}
+	names := []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage}
+	now := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("keep", func(t *testing.T) {
+		ret := bl.Apply(tbl, names, now, true)
+		if len(ret) != len(tbl) {
+			t.Fatalf("got %d rows, want %d", len(ret), len(tbl))
+		}
+		for i, want := range []baselineState{
+			baselineAccepted,
+			baselineExpired,
+			baselineDrifted,
+			baselineNone,
+			baselineNone,
+			baselineAccepted,
+			baselineDrifted,
+		} {
+			if got := ret[i].Baseline; got != want {
+				t.Errorf("row %s: got state %q, want %q", ret[i].Name, got, want)
+			}
+		}
+		hermes := ret[3]
+		if got := hermes.Children[0].Baseline; got != baselineNone {
+			t.Errorf("got state %q for container helmet, want none", got)
+		}
+		if got := hermes.Children[1].Baseline; got != baselineAccepted {
+			t.Errorf("got state %q for container sandals, want accepted", got)
+		}
+		pythia := ret[5]
+		if got := pythia.Children[0].Baseline; got != baselineAccepted {
+			t.Errorf("got state %q for container tripod, want accepted along with its parent", got)
+		}
+		if got := pythia.Children[1].Baseline; got != baselineDrifted {
+			t.Errorf("got state %q for container oracle, want drifted", got)
+		}
+		if n := len(ret.withoutBaselined()); n != 5 {
+			t.Errorf("got %d rows without baselined, want 5", n)
+		}
+	})
+	t.Run("hide", func(t *testing.T) {
+		ret := bl.Apply(tbl, names, now, false)
+		if len(ret) != 6 {
+			t.Fatalf("got %d rows, want 6", len(ret))
+		}
+		for _, row := range ret {
+			if row.Name == "zeus" {
+				t.Errorf("expected accepted row to be removed")
+			}
+			if row.Name == "hermes" && len(row.Children) != 1 {
+				t.Errorf("expected accepted container row to be removed")
+			}
+			// The accepted row is kept to surface
+			// its drifted container row.
+			if row.Name == "pythia" && (len(row.Children) != 1 || row.Children[0].ContainerName != "oracle") {
+				t.Errorf("expected only the drifted container row of the accepted row")
+			}
+		}
+	})
+}
+
+func TestParseBaselineDate(t *testing.T) {
+	d, err := parseBaselineDate("2022-03-04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.After(time.Date(2022, 3, 4, 23, 59, 0, 0, time.UTC)) {
+		t.Errorf("expected date-only value to expire at the end of the day, got %s", d)
+	}
+	if _, err := parseBaselineDate("04/03/2022"); err == nil {
+		t.Error("expected an error for an invalid date")
+	}
+}
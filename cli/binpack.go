@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// binSize represents the CPU, in millicores, and the
+// memory, in bytes, of a node or of the requests of a pod.
+type binSize struct {
+	cpu, mem int64
+}
+
+func newBinSize(rl corev1.ResourceList) binSize {
+	return binSize{
+		cpu: rl.Cpu().MilliValue(),
+		mem: rl.Memory().Value(),
+	}
+}
+
+func (b binSize) fits(in binSize) bool {
+	return b.cpu <= in.cpu && b.mem <= in.mem
+}
+
+// binPack simulates the scheduling of the pods with the given
+// requests on the nodes with the given allocatable resources,
+// with a first-fit decreasing strategy. The largest nodes are
+// filled first and, once no unused node fits a pod, a new node
+// as large as the largest node that fits it is added. It returns
+// the number of nodes used, and the number of pods that don't
+// fit in any.
+func binPack(nodes, pods []binSize) (count, unfit int) {
+	if len(nodes) == 0 {
+		return 0, len(pods)
+	}
+	var maxSize binSize
+	for _, n := range nodes {
+		if n.cpu > maxSize.cpu {
+			maxSize.cpu = n.cpu
+		}
+		if n.mem > maxSize.mem {
+			maxSize.mem = n.mem
+		}
+	}
+	ns := make([]binSize, len(nodes))
+	copy(ns, nodes)
+	sort.SliceStable(ns, func(i, j int) bool {
+		return ns[i].relativeSize(maxSize) > ns[j].relativeSize(maxSize)
+	})
+	largest := ns[0]
+
+	ps := make([]binSize, len(pods))
+	copy(ps, pods)
+	sort.SliceStable(ps, func(i, j int) bool {
+		return dominantShare(ps[i], largest) > dominantShare(ps[j], largest)
+	})
+	// free holds the resources left on the nodes in use,
+	// and used marks the nodes of the list in use.
+	var free []binSize
+	used := make([]bool, len(ns))
+
+	for _, p := range ps {
+		if !fitsAny(p, ns) {
+			unfit++
+			continue
+		}
+		placed := false
+		for i := range free {
+			if p.fits(free[i]) {
+				free[i].cpu -= p.cpu
+				free[i].mem -= p.mem
+				placed = true
+				break
+			}
+		}
+		if placed {
+			continue
+		}
+		// Use the first unused node that fits the pod, or
+		// a new node as large as the largest that fits it.
+		next := -1
+		for i, n := range ns {
+			if !used[i] && p.fits(n) {
+				next = i
+				used[i] = true
+				break
+			}
+		}
+		if next == -1 {
+			for i, n := range ns {
+				if p.fits(n) {
+					next = i
+					break
+				}
+			}
+		}
+		free = append(free, binSize{
+			cpu: ns[next].cpu - p.cpu,
+			mem: ns[next].mem - p.mem,
+		})
+	}
+	return len(free), unfit
+}
+
+// relativeSize returns the sum of the shares of
+// the resources of the reference held by b.
+func (b binSize) relativeSize(ref binSize) float64 {
+	var size float64
+	if ref.cpu > 0 {
+		size += float64(b.cpu) / float64(ref.cpu)
+	}
+	if ref.mem > 0 {
+		size += float64(b.mem) / float64(ref.mem)
+	}
+	return size
+}
+
+// dominantShare returns the highest share of the
+// resources of the node requested by the pod.
+func dominantShare(p, node binSize) float64 {
+	var cpu, mem float64
+	if node.cpu > 0 {
+		cpu = float64(p.cpu) / float64(node.cpu)
+	}
+	if node.mem > 0 {
+		mem = float64(p.mem) / float64(node.mem)
+	}
+	if cpu > mem {
+		return cpu
+	}
+	return mem
+}
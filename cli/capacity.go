@@ -0,0 +1,446 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+const defaultNodePoolLabel = "node.kubernetes.io/instance-type"
+
+// capacityReport represents the capacity of the nodes of
+// a cluster compared with the requests of the workloads,
+// before and after their recommendations are applied.
+type capacityReport struct {
+	Nodes     []capacityNode      `json:"nodes"`
+	Cluster   []capacityResource  `json:"cluster"`
+	Pools     []capacityPool      `json:"pools"`
+	Oversized []capacityOversized `json:"oversized"`
+}
+
+// capacityNode represents the allocatable resources of a node.
+type capacityNode struct {
+	Name        string              `json:"name"`
+	Pool        string              `json:"pool"`
+	Schedulable bool                `json:"schedulable"`
+	Allocatable corev1.ResourceList `json:"allocatable"`
+}
+
+// capacityResource represents the allocatable capacity of the
+// schedulable nodes for a resource, and the total requests and
+// recommendations of the workloads, weighted by their replicas.
+type capacityResource struct {
+	Resource        corev1.ResourceName `json:"resource"`
+	Allocatable     *resource.Quantity  `json:"allocatable"`
+	Requests        *resource.Quantity  `json:"requests"`
+	Recommendations *resource.Quantity  `json:"recommendations"`
+	Difference      *float64            `json:"difference,omitempty"`
+}
+
+// capacityPool represents the estimated number of nodes of a
+// pool needed to schedule the pods of the workloads running on
+// it, with their current requests and with the recommendations.
+type capacityPool struct {
+	Pool   string `json:"pool"`
+	Nodes  int    `json:"nodes"`
+	Pods   int    `json:"pods"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+	// Unfit is the number of pods that don't fit in
+	// any node of the pool with the recommendations.
+	Unfit int `json:"unfit,omitempty"`
+}
+
+// capacityOversized represents a workload whose recommendation
+// doesn't fit in the allocatable resources of any node.
+type capacityOversized struct {
+	Namespace      string                `json:"namespace"`
+	VPA            string                `json:"vpa"`
+	Target         string                `json:"target"`
+	Recommendation corev1.ResourceList   `json:"recommendation"`
+	Exceeds        []corev1.ResourceName `json:"exceeds"`
+}
+
+// capacityWorkload represents the per-pod requests and
+// recommendations of the target of a VPA, and the nodes
+// its pods are scheduled on.
+type capacityWorkload struct {
+	Namespace       string
+	VPA             string
+	Target          string
+	Replicas        int32
+	Requests        vpa.ResourceQuantities
+	Recommendations vpa.ResourceQuantities
+	NodeNames       []string
+}
+
+// newCapacityWorkloads returns the workloads of the rows of the table.
+func newCapacityWorkloads(t table) []capacityWorkload {
+	ret := make([]capacityWorkload, 0, len(t))
+
+	for _, row := range t {
+		w := capacityWorkload{
+			Namespace:       row.Namespace,
+			VPA:             row.Name,
+			Target:          row.TargetName,
+			Replicas:        row.TargetReplicas,
+			Requests:        row.Requests,
+			Recommendations: row.Recommendations,
+		}
+		if row.Target != nil {
+			for _, pod := range row.Target.Pods() {
+				w.NodeNames = append(w.NodeNames, pod.Spec.NodeName)
+			}
+		}
+		ret = append(ret, w)
+	}
+	return ret
+}
+
+// newCapacityReport returns the capacity report of the nodes
+// for the workloads. The pool of a node is the value of the
+// given label. The cluster totals are computed for the named
+// resources, and the workloads are packed on the nodes from
+// their CPU and memory.
+func newCapacityReport(nodes []*corev1.Node, workloads []capacityWorkload, names []corev1.ResourceName, poolLabel string) capacityReport {
+	r := capacityReport{
+		Nodes:     make([]capacityNode, 0, len(nodes)),
+		Pools:     make([]capacityPool, 0),
+		Oversized: make([]capacityOversized, 0),
+	}
+	var (
+		allocatable vpa.ResourceQuantities
+		requests    vpa.ResourceQuantities
+		recs        vpa.ResourceQuantities
+		sizes       []binSize
+		nodePools   = make(map[string]string)
+		poolSizes   = make(map[string][]binSize)
+	)
+	// Sort a copy to leave the order of the caller's slice.
+	nodes = append([]*corev1.Node(nil), nodes...)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	for _, n := range nodes {
+		pool, ok := n.Labels[poolLabel]
+		if !ok || pool == "" {
+			pool = groupNone
+		}
+		cn := capacityNode{
+			Name:        n.Name,
+			Pool:        pool,
+			Schedulable: !n.Spec.Unschedulable,
+			Allocatable: n.Status.Allocatable,
+		}
+		r.Nodes = append(r.Nodes, cn)
+
+		if !cn.Schedulable {
+			continue
+		}
+		nodePools[n.Name] = pool
+		size := newBinSize(n.Status.Allocatable)
+		sizes = append(sizes, size)
+		poolSizes[pool] = append(poolSizes[pool], size)
+
+		for _, name := range names {
+			if q, ok := n.Status.Allocatable[name]; ok {
+				addQuantity(&allocatable, name, &q)
+			}
+		}
+	}
+	before := make(map[string][]binSize)
+	after := make(map[string][]binSize)
+
+	for _, w := range workloads {
+		replicas := int64(w.Replicas)
+		for _, name := range names {
+			addQuantity(&requests, name, multiplyQuantity(w.Requests.Get(name), replicas))
+			// Like the bin packing, the resources without
+			// recommendation count with their current requests.
+			rec := recommendationOrRequest(w.Recommendations, w.Requests, name)
+			addQuantity(&recs, name, multiplyQuantity(rec, replicas))
+		}
+		req := newBinSize(toResourceList(w.Requests))
+		rec := newBinSize(toResourceList(w.Recommendations))
+		hasRec := rec.cpu != 0 || rec.mem != 0
+
+		// The resources without recommendation
+		// keep their current requests.
+		if rec.cpu == 0 {
+			rec.cpu = req.cpu
+		}
+		if rec.mem == 0 {
+			rec.mem = req.mem
+		}
+		if hasRec && len(sizes) != 0 && !fitsAny(rec, sizes) {
+			r.Oversized = append(r.Oversized, capacityOversized{
+				Namespace:      w.Namespace,
+				VPA:            w.VPA,
+				Target:         w.Target,
+				Recommendation: toResourceList(w.Recommendations),
+				Exceeds:        exceededResources(rec, sizes),
+			})
+		}
+		for _, name := range w.NodeNames {
+			pool, ok := nodePools[name]
+			if !ok {
+				// Unscheduled pods, and pods on nodes
+				// that are cordoned or not selected.
+				continue
+			}
+			before[pool] = append(before[pool], req)
+			after[pool] = append(after[pool], rec)
+		}
+	}
+	for _, name := range names {
+		cr := capacityResource{
+			Resource:        name,
+			Allocatable:     allocatable.Get(name),
+			Requests:        requests.Get(name),
+			Recommendations: recs.Get(name),
+		}
+		cr.Difference = vpa.DiffQuantitiesAsPercent(cr.Requests, cr.Recommendations)
+		r.Cluster = append(r.Cluster, cr)
+	}
+	pools := make([]string, 0, len(poolSizes))
+	for pool := range poolSizes {
+		pools = append(pools, pool)
+	}
+	sort.Strings(pools)
+
+	for _, pool := range pools {
+		cp := capacityPool{
+			Pool:  pool,
+			Nodes: len(poolSizes[pool]),
+			Pods:  len(before[pool]),
+		}
+		cp.Before, _ = binPack(poolSizes[pool], before[pool])
+		cp.After, cp.Unfit = binPack(poolSizes[pool], after[pool])
+		r.Pools = append(r.Pools, cp)
+	}
+	return r
+}
+
+func fitsAny(b binSize, nodes []binSize) bool {
+	for _, n := range nodes {
+		if b.fits(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// exceededResources returns the resources of b that exceed
+// the allocatable of the largest node, or both CPU and memory
+// if no single node is large enough for both.
+func exceededResources(b binSize, nodes []binSize) []corev1.ResourceName {
+	var maxSize binSize
+	for _, n := range nodes {
+		if n.cpu > maxSize.cpu {
+			maxSize.cpu = n.cpu
+		}
+		if n.mem > maxSize.mem {
+			maxSize.mem = n.mem
+		}
+	}
+	var ret []corev1.ResourceName
+	if b.cpu > maxSize.cpu {
+		ret = append(ret, corev1.ResourceCPU)
+	}
+	if b.mem > maxSize.mem {
+		ret = append(ret, corev1.ResourceMemory)
+	}
+	if len(ret) == 0 {
+		ret = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+	}
+	return ret
+}
+
+// capacityOptions represents the options of the capacity command.
+type capacityOptions struct {
+	*CommandOptions
+
+	Output        string
+	NodeSelector  string
+	NodePoolLabel string
+}
+
+// newCapacityCmd returns a new command that compares the
+// capacity of the nodes with the requests and recommendations.
+func newCapacityCmd(co *CommandOptions) *cobra.Command {
+	cpo := &capacityOptions{
+		CommandOptions: co,
+		NodePoolLabel:  defaultNodePoolLabel,
+	}
+	cmd := &cobra.Command{
+		Use:   "capacity [options]",
+		Short: "Compare the capacity of the nodes with the requests and recommendations",
+		Long: `Compare the capacity of the nodes with the requests and recommendations.
+
+The allocatable resources of the schedulable nodes are compared with the
+requests of the targets of the VPA resources, multiplied by their replicas,
+before and after the recommendations are applied.
+
+For each node pool, identified by a node label, the pods of the targets
+scheduled on its nodes are packed on as few nodes as possible with a
+first-fit decreasing strategy, to estimate the number of nodes needed
+before and after. The estimates only account for the pods of the targets
+of the VPA resources, not for the other pods of the nodes, such as the
+pods of DaemonSets without a VPA.
+
+The targets whose recommendation doesn't fit in the allocatable resources
+of any node are flagged, as their pods couldn't be scheduled once updated.`,
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		Run:                   cpo.Run,
+	}
+	cmd.Flags().StringVarP(&cpo.Output, flagOutput, flagOutputShorthand, cpo.Output,
+		"Output format. One of 'json'")
+	cmd.Flags().StringVar(&cpo.NodeSelector, "node-selector", cpo.NodeSelector,
+		"Selector (label query) of the nodes to consider")
+	cmd.Flags().StringVar(&cpo.NodePoolLabel, "node-pool-label", cpo.NodePoolLabel,
+		"The label of the nodes that identifies their node pool")
+	cmd.Flags().StringSliceVar(&co.Flags.Resources, flagResources, co.Flags.Resources,
+		"Comma-separated list of the resources to total for the cluster")
+
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (cpo *capacityOptions) Run(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(cpo.Complete(c, args))
+	cmdutil.CheckErr(cpo.Validate(c, args))
+	cmdutil.CheckErr(cpo.Execute())
+}
+
+// Validate ensure that required options to run the
+// command are set and valid.
+func (cpo *capacityOptions) Validate(c *cobra.Command, args []string) error {
+	switch cpo.Output {
+	case "", jsonOutput:
+	default:
+		return fmt.Errorf("unsupported output format: %s", cpo.Output)
+	}
+	if cpo.NodePoolLabel == "" {
+		return fmt.Errorf("node pool label cannot be empty")
+	}
+	return cpo.CommandOptions.Validate(c, args)
+}
+
+// Execute runs the command.
+func (cpo *capacityOptions) Execute() error {
+	ctx := context.Background()
+
+	vpas, err := cpo.listVPAs(ctx)
+	if err != nil {
+		return err
+	}
+	nodes, err := cpo.Client.ListNodes(ctx, cpo.NodeSelector)
+	if err != nil {
+		return err
+	}
+	t := cpo.bindRecommendationsAndRequests(vpas)
+	r := newCapacityReport(nodes, newCapacityWorkloads(t), cpo.Flags.resourceNames(), cpo.NodePoolLabel)
+
+	if cpo.Output == jsonOutput {
+		enc := json.NewEncoder(cpo.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return printCapacityReport(cpo.Out, r, cpo.Flags)
+}
+
+func printCapacityReport(w io.Writer, r capacityReport, flags *Flags) error {
+	if len(r.Nodes) == 0 {
+		fmt.Fprintln(w, "No nodes found.")
+		return nil
+	}
+	tw := newKubectlTableWriter(w)
+	if !flags.NoHeaders {
+		tw.SetHeader([]string{hdrName, "Pool", "Schedulable", "CPU Allocatable", "Memory Allocatable"})
+	}
+	for _, n := range r.Nodes {
+		cpu, mem := n.Allocatable.Cpu(), n.Allocatable.Memory()
+		tw.Append([]string{
+			n.Name,
+			n.Pool,
+			strconv.FormatBool(n.Schedulable),
+			formatQuantity(cpu),
+			formatResourceRecommendation(corev1.ResourceMemory, mem, mem),
+		})
+	}
+	tw.Render()
+	fmt.Fprintln(w)
+
+	tw = newKubectlTableWriter(w)
+	if !flags.NoHeaders {
+		tw.SetHeader([]string{"Resource", "Allocatable", "Requests", "Recommendations", "% Diff"})
+	}
+	for _, cr := range r.Cluster {
+		tw.Append([]string{
+			string(cr.Resource),
+			formatResourceRecommendation(cr.Resource, cr.Allocatable, cr.Allocatable),
+			formatResourceRecommendation(cr.Resource, cr.Requests, cr.Allocatable),
+			formatResourceRecommendation(cr.Resource, cr.Recommendations, cr.Allocatable),
+			formatPercentage(cr.Difference, nil, cr.Resource, flags),
+		})
+	}
+	tw.Render()
+	fmt.Fprintln(w)
+
+	tw = newKubectlTableWriter(w)
+	if !flags.NoHeaders {
+		tw.SetHeader([]string{"Pool", "Nodes", "Pods", "Nodes Before", "Nodes After", "Unfit"})
+	}
+	for _, p := range r.Pools {
+		tw.Append([]string{
+			p.Pool,
+			strconv.Itoa(p.Nodes),
+			strconv.Itoa(p.Pods),
+			strconv.Itoa(p.Before),
+			strconv.Itoa(p.After),
+			strconv.Itoa(p.Unfit),
+		})
+	}
+	tw.Render()
+
+	if len(r.Oversized) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Recommendations larger than the allocatable resources of every node:")
+
+	tw = newKubectlTableWriter(w)
+	if !flags.NoHeaders {
+		tw.SetHeader([]string{hdrNamespace, hdrName, hdrTarget, hdrCPUTarget, hdrMemTarget, "Exceeds"})
+	}
+	for _, o := range r.Oversized {
+		exceeds := make([]string, 0, len(o.Exceeds))
+		for _, name := range o.Exceeds {
+			exceeds = append(exceeds, string(name))
+		}
+		mem := o.Recommendation.Memory()
+		tw.Append([]string{
+			o.Namespace,
+			o.VPA,
+			o.Target,
+			formatQuantity(o.Recommendation.Cpu()),
+			formatResourceRecommendation(corev1.ResourceMemory, mem, mem),
+			strings.Join(exceeds, ", "),
+		})
+	}
+	tw.Render()
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func TestBinPack(t *testing.T) {
+	node := binSize{cpu: 4000, mem: 16 << 30}
+
+	for _, tt := range []struct {
+		name  string
+		nodes []binSize
+		pods  []binSize
+		count int
+		unfit int
+	}{
+		{"empty", []binSize{node}, nil, 0, 0},
+		{"no nodes", nil, []binSize{{cpu: 100}}, 0, 1},
+		{
+			"cpu bound",
+			[]binSize{node, node},
+			[]binSize{{1500, 1 << 30}, {1500, 1 << 30}, {1500, 1 << 30}, {1500, 1 << 30}, {1500, 1 << 30}},
+			3, 0,
+		},
+		{
+			"decreasing",
+			[]binSize{node},
+			[]binSize{{1000, 1 << 30}, {3000, 1 << 30}, {1000, 1 << 30}, {3000, 1 << 30}},
+			2, 0,
+		},
+		{
+			"unfit",
+			[]binSize{node},
+			[]binSize{{5000, 1 << 30}, {1000, 32 << 30}, {1000, 1 << 30}},
+			1, 2,
+		},
+		{
+			// The pod that doesn't fit the next node is
+			// placed on the following node that fits it.
+			"unused node",
+			[]binSize{{cpu: 4000, mem: 5 << 30}, {cpu: 1000, mem: 16 << 30}},
+			[]binSize{{3000, 1 << 30}, {500, 10 << 30}},
+			2, 0,
+		},
+		{
+			// The pod doesn't fit the node with the most CPU,
+			// but fits the node with the most memory, whose
+			// size is used for the new node.
+			"memory node",
+			[]binSize{{cpu: 8000, mem: 20 << 30}, {cpu: 2000, mem: 64 << 30}},
+			[]binSize{{1000, 40 << 30}, {1000, 40 << 30}},
+			2, 0,
+		},
+	} {
+		count, unfit := binPack(tt.nodes, tt.pods)
+		if count != tt.count || unfit != tt.unfit {
+			t.Errorf("%s: got %d nodes and %d unfit, want %d and %d", tt.name, count, unfit, tt.count, tt.unfit)
+		}
+	}
+}
+
+func TestNewCapacityReport(t *testing.T) {
+	newNode := func(name, pool, cpu, mem string, unschedulable bool) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{defaultNodePoolLabel: pool},
+			},
+			Spec: corev1.NodeSpec{Unschedulable: unschedulable},
+			Status: corev1.NodeStatus{
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(mem),
+				},
+			},
+		}
+	}
+	quantities := func(cpu, mem string) vpa.ResourceQuantities {
+		c, m := resource.MustParse(cpu), resource.MustParse(mem)
+		return vpa.ResourceQuantities{CPU: &c, Memory: &m}
+	}
+	nodes := []*corev1.Node{
+		newNode("b", "small", "2", "8Gi", false),
+		newNode("a", "small", "2", "8Gi", false),
+		newNode("c", "large", "8", "32Gi", false),
+		newNode("d", "large", "8", "32Gi", true),
+	}
+	workloads := []capacityWorkload{
+		{
+			Namespace:       "default",
+			VPA:             "web",
+			Target:          "web",
+			Replicas:        4,
+			Requests:        quantities("1", "2Gi"),
+			Recommendations: quantities("250m", "1Gi"),
+			NodeNames:       []string{"a", "a", "b", "b"},
+		},
+		{
+			Namespace:       "default",
+			VPA:             "db",
+			Target:          "db",
+			Replicas:        1,
+			Requests:        quantities("4", "16Gi"),
+			Recommendations: quantities("12", "16Gi"),
+			NodeNames:       []string{"c"},
+		},
+		{
+			Namespace: "default",
+			VPA:       "pending",
+			Target:    "pending",
+			Replicas:  1,
+			Requests:  quantities("100m", "64Mi"),
+			NodeNames: []string{""},
+		},
+	}
+	names := []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage}
+	r := newCapacityReport(nodes, workloads, names, defaultNodePoolLabel)
+
+	if len(r.Nodes) != 4 || r.Nodes[0].Name != "a" || r.Nodes[3].Schedulable {
+		t.Errorf("unexpected nodes: %+v", r.Nodes)
+	}
+	if nodes[0].Name != "b" {
+		t.Errorf("the nodes of the caller were sorted")
+	}
+	if len(r.Cluster) != len(names) || r.Cluster[2].Resource != corev1.ResourceEphemeralStorage {
+		t.Fatalf("unexpected cluster resources: %+v", r.Cluster)
+	}
+	cpu := r.Cluster[0]
+	for _, tt := range []struct {
+		name string
+		got  *resource.Quantity
+		want string
+	}{
+		{"allocatable", cpu.Allocatable, "12"},
+		{"requests", cpu.Requests, "8100m"},
+		// The pending workload without recommendation
+		// counts with its requests.
+		{"recommendations", cpu.Recommendations, "13100m"},
+	} {
+		if tt.got == nil || tt.got.Cmp(resource.MustParse(tt.want)) != 0 {
+			t.Errorf("cpu %s: got %v, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+	want := []capacityPool{
+		{Pool: "large", Nodes: 1, Pods: 1, Before: 1, After: 0, Unfit: 1},
+		{Pool: "small", Nodes: 2, Pods: 4, Before: 2, After: 1},
+	}
+	if len(r.Pools) != len(want) {
+		t.Fatalf("got %d pools, want %d", len(r.Pools), len(want))
+	}
+	for i := range want {
+		if r.Pools[i] != want[i] {
+			t.Errorf("pool #%d: got %+v, want %+v", i, r.Pools[i], want[i])
+		}
+	}
+	if len(r.Oversized) != 1 {
+		t.Fatalf("got %d oversized recommendations, want 1", len(r.Oversized))
+	}
+	if o := r.Oversized[0]; o.VPA != "db" || len(o.Exceeds) != 1 || o.Exceeds[0] != corev1.ResourceCPU {
+		t.Errorf("unexpected oversized recommendation: %+v", o)
+	}
+}
@@ -0,0 +1,371 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"k8s.io/kubectl/pkg/cmd/get"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/internal/humanize"
+	"github.com/wI2L/kubectl-vpa-recommendation/stats"
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+const (
+	checkpointOK      = "ok"
+	checkpointStale   = "stale"
+	checkpointMissing = "missing"
+)
+
+const flagStaleAfter = "stale-after"
+
+// checkpointRow represents the checkpoint of a container
+// of a VPA resource, or its absence.
+type checkpointRow struct {
+	Namespace         string                `json:"namespace"`
+	VPA               string                `json:"vpa"`
+	Container         string                `json:"container,omitempty"`
+	Checkpoint        string                `json:"checkpoint,omitempty"`
+	Status            string                `json:"status"`
+	FirstSample       *metav1.Time          `json:"firstSample,omitempty"`
+	LastSample        *metav1.Time          `json:"lastSample,omitempty"`
+	LastUpdate        *metav1.Time          `json:"lastUpdate,omitempty"`
+	Samples           int                   `json:"samples"`
+	CPUTotalWeight    float64               `json:"cpuTotalWeight"`
+	MemoryTotalWeight float64               `json:"memoryTotalWeight"`
+	CPUBuckets        []vpa.HistogramBucket `json:"cpuBuckets,omitempty"`
+	MemoryBuckets     []vpa.HistogramBucket `json:"memoryBuckets,omitempty"`
+}
+
+// newCheckpointRows returns a row for each checkpoint of the VPA,
+// and for each container of its recommendation that has none. A
+// checkpoint that wasn't updated for longer than staleAfter is
+// stale. A single row is returned if the VPA has no checkpoint
+// and no recommendation.
+func newCheckpointRows(
+	v *vpav1.VerticalPodAutoscaler,
+	checkpoints []*vpav1.VerticalPodAutoscalerCheckpoint,
+	now time.Time,
+	staleAfter time.Duration,
+	withBuckets bool,
+) []checkpointRow {
+	var rows []checkpointRow
+
+	seen := make(map[string]bool)
+	for _, cp := range vpa.CheckpointsOf(v.Name, checkpoints) {
+		st := cp.Status
+		row := checkpointRow{
+			Namespace:         v.Namespace,
+			VPA:               v.Name,
+			Container:         cp.Spec.ContainerName,
+			Checkpoint:        cp.Name,
+			Status:            checkpointOK,
+			Samples:           st.TotalSamplesCount,
+			CPUTotalWeight:    st.CPUHistogram.TotalWeight,
+			MemoryTotalWeight: st.MemoryHistogram.TotalWeight,
+			FirstSample:       timeOrNil(st.FirstSampleStart),
+			LastSample:        timeOrNil(st.LastSampleStart),
+			LastUpdate:        timeOrNil(st.LastUpdateTime),
+		}
+		if row.LastUpdate == nil || now.Sub(row.LastUpdate.Time) > staleAfter {
+			row.Status = checkpointStale
+		}
+		if withBuckets {
+			row.CPUBuckets = vpa.CheckpointBuckets(st.CPUHistogram, vpa.CPUHistogramOptions)
+			row.MemoryBuckets = vpa.CheckpointBuckets(st.MemoryHistogram, vpa.MemoryHistogramOptions)
+		}
+		seen[row.Container] = true
+		rows = append(rows, row)
+	}
+	if v.Status.Recommendation != nil {
+		for _, cr := range v.Status.Recommendation.ContainerRecommendations {
+			if !seen[cr.ContainerName] {
+				rows = append(rows, checkpointRow{
+					Namespace: v.Namespace,
+					VPA:       v.Name,
+					Container: cr.ContainerName,
+					Status:    checkpointMissing,
+				})
+			}
+		}
+	}
+	if len(rows) == 0 {
+		rows = append(rows, checkpointRow{
+			Namespace: v.Namespace,
+			VPA:       v.Name,
+			Status:    checkpointMissing,
+		})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].Container < rows[j].Container
+	})
+	return rows
+}
+
+func timeOrNil(t metav1.Time) *metav1.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// checkpointsOptions represents the options of the checkpoints command.
+type checkpointsOptions struct {
+	*CommandOptions
+
+	Output        string
+	StaleAfter    time.Duration
+	ShowHistogram bool
+}
+
+// newCheckpointsCmd returns a new command that lists
+// the checkpoints of the recommender for VPA resources.
+func newCheckpointsCmd(co *CommandOptions, f cmdutil.Factory) *cobra.Command {
+	cko := &checkpointsOptions{
+		CommandOptions: co,
+		StaleAfter:     time.Hour,
+	}
+	cmd := &cobra.Command{
+		Use:   "checkpoints [NAME...] [options]",
+		Short: "List the checkpoints of the recommender for VPA resources",
+		Long: `List the checkpoints of the recommender for VPA resources.
+
+The recommender periodically saves the histograms of the CPU usage and
+memory peaks it aggregated for each container of a VPA resource in a
+VerticalPodAutoscalerCheckpoint resource, from which it restores its state
+when it restarts. For each container, this command shows how much history
+the checkpoint holds, the number of samples and the total weight of the
+histograms, and when the checkpoint was last updated.
+
+The status of a checkpoint is one of:
+  ok       the checkpoint was updated recently
+  stale    the checkpoint wasn't updated for longer than --stale-after
+  missing  the container, or the VPA, has no checkpoint`,
+		Args:                  cobra.ArbitraryArgs,
+		DisableFlagsInUseLine: true,
+		Run:                   cko.Run,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, tc string) ([]string, cobra.ShellCompDirective) {
+			comps := get.CompGetResource(f, cmd, vpaPlural, tc)
+			return comps, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVarP(&cko.Output, flagOutput, flagOutputShorthand, cko.Output,
+		"Output format. One of 'json'")
+	cmd.Flags().DurationVar(&cko.StaleAfter, flagStaleAfter, cko.StaleAfter,
+		"The duration after which a checkpoint that wasn't updated is stale")
+	cmd.Flags().BoolVar(&cko.ShowHistogram, flagShowHistogram, cko.ShowHistogram,
+		"Show the CPU and memory histograms stored in the checkpoints")
+
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (cko *checkpointsOptions) Run(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(cko.Complete(c, args))
+	cmdutil.CheckErr(cko.Validate(c, args))
+	cmdutil.CheckErr(cko.Execute())
+}
+
+// Validate ensure that required options to run the
+// command are set and valid.
+func (cko *checkpointsOptions) Validate(c *cobra.Command, args []string) error {
+	switch cko.Output {
+	case "", jsonOutput:
+	default:
+		return fmt.Errorf("unsupported output format: %s", cko.Output)
+	}
+	if cko.StaleAfter <= 0 {
+		return fmt.Errorf("--%s must be strictly positive", flagStaleAfter)
+	}
+	return cko.CommandOptions.Validate(c, args)
+}
+
+// Execute runs the command.
+func (cko *checkpointsOptions) Execute() error {
+	ctx := context.Background()
+
+	vpas, err := cko.listVPAs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(vpas) == 0 {
+		cko.printNoResources()
+		return nil
+	}
+	ns := cko.Namespace
+	if cko.Flags.AllNamespaces {
+		ns = ""
+	}
+	checkpoints, err := cko.Client.ListVPACheckpoints(ctx, ns)
+	if err != nil {
+		return err
+	}
+	byNamespace := make(map[string][]*vpav1.VerticalPodAutoscalerCheckpoint)
+	for _, cp := range checkpoints {
+		byNamespace[cp.Namespace] = append(byNamespace[cp.Namespace], cp)
+	}
+	sort.SliceStable(vpas, func(i, j int) bool {
+		if vpas[i].Namespace != vpas[j].Namespace {
+			return vpas[i].Namespace < vpas[j].Namespace
+		}
+		return vpas[i].Name < vpas[j].Name
+	})
+	now := time.Now()
+	rows := make([]checkpointRow, 0, len(vpas))
+
+	for _, v := range vpas {
+		rows = append(rows, newCheckpointRows(v, byNamespace[v.Namespace], now, cko.StaleAfter, cko.ShowHistogram)...)
+	}
+	if cko.Output == jsonOutput {
+		enc := json.NewEncoder(cko.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+	printCheckpointRows(cko.Out, rows, now, cko.Flags)
+
+	if cko.ShowHistogram {
+		return printCheckpointHistograms(cko.Out, rows)
+	}
+	return nil
+}
+
+func printCheckpointRows(w io.Writer, rows []checkpointRow, now time.Time, flags *Flags) {
+	tw := newKubectlTableWriter(w)
+
+	if !flags.NoHeaders {
+		tw.SetHeader([]string{
+			hdrNamespace, hdrName, "Container", "Status", "History",
+			"Samples", "CPU Weight", "Memory Weight", "Last Update",
+		})
+	}
+	for _, r := range rows {
+		tw.Append([]string{
+			r.Namespace,
+			r.VPA,
+			orUnset(r.Container),
+			formatCheckpointStatus(r.Status, flags),
+			formatSince(r.FirstSample, now, ""),
+			formatCheckpointSamples(r),
+			formatCheckpointWeight(r, r.CPUTotalWeight),
+			formatCheckpointWeight(r, r.MemoryTotalWeight),
+			formatSince(r.LastUpdate, now, " ago"),
+		})
+	}
+	tw.Render()
+}
+
+// formatSince formats the duration elapsed since t.
+func formatSince(t *metav1.Time, now time.Time, suffix string) string {
+	if t == nil {
+		return tableUnsetCell
+	}
+	return duration.HumanDuration(now.Sub(t.Time)) + suffix
+}
+
+func formatCheckpointSamples(r checkpointRow) string {
+	if r.Status == checkpointMissing {
+		return tableUnsetCell
+	}
+	return fmt.Sprintf("%d", r.Samples)
+}
+
+func formatCheckpointWeight(r checkpointRow, w float64) string {
+	if r.Status == checkpointMissing {
+		return tableUnsetCell
+	}
+	return fmt.Sprintf("%.2f", w)
+}
+
+func formatCheckpointStatus(status string, flags *Flags) string {
+	if termenv.EnvNoColor() || flags.NoColors {
+		return status
+	}
+	p := termenv.ColorProfile()
+	s := termenv.String(status)
+
+	switch status {
+	case checkpointOK:
+		s = s.Foreground(p.Color("#A8CC8C"))
+	case checkpointStale:
+		s = s.Foreground(p.Color("#DBAB79"))
+	case checkpointMissing:
+		s = s.Foreground(p.Color("#E88388"))
+	}
+	return s.String()
+}
+
+// checkpointHistogramWidth is the width of the
+// longest bar of the rendered histograms.
+const checkpointHistogramWidth = 40
+
+func printCheckpointHistograms(w io.Writer, rows []checkpointRow) error {
+	for _, r := range rows {
+		if len(r.CPUBuckets) == 0 && len(r.MemoryBuckets) == 0 {
+			continue
+		}
+		for _, h := range []struct {
+			name    string
+			buckets []vpa.HistogramBucket
+			label   func(float64) string
+		}{
+			{"CPU usage", r.CPUBuckets, formatCPUBucketBound},
+			{"Memory peaks", r.MemoryBuckets, formatMemoryBucketBound},
+		} {
+			if len(h.buckets) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "\n%s/%s, container %s, %s:\n", r.Namespace, r.VPA, r.Container, h.name)
+
+			label := h.label
+			err := checkpointHistogram(h.buckets).RenderWithLabels(w, checkpointHistogramWidth, func(b stats.Bucket) string {
+				return fmt.Sprintf("[%s, %s)", label(*b.Lower), label(*b.Upper))
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkpointHistogram converts the buckets of a histogram
+// checkpoint to a histogram, whose counts are the weights.
+func checkpointHistogram(buckets []vpa.HistogramBucket) stats.Histogram {
+	h := make(stats.Histogram, 0, len(buckets))
+	for i := range buckets {
+		b := buckets[i]
+		h = append(h, stats.Bucket{
+			Lower: &b.Start,
+			Upper: &b.End,
+			Count: int(b.Weight),
+		})
+	}
+	return h
+}
+
+// formatCPUBucketBound formats a bound of a CPU
+// bucket, expressed in cores, as a quantity.
+func formatCPUBucketBound(v float64) string {
+	return resource.NewMilliQuantity(int64(math.Round(v*1000)), resource.DecimalSI).String()
+}
+
+// formatMemoryBucketBound formats a bound of a memory
+// bucket, expressed in bytes, with binary units.
+func formatMemoryBucketBound(v float64) string {
+	s := humanize.BigIBytes(big.NewInt(int64(math.Round(v))), 1)
+	return strings.ReplaceAll(s, " ", "")
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func TestNewCheckpointRows(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	newCheckpoint := func(container string, updated time.Duration) *vpav1.VerticalPodAutoscalerCheckpoint {
+		cp := &vpav1.VerticalPodAutoscalerCheckpoint{}
+		cp.Name = "app-" + container
+		cp.Spec.VPAObjectName = "app"
+		cp.Spec.ContainerName = container
+		cp.Status.LastUpdateTime = metav1.NewTime(now.Add(-updated))
+		cp.Status.FirstSampleStart = metav1.NewTime(now.Add(-72 * time.Hour))
+		cp.Status.TotalSamplesCount = 100
+		cp.Status.CPUHistogram.BucketWeights = map[int]uint32{3: 10000}
+		return cp
+	}
+	v := newComparedTestVPA("app", "app", nil)
+	v.Status.Recommendation = &vpav1.RecommendedPodResources{
+		ContainerRecommendations: []vpav1.RecommendedContainerResources{
+			{ContainerName: "app"},
+			{ContainerName: "proxy"},
+			{ContainerName: "sidecar"},
+		},
+	}
+	checkpoints := []*vpav1.VerticalPodAutoscalerCheckpoint{
+		newCheckpoint("app", 5*time.Minute),
+		newCheckpoint("sidecar", 3*time.Hour),
+	}
+	rows := newCheckpointRows(v, checkpoints, now, time.Hour, true)
+
+	want := []struct{ container, status string }{
+		{"app", checkpointOK},
+		{"proxy", checkpointMissing},
+		{"sidecar", checkpointStale},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		if rows[i].Container != w.container || rows[i].Status != w.status {
+			t.Errorf("row #%d: got %s/%s, want %s/%s", i, rows[i].Container, rows[i].Status, w.container, w.status)
+		}
+	}
+	if len(rows[0].CPUBuckets) != 1 || rows[0].MemoryBuckets != nil {
+		t.Errorf("unexpected buckets: %v, %v", rows[0].CPUBuckets, rows[0].MemoryBuckets)
+	}
+	if got := formatSince(rows[0].FirstSample, now, ""); got != "3d" {
+		t.Errorf("got history %q, want 3d", got)
+	}
+	// A VPA without recommendation nor checkpoint.
+	rows = newCheckpointRows(newComparedTestVPA("other", "other", nil), checkpoints, now, time.Hour, false)
+	if len(rows) != 1 || rows[0].Status != checkpointMissing || rows[0].Container != "" {
+		t.Errorf("unexpected rows: %+v", rows)
+	}
+}
+
+func TestPrintCheckpointHistograms(t *testing.T) {
+	rows := []checkpointRow{{
+		Namespace: "default",
+		VPA:       "app",
+		Container: "app",
+		CPUBuckets: checkpointBuckets(map[int]uint32{
+			1: 10000,
+			2: 5000,
+		}),
+	}}
+	var buf bytes.Buffer
+	if err := printCheckpointHistograms(&buf, rows); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, s := range []string{"default/app, container app, CPU usage:", "[10m, 20m)", "[20m, 32m)", "10000", "5000"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected output to contain %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestFormatBucketBounds(t *testing.T) {
+	if got := formatCPUBucketBound(0.0315); got != "32m" {
+		t.Errorf("got %q, want 32m", got)
+	}
+	if got := formatMemoryBucketBound(1 << 30); got != "1.0Gi" {
+		t.Errorf("got %q, want 1.0Gi", got)
+	}
+}
+
+func checkpointBuckets(weights map[int]uint32) []vpa.HistogramBucket {
+	return vpa.CheckpointBuckets(vpav1.HistogramCheckpoint{BucketWeights: weights}, vpa.CPUHistogramOptions)
+}
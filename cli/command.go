@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/klog/v2"
@@ -18,10 +19,10 @@ import (
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/util"
 	"k8s.io/kubectl/pkg/util/templates"
-	"k8s.io/utils/pointer"
 
 	"github.com/wI2L/kubectl-vpa-recommendation/client"
 	"github.com/wI2L/kubectl-vpa-recommendation/internal/version"
+	"github.com/wI2L/kubectl-vpa-recommendation/pkg/analysis"
 	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
 )
 
@@ -41,6 +42,16 @@ type CommandOptions struct {
 	ClientFlags   *client.Flags
 	Namespace     string
 	ResourceNames []string
+	Rounder       *vpa.Rounder
+	ConfigFile    string
+	Profile       string
+
+	// events caches the warning events
+	// of the pods of each namespace.
+	events map[string][]*corev1.Event
+	// config is the configuration
+	// applied to the flags.
+	config *configState
 
 	genericclioptions.IOStreams
 }
@@ -74,7 +85,10 @@ func NewCmd(streams genericclioptions.IOStreams, name string) *cobra.Command {
 			comps := get.CompGetResource(f, cmd, vpaPlural, tc)
 			return comps, cobra.ShellCompDirectiveNoFileComp
 		},
-		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+		PersistentPreRunE: func(c *cobra.Command, _ []string) error {
+			if err := opts.applyConfig(c); err != nil {
+				return err
+			}
 			return opts.Flags.Tidy()
 		},
 	}
@@ -82,9 +96,15 @@ func NewCmd(streams genericclioptions.IOStreams, name string) *cobra.Command {
 
 	// Bind client config and common flags
 	// to the command's flag set.
-	opts.ClientFlags.AddFlags(cmd.Flags())
+	opts.ClientFlags.AddFlags(cmd.PersistentFlags())
+	opts.Flags.AddPersistentFlags(cmd.PersistentFlags())
 	opts.Flags.AddFlags(cmd.Flags())
 
+	cmd.PersistentFlags().StringVar(&opts.ConfigFile, flagConfig, opts.ConfigFile,
+		"Path to the configuration file. Default to $XDG_CONFIG_HOME/kubectl-vpa-recommendation/config.yaml")
+	cmd.PersistentFlags().StringVar(&opts.Profile, flagProfile, opts.Profile,
+		"The name of the profile of the configuration file to use")
+
 	// Replace the default flags added by cobra.
 	cmd.Flags().Bool("version", false, "Print the command version and exit")
 	cmd.Flags().BoolP("help", "h", false, "Print the command help and exit")
@@ -93,6 +113,22 @@ func NewCmd(streams genericclioptions.IOStreams, name string) *cobra.Command {
 	opts.ClientFlags.RegisterCompletionFunc(cmd, f)
 	_ = cmd.RegisterFlagCompletionFunc(flagSortColumns, getSortColumnsComps)
 
+	cmd.AddCommand(
+		newBaselineCmd(&opts, f),
+		newServeCmd(&opts),
+		newSnapshotCmd(&opts, f),
+		newCoverageCmd(&opts),
+		newGenerateCmd(&opts),
+		newAppliedCmd(&opts, f),
+		newStatsCmd(&opts, f),
+		newCapacityCmd(&opts),
+		newValidateCmd(&opts),
+		newExplainCmd(&opts, f),
+		newCompareCmd(&opts, f),
+		newCheckpointsCmd(&opts, f),
+		newConfigCmd(&opts),
+	)
+
 	return templates.Normalize(cmd)
 }
 
@@ -119,6 +155,10 @@ func (co *CommandOptions) Complete(_ *cobra.Command, _ []string) error {
 	if err != nil {
 		return fmt.Errorf("couldn't create client: %w", err)
 	}
+	co.Rounder, err = newRounder(co.Flags)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -143,44 +183,120 @@ func (co *CommandOptions) Validate(_ *cobra.Command, args []string) error {
 func (co *CommandOptions) Execute() error {
 	ctx := context.Background()
 
-	// Check if the cluster is reachable and
-	// has the required CustomResourceDefinition.
-	err := co.Client.IsClusterReachable()
+	vpas, err := co.listVPAs(ctx)
 	if err != nil {
 		return err
 	}
-	gv := vpav1.SchemeGroupVersion
-	ok, err := co.Client.HasGroupVersion(gv)
-	if err != nil {
-		return err
+	if len(vpas) == 0 {
+		co.printNoResources()
+		return nil
 	}
-	if !ok {
-		return fmt.Errorf("group %s not available", gv.String())
+	tables := co.newTables(vpas)
+
+	if co.Flags.MaxAge > 0 {
+		now := time.Now()
+		for i := range tables {
+			tables[i] = tables[i].withMaxAge(co.Flags.MaxAge, now)
+		}
 	}
-	vpas, err := co.Client.ListVPAResources(ctx, client.ListOptions{
-		Namespace:      co.Namespace,
-		AllNamespaces:  co.Flags.AllNamespaces,
-		ResourceNames:  co.ResourceNames,
-		LabelSelector:  co.Flags.LabelSelector,
-		FieldSelector:  co.Flags.FieldSelector,
-		TimeoutSeconds: pointer.Int64(int64(defaultTimeout.Seconds())),
-		Limit:          250,
-	})
-	if err != nil {
-		return err
+
+	names := co.Flags.resourceNames()
+
+	if co.Flags.Baseline != "" {
+		bl, err := loadBaseline(co.Flags.Baseline)
+		if err != nil {
+			return err
+		}
+		for i := range tables {
+			tables[i] = bl.Apply(tables[i], names, time.Now(), co.Flags.ShowBaselined)
+		}
 	}
-	if len(vpas) == 0 {
-		if co.Flags.AllNamespaces {
-			fmt.Println("No VPA resources found.")
-		} else {
-			fmt.Printf("No VPA resources found in %s namespace.\n", co.Namespace)
+	// The groups are aggregated before the tables
+	// are truncated, to account for all the rows.
+	var groups []*groupAggregate
+	if co.Flags.groupBy != nil {
+		groups = aggregate(tables, names)
+	}
+	for i := range tables {
+		var total *tableRow
+		if co.Flags.split && co.Flags.GroupTotals {
+			if g := aggregate(tables[i:i+1], names); len(g) != 0 {
+				total = g[0].totalRow()
+			}
+		}
+		tables[i].SortBy(co.Flags.SortOrder, co.Flags.SortColumns...)
+		if n := co.Flags.Top; n > 0 && len(tables[i]) > n {
+			tables[i] = tables[i][:n]
+		}
+		if err := tables[i].printWithTotal(co.Out, co.Flags, total); err != nil {
+			return err
+		}
+		if i != len(tables)-1 {
+			_, err := os.Stdout.WriteString("\n")
+			if err != nil {
+				return err
+			}
 		}
-		return nil
 	}
-	klog.V(4).Infof("fetched %d VPA(s)", len(vpas))
+	warnQOSDowngrades(tables)
 
+	if groups != nil {
+		fmt.Fprintln(co.Out)
+		if err := printGroups(co.Out, groups, co.Flags); err != nil {
+			return err
+		}
+	}
+	if co.Flags.ShowVariance {
+		fmt.Fprintln(co.Out)
+		return printRequestsVariance(co.Out, tables, names)
+	}
+	return nil
+}
+
+// listVPAs returns the list of VPA resources
+// that match the command options.
+func (co *CommandOptions) listVPAs(ctx context.Context) ([]*vpav1.VerticalPodAutoscaler, error) {
+	return analysis.List(ctx, co.Client, co.analysisOptions())
+}
+
+// analysisOptions returns the options of the
+// analysis of the VPA resources of the command.
+func (co *CommandOptions) analysisOptions() analysis.Options {
+	return analysis.Options{
+		Namespace:          co.Namespace,
+		AllNamespaces:      co.Flags.AllNamespaces,
+		Names:              co.ResourceNames,
+		LabelSelector:      co.Flags.LabelSelector,
+		FieldSelector:      co.Flags.FieldSelector,
+		Recommender:        co.Flags.Recommender,
+		RecommendationType: co.Flags.RecommendationType,
+		RequestsSource:     co.Flags.RequestsSource,
+		Rounder:            co.Rounder,
+		Thresholder:        co.Flags.thresholder,
+		Timeout:            defaultTimeout,
+	}
+}
+
+// printNoResources writes the message displayed when
+// no VPA resources matched the command options.
+func (co *CommandOptions) printNoResources() {
+	if co.Flags.AllNamespaces {
+		fmt.Fprintln(co.Out, "No VPA resources found.")
+	} else {
+		fmt.Fprintf(co.Out, "No VPA resources found in %s namespace.\n", co.Namespace)
+	}
+}
+
+// newTables returns the tables of the VPA resources in the list.
+// In split mode, a table is created for each namespace.
+func (co *CommandOptions) newTables(vpas []*vpav1.VerticalPodAutoscaler) []table {
 	var tables []table
 
+	if co.Flags.split && co.Flags.groupBy != nil {
+		// Bind all the VPA objects at once, and
+		// create a table for each group.
+		return partitionByGroup(co.bindRecommendationsAndRequests(vpas))
+	}
 	if co.Flags.split {
 		// Sort the result list by namespace, and create
 		// a table of VPA objects for each.
@@ -199,19 +315,7 @@ func (co *CommandOptions) Execute() error {
 		table := co.bindRecommendationsAndRequests(vpas)
 		tables = append(tables, table)
 	}
-	for i := range tables {
-		tables[i].SortBy(co.Flags.SortOrder, co.Flags.SortColumns...)
-		if err := tables[i].Print(co.Out, co.Flags); err != nil {
-			return err
-		}
-		if i != len(tables)-1 {
-			_, err := os.Stdout.WriteString("\n")
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+	return tables
 }
 
 // bindRecommendationsAndRequests returns a table that bind the
@@ -220,67 +324,109 @@ func (co *CommandOptions) Execute() error {
 func (co *CommandOptions) bindRecommendationsAndRequests(list []*vpav1.VerticalPodAutoscaler) table {
 	var table table
 
-	for _, v := range list {
-		ref := v.Spec.TargetRef
-		if ref == nil {
-			klog.V(4).Infof("vpa %s/%s has no target", v.Namespace, v.Name)
-			continue
-		}
-		tc, err := vpa.NewTargetController(co.Client, v.Spec.TargetRef, v.Namespace)
-		if err != nil {
-			klog.V(4).Infof("couldn't get target for vpa %s/%s", v.Namespace, v.Name)
-			continue
+	r := analysis.AnalyzeVPAs(context.Background(), co.Client, list, co.analysisOptions())
+
+	for i := range r.Items {
+		item := &r.Items[i]
+		row := newTableRow(item)
+
+		if co.Flags.groupBy != nil {
+			row.Group = co.Flags.groupBy.keyOf(item)
 		}
-		row := newTableRow(v, tc, v.Name, co.Flags.RecommendationType)
 		table = append(table, row)
 
-		if v.Status.Recommendation != nil && len(v.Status.Recommendation.ContainerRecommendations) > 1 {
-			if !co.Flags.ShowContainers {
-				continue
-			}
-			for i, c := range v.Status.Recommendation.ContainerRecommendations {
-				prefix := treeElemPrefix
+		var risks map[string]risk
+		if co.Flags.ShowRisk {
+			health := item.TargetController().Health(co.warningEvents(item.Namespace), time.Now().Add(-co.Flags.RiskWindow))
 
-				if i == len(v.Status.Recommendation.ContainerRecommendations)-1 {
-					prefix = treeLastElemPrefix
-				}
-				rqs := tc.GetContainerRequests(c.ContainerName)
-				rcs := vpa.ResourceQuantities{
-					CPU:    c.Target.Cpu(),
-					Memory: c.Target.Memory(),
-				}
-				childRow := &tableRow{
-					Name:             fmt.Sprintf("%s %s", prefix, c.ContainerName),
-					Requests:         rqs,
-					Recommendations:  rcs,
-					CPUDifference:    vpa.DiffQuantitiesAsPercent(rqs.CPU, rcs.CPU),
-					MemoryDifference: vpa.DiffQuantitiesAsPercent(rqs.Memory, rcs.Memory),
+			var overall risk
+			overall, risks = assessRisks(item, health, co.Flags.RecommendationType, co.Flags.RestartThreshold)
+			row.Risk = &overall
+		}
+		if !co.Flags.ShowContainers {
+			continue
+		}
+		if children := newContainerRows(item); len(children) > 1 {
+			for _, child := range children {
+				if r, ok := risks[child.ContainerName]; ok {
+					r := r
+					child.Risk = &r
 				}
-				row.Children = append(row.Children, childRow)
 			}
+			row.Children = children
 		}
 	}
 	return table
 }
 
-func newTableRow(v *vpav1.VerticalPodAutoscaler, tc *vpa.TargetController, name string, rt vpa.RecommendationType) *tableRow {
-	rqs := tc.GetRequests()
-	rcs := vpa.TotalRecommendations(v, rt)
-
-	row := &tableRow{
-		Name:             name,
-		Namespace:        v.Namespace,
-		GVK:              v.GroupVersionKind(),
-		Mode:             updateModeFromSpec(v.Spec.UpdatePolicy),
-		Target:           tc,
-		TargetName:       tc.Name,
-		TargetGVK:        tc.GroupVersionKind,
-		Requests:         rqs,
-		Recommendations:  rcs,
-		CPUDifference:    vpa.DiffQuantitiesAsPercent(rqs.CPU, rcs.CPU),
-		MemoryDifference: vpa.DiffQuantitiesAsPercent(rqs.Memory, rcs.Memory),
+// newContainerRows returns the rows of the containers of an item.
+// The containers with a recommendation come first, followed by the
+// init and sidecar containers of the target's pod spec that have none.
+func newContainerRows(item *analysis.Item) []*tableRow {
+	rows := make([]*tableRow, 0, len(item.Containers))
+
+	for i, c := range item.Containers {
+		prefix := treeElemPrefix
+
+		if i == len(item.Containers)-1 {
+			prefix = treeLastElemPrefix
+		}
+		name := fmt.Sprintf("%s %s", prefix, c.Name)
+
+		if c.Type == vpa.ContainerInit || c.Type == vpa.ContainerSidecar {
+			name = fmt.Sprintf("%s (%s)", name, c.Type)
+		}
+		rows = append(rows, &tableRow{
+			Name:                name,
+			ContainerName:       c.Name,
+			ContainerType:       c.Type,
+			TargetReplicas:      targetReplicas(item),
+			Thresholds:          item.Thresholds,
+			RecommendedAt:       c.RecommendedAt,
+			RecommendedAtSource: c.RecommendedAtSource,
+			OutdatedTemplate:    c.OutdatedTemplate,
+			Requests:            c.Requests,
+			Recommendations:     c.Recommendations,
+			Suggested:           c.Suggested,
+			Differences:         c.Differences,
+		})
+	}
+	return rows
+}
+
+func newTableRow(item *analysis.Item) *tableRow {
+	return &tableRow{
+		Name:                item.Name,
+		Namespace:           item.Namespace,
+		GVK:                 item.GroupVersionKind,
+		Mode:                orUnset(item.Mode),
+		Recommenders:        item.Recommenders,
+		Target:              item.TargetController(),
+		TargetName:          item.Target.Name,
+		TargetGVK:           item.TargetController().GroupVersionKind,
+		TargetReplicas:      targetReplicas(item),
+		MixedRequests:       item.MixedRequests,
+		QOSClass:            item.QOSClass,
+		RecommendedQOSClass: item.RecommendedQOSClass,
+		Thresholds:          item.Thresholds,
+		RecommendedAt:       item.RecommendedAt,
+		RecommendedAtSource: item.RecommendedAtSource,
+		OutdatedTemplate:    item.OutdatedTemplate,
+		Requests:            item.Requests,
+		Recommendations:     item.Recommendations,
+		Suggested:           item.Suggested,
+		Differences:         item.Differences,
+	}
+}
+
+// targetReplicas returns the number of replicas of the target of
+// an item. Controllers without a replicas field, such as DaemonSets,
+// are counted as a single replica, as in the statistics.
+func targetReplicas(item *analysis.Item) int32 {
+	if item.Replicas == nil {
+		return 1
 	}
-	return row
+	return int32(*item.Replicas)
 }
 
 func updateModeFromSpec(spec *vpav1.PodUpdatePolicy) string {
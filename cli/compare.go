@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/kubectl/pkg/cmd/get"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/pkg/analysis"
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+// comparedVPA represents one of the two VPA resources of a comparison.
+type comparedVPA struct {
+	Name         string   `json:"name"`
+	Mode         string   `json:"mode"`
+	Recommenders []string `json:"recommenders"`
+}
+
+// comparison represents the recommendations of two
+// VPA resources that target the same controller.
+type comparison struct {
+	Namespace string          `json:"namespace"`
+	Target    reportTarget    `json:"target"`
+	A         comparedVPA     `json:"a"`
+	B         comparedVPA     `json:"b"`
+	Rows      []comparisonRow `json:"rows"`
+}
+
+// comparisonRow represents the recommendations of the
+// two VPA resources for a resource of a container. The
+// difference is the increase/decrease of B in terms of A.
+type comparisonRow struct {
+	Container  string              `json:"container"`
+	Resource   corev1.ResourceName `json:"resource"`
+	A          *resource.Quantity  `json:"a,omitempty"`
+	B          *resource.Quantity  `json:"b,omitempty"`
+	Difference *float64            `json:"difference"`
+}
+
+// pairByTarget returns a comparison for each pair of VPA
+// resources of the list that target the same controller.
+// The recommendations of the comparisons are not set.
+func pairByTarget(vpas []*vpav1.VerticalPodAutoscaler) []comparison {
+	type targetKey struct {
+		namespace, apiVersion, kind, name string
+	}
+	var keys []targetKey
+	byTarget := make(map[targetKey][]*vpav1.VerticalPodAutoscaler)
+
+	for _, v := range vpas {
+		ref := v.Spec.TargetRef
+		if ref == nil {
+			continue
+		}
+		k := targetKey{v.Namespace, ref.APIVersion, ref.Kind, ref.Name}
+		if _, ok := byTarget[k]; !ok {
+			keys = append(keys, k)
+		}
+		byTarget[k] = append(byTarget[k], v)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].namespace != keys[j].namespace {
+			return keys[i].namespace < keys[j].namespace
+		}
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].name < keys[j].name
+	})
+	var ret []comparison
+
+	for _, k := range keys {
+		list := byTarget[k]
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+		for i := 0; i < len(list); i++ {
+			for j := i + 1; j < len(list); j++ {
+				ret = append(ret, comparison{
+					Namespace: k.namespace,
+					Target: reportTarget{
+						APIVersion: k.apiVersion,
+						Kind:       k.kind,
+						Name:       k.name,
+					},
+					A: newComparedVPA(list[i]),
+					B: newComparedVPA(list[j]),
+				})
+			}
+		}
+	}
+	return ret
+}
+
+func newComparedVPA(v *vpav1.VerticalPodAutoscaler) comparedVPA {
+	return comparedVPA{
+		Name:         v.Name,
+		Mode:         updateModeFromSpec(v.Spec.UpdatePolicy),
+		Recommenders: analysis.RecommenderNames(v),
+	}
+}
+
+// compareRecommendations returns a row for each resource of each
+// container that has a recommendation in either VPA resource.
+func compareRecommendations(a, b *vpav1.VerticalPodAutoscaler, rt vpa.RecommendationType, names []corev1.ResourceName) []comparisonRow {
+	recsA, recsB := containerRecommendations(a, rt), containerRecommendations(b, rt)
+
+	var containers []string
+	for name := range recsA {
+		containers = append(containers, name)
+	}
+	for name := range recsB {
+		if _, ok := recsA[name]; !ok {
+			containers = append(containers, name)
+		}
+	}
+	sort.Strings(containers)
+
+	var rows []comparisonRow
+	for _, c := range containers {
+		for _, name := range names {
+			qa, qb := recsA[c].Get(name), recsB[c].Get(name)
+			rows = append(rows, comparisonRow{
+				Container:  c,
+				Resource:   name,
+				A:          qa,
+				B:          qb,
+				Difference: vpa.DiffQuantitiesAsPercent(qb, qa),
+			})
+		}
+	}
+	return rows
+}
+
+func containerRecommendations(v *vpav1.VerticalPodAutoscaler, rt vpa.RecommendationType) map[string]vpa.ResourceQuantities {
+	recs := make(map[string]vpa.ResourceQuantities)
+	if v.Status.Recommendation != nil {
+		for _, cr := range v.Status.Recommendation.ContainerRecommendations {
+			recs[cr.ContainerName] = vpa.ContainerRecommendations(cr, rt)
+		}
+	}
+	return recs
+}
+
+// compareOptions represents the options of the compare command.
+type compareOptions struct {
+	*CommandOptions
+
+	Output string
+}
+
+// newCompareCmd returns a new command that shows the
+// recommendations of VPA resources that target the same
+// controller side by side.
+func newCompareCmd(co *CommandOptions, f cmdutil.Factory) *cobra.Command {
+	cpo := &compareOptions{CommandOptions: co}
+
+	cmd := &cobra.Command{
+		Use:   "compare [NAME NAME] [options]",
+		Short: "Compare the recommendations of VPA resources that target the same controller",
+		Long: `Compare the recommendations of VPA resources that target the same controller.
+
+When several recommenders run in a cluster, or when a VPA in Off mode is
+used to evaluate another configuration, a controller may be targeted by more
+than one VPA resource. For each pair of such resources, this command shows
+the recommendations of both, side by side, with the difference of the
+second in terms of the first. If two names are given, only these resources
+are compared, even if their targets differ.`,
+		Args:                  cobra.RangeArgs(0, 2),
+		DisableFlagsInUseLine: true,
+		Run:                   cpo.Run,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, tc string) ([]string, cobra.ShellCompDirective) {
+			comps := get.CompGetResource(f, cmd, vpaPlural, tc)
+			return comps, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVarP(&cpo.Output, flagOutput, flagOutputShorthand, cpo.Output,
+		"Output format. One of 'json'")
+
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (cpo *compareOptions) Run(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(cpo.Complete(c, args))
+	cmdutil.CheckErr(cpo.Validate(c, args))
+	cmdutil.CheckErr(cpo.Execute())
+}
+
+// Validate ensure that required options to run the
+// command are set and valid.
+func (cpo *compareOptions) Validate(c *cobra.Command, args []string) error {
+	switch cpo.Output {
+	case "", jsonOutput:
+	default:
+		return fmt.Errorf("unsupported output format: %s", cpo.Output)
+	}
+	if len(args) == 1 {
+		return fmt.Errorf("expected two VPA names, or none")
+	}
+	return cpo.CommandOptions.Validate(c, args)
+}
+
+// Execute runs the command.
+func (cpo *compareOptions) Execute() error {
+	vpas, err := cpo.listVPAs(context.Background())
+	if err != nil {
+		return err
+	}
+	if len(vpas) == 0 {
+		cpo.printNoResources()
+		return nil
+	}
+	var comparisons []comparison
+
+	if len(cpo.ResourceNames) == 2 {
+		if len(vpas) != 2 {
+			return fmt.Errorf("expected to find 2 VPA resources, found %d", len(vpas))
+		}
+		a, b := vpas[0], vpas[1]
+		if a.Name != cpo.ResourceNames[0] {
+			a, b = b, a
+		}
+		c := comparison{
+			Namespace: a.Namespace,
+			A:         newComparedVPA(a),
+			B:         newComparedVPA(b),
+		}
+		if ref := a.Spec.TargetRef; ref != nil {
+			c.Target = reportTarget{APIVersion: ref.APIVersion, Kind: ref.Kind, Name: ref.Name}
+			if rb := b.Spec.TargetRef; rb == nil || rb.Kind != ref.Kind || rb.Name != ref.Name {
+				klog.Warningf("vpa %s and %s don't target the same controller", a.Name, b.Name)
+			}
+		}
+		c.Rows = compareRecommendations(a, b, cpo.Flags.RecommendationType, cpo.Flags.resourceNames())
+		comparisons = append(comparisons, c)
+	} else {
+		byName := make(map[string]*vpav1.VerticalPodAutoscaler, len(vpas))
+		for _, v := range vpas {
+			byName[v.Namespace+"/"+v.Name] = v
+		}
+		for _, c := range pairByTarget(vpas) {
+			a, b := byName[c.Namespace+"/"+c.A.Name], byName[c.Namespace+"/"+c.B.Name]
+			c.Rows = compareRecommendations(a, b, cpo.Flags.RecommendationType, cpo.Flags.resourceNames())
+			comparisons = append(comparisons, c)
+		}
+	}
+	if cpo.Output == jsonOutput {
+		if comparisons == nil {
+			comparisons = []comparison{}
+		}
+		enc := json.NewEncoder(cpo.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(comparisons)
+	}
+	if len(comparisons) == 0 {
+		fmt.Fprintln(cpo.Out, "No VPA resources target the same controller.")
+		return nil
+	}
+	for i, c := range comparisons {
+		if i != 0 {
+			fmt.Fprintln(cpo.Out)
+		}
+		printComparison(cpo.Out, c, cpo.Flags)
+	}
+	return nil
+}
+
+func printComparison(w io.Writer, c comparison, flags *Flags) {
+	fmt.Fprintf(w, "Target: %s/%s/%s\n", c.Namespace, c.Target.Kind, c.Target.Name)
+	fmt.Fprintf(w, "A: %s (mode %s, recommenders %s)\n", c.A.Name, c.A.Mode, formatRecommenders(c.A.Recommenders))
+	fmt.Fprintf(w, "B: %s (mode %s, recommenders %s)\n", c.B.Name, c.B.Mode, formatRecommenders(c.B.Recommenders))
+
+	tw := newKubectlTableWriter(w)
+	if !flags.NoHeaders {
+		tw.SetHeader([]string{"Container", "Resource", c.A.Name, c.B.Name, "% Diff"})
+	}
+	for _, r := range c.Rows {
+		tw.Append([]string{
+			r.Container,
+			string(r.Resource),
+			formatQuantity(r.A),
+			formatQuantity(r.B),
+			formatPercentage(r.Difference, nil, r.Resource, flags),
+		})
+	}
+	tw.Render()
+}
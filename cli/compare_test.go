@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"testing"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func newComparedTestVPA(name, target string, recs map[string]corev1.ResourceList) *vpav1.VerticalPodAutoscaler {
+	v := &vpav1.VerticalPodAutoscaler{}
+	v.Name = name
+	v.Namespace = "default"
+	v.Spec.TargetRef = &autoscalingv1.CrossVersionObjectReference{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Name:       target,
+	}
+	if recs != nil {
+		v.Status.Recommendation = &vpav1.RecommendedPodResources{}
+		for c, rl := range recs {
+			v.Status.Recommendation.ContainerRecommendations = append(v.Status.Recommendation.ContainerRecommendations,
+				vpav1.RecommendedContainerResources{ContainerName: c, Target: rl})
+		}
+	}
+	return v
+}
+
+func TestPairByTarget(t *testing.T) {
+	vpas := []*vpav1.VerticalPodAutoscaler{
+		newComparedTestVPA("web-c", "web", nil),
+		newComparedTestVPA("api", "api", nil),
+		newComparedTestVPA("web-a", "web", nil),
+		newComparedTestVPA("web-b", "web", nil),
+	}
+	cs := pairByTarget(vpas)
+	if len(cs) != 3 {
+		t.Fatalf("got %d comparisons, want 3", len(cs))
+	}
+	want := [][2]string{{"web-a", "web-b"}, {"web-a", "web-c"}, {"web-b", "web-c"}}
+	for i, c := range cs {
+		if c.A.Name != want[i][0] || c.B.Name != want[i][1] {
+			t.Errorf("comparison #%d: got %s/%s, want %s/%s", i, c.A.Name, c.B.Name, want[i][0], want[i][1])
+		}
+		if c.Target.Name != "web" {
+			t.Errorf("comparison #%d: got target %s, want web", i, c.Target.Name)
+		}
+	}
+}
+
+func TestCompareRecommendations(t *testing.T) {
+	a := newComparedTestVPA("a", "web", map[string]corev1.ResourceList{
+		"app": {corev1.ResourceCPU: resource.MustParse("200m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+	})
+	b := newComparedTestVPA("b", "web", map[string]corev1.ResourceList{
+		"app":     {corev1.ResourceCPU: resource.MustParse("300m"), corev1.ResourceMemory: resource.MustParse("64Mi")},
+		"sidecar": {corev1.ResourceCPU: resource.MustParse("10m")},
+	})
+	rows := compareRecommendations(a, b, vpa.RecommendationTarget, []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory})
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, want 4", len(rows))
+	}
+	for i, want := range []struct {
+		container string
+		diff      *float64
+	}{
+		{"app", float64Ptr(50)},
+		{"app", float64Ptr(-50)},
+		{"sidecar", nil},
+		{"sidecar", nil},
+	} {
+		r := rows[i]
+		if r.Container != want.container {
+			t.Errorf("row #%d: got container %s, want %s", i, r.Container, want.container)
+		}
+		switch {
+		case want.diff == nil && r.Difference != nil:
+			t.Errorf("row #%d: got difference %f, want none", i, *r.Difference)
+		case want.diff != nil && (r.Difference == nil || *r.Difference != *want.diff):
+			t.Errorf("row #%d: got difference %v, want %f", i, r.Difference, *want.diff)
+		}
+	}
+}
+
+func float64Ptr(f float64) *float64 { return &f }
@@ -0,0 +1,443 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	flagConfig  = "config"
+	flagProfile = "profile"
+
+	configDirName   = "kubectl-vpa-recommendation"
+	configFileName  = "config.yaml"
+	configEnvPrefix = "KUBECTL_VPA_RECOMMENDATION_"
+	yamlOutput      = "yaml"
+)
+
+// config represents the configuration file of the plugin. The
+// settings are keyed by the name of the flag they set, such as
+// warning-threshold or sort-columns.
+type config struct {
+	// Settings are the defaults applied to all invocations.
+	Settings map[string]interface{} `json:"settings,omitempty"`
+	// Profiles maps the name of a profile to its settings,
+	// which override the defaults of the file.
+	Profiles map[string]map[string]interface{} `json:"profiles,omitempty"`
+	// Contexts maps the name of a kubeconfig context to the
+	// profile selected when no profile is given explicitly.
+	Contexts map[string]string `json:"contexts,omitempty"`
+}
+
+// settingSource represents where the value of a setting comes from.
+type settingSource string
+
+const (
+	sourceDefault settingSource = "default"
+	sourceFile    settingSource = "file"
+	sourceProfile settingSource = "profile"
+	sourceEnv     settingSource = "env"
+	sourceFlag    settingSource = "flag"
+)
+
+// setting represents the effective value of a flag.
+type setting struct {
+	Name   string        `json:"name"`
+	Value  string        `json:"value"`
+	Source settingSource `json:"source"`
+}
+
+// configState represents the configuration
+// applied to the flags of an invocation.
+type configState struct {
+	Path     string    `json:"path"`
+	Loaded   bool      `json:"loaded"`
+	Profile  string    `json:"profile,omitempty"`
+	Context  string    `json:"context,omitempty"`
+	Settings []setting `json:"settings"`
+}
+
+// defaultConfigPath returns the path of the configuration
+// file in the XDG configuration directory of the user.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, configDirName, configFileName)
+}
+
+// loadConfig reads the configuration file at path. A missing
+// file is an empty configuration, unless mustExist is true.
+func loadConfig(path string, mustExist bool) (*config, bool, error) {
+	cfg := &config{}
+	if path == "" {
+		return cfg, false, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !mustExist {
+			return cfg, false, nil
+		}
+		return nil, false, fmt.Errorf("couldn't read config file: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, false, fmt.Errorf("couldn't decode config file %s: %w", path, err)
+	}
+	return cfg, true, nil
+}
+
+// resolve returns the values of the settings of the file, overridden
+// by those of the profile. If profile is empty, the profile mapped to
+// the kubeconfig context is used, if any. It returns the name of the
+// profile used.
+func (c *config) resolve(profile, kubeContext string) (map[string]string, map[string]settingSource, string, error) {
+	values := make(map[string]string)
+	sources := make(map[string]settingSource)
+
+	add := func(settings map[string]interface{}, source settingSource) error {
+		for name, v := range settings {
+			s, err := settingValue(v)
+			if err != nil {
+				return fmt.Errorf("invalid value for setting %s: %w", name, err)
+			}
+			values[name] = s
+			sources[name] = source
+		}
+		return nil
+	}
+	if err := add(c.Settings, sourceFile); err != nil {
+		return nil, nil, "", err
+	}
+	if profile == "" && kubeContext != "" {
+		profile = c.Contexts[kubeContext]
+	}
+	if profile != "" {
+		settings, ok := c.Profiles[profile]
+		if !ok {
+			return nil, nil, "", fmt.Errorf("unknown profile %q", profile)
+		}
+		if err := add(settings, sourceProfile); err != nil {
+			return nil, nil, "", fmt.Errorf("profile %s: %w", profile, err)
+		}
+	}
+	return values, sources, profile, nil
+}
+
+// settingValue returns the string representation of a setting
+// value decoded from YAML, as it would be given to its flag.
+// Lists are joined with commas.
+func settingValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, e := range v {
+			s, err := settingValue(e)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// envName returns the name of the environment
+// variable that sets the value of a flag.
+func envName(flag string) string {
+	return configEnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// flagSet returns a flag set bound to the fields of the flags,
+// used to apply the settings of the configuration.
+func (f *Flags) flagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	f.AddPersistentFlags(fs)
+	f.AddFlags(fs)
+	return fs
+}
+
+// applySettings sets the flags of the set from the settings of
+// the file and the profile, and from the environment. The flags
+// changed on the command line are left untouched. The precedence
+// is flag > env > profile > file. It returns the effective value
+// and source of each flag.
+func applySettings(
+	fs *pflag.FlagSet,
+	values map[string]string,
+	sources map[string]settingSource,
+	lookupEnv func(string) (string, bool),
+	changed func(string) bool,
+) ([]setting, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if fs.Lookup(name) == nil {
+			return nil, fmt.Errorf("unknown setting %q", name)
+		}
+	}
+	fs.VisitAll(func(f *pflag.Flag) {
+		if v, ok := lookupEnv(envName(f.Name)); ok {
+			values[f.Name] = v
+			sources[f.Name] = sourceEnv
+		}
+	})
+	var (
+		settings []setting
+		err      error
+	)
+	fs.VisitAll(func(f *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		source, ok := sources[f.Name]
+		switch {
+		case changed(f.Name):
+			source = sourceFlag
+		case ok:
+			if e := fs.Set(f.Name, values[f.Name]); e != nil {
+				err = fmt.Errorf("invalid value %q for setting %s from %s: %w", values[f.Name], f.Name, source, e)
+				return
+			}
+		default:
+			source = sourceDefault
+		}
+		settings = append(settings, setting{
+			Name:   f.Name,
+			Value:  formatFlagValue(f.Value),
+			Source: source,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// formatFlagValue formats the value of a flag, without the
+// brackets surrounding the values of slice flags, and without
+// the trailing zeros of their floating-point values.
+func formatFlagValue(v pflag.Value) string {
+	sv, ok := v.(pflag.SliceValue)
+	if !ok {
+		return v.String()
+	}
+	values := sv.GetSlice()
+	for i, e := range values {
+		if f, err := strconv.ParseFloat(e, 64); err == nil {
+			values[i] = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+// applyConfig applies the configuration file, the selected profile
+// and the environment to the flags that weren't changed on the
+// command line of the executing command.
+func (co *CommandOptions) applyConfig(cmd *cobra.Command) error {
+	path, mustExist := co.ConfigFile, true
+	if path == "" {
+		path, mustExist = os.Getenv(configEnvPrefix+"CONFIG"), true
+	}
+	if path == "" {
+		path, mustExist = defaultConfigPath(), false
+	}
+	cfg, loaded, err := loadConfig(path, mustExist)
+	if err != nil {
+		return err
+	}
+	profile := co.Profile
+	if profile == "" {
+		profile = os.Getenv(configEnvPrefix + "PROFILE")
+	}
+	kubeContext := co.kubeContext()
+
+	values, sources, profile, err := cfg.resolve(profile, kubeContext)
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	settings, err := applySettings(co.Flags.flagSet(), values, sources, os.LookupEnv, cmd.Flags().Changed)
+	if err != nil {
+		return err
+	}
+	if loaded {
+		klog.V(4).Infof("loaded config file %s", path)
+	}
+	if profile != "" {
+		klog.V(4).Infof("using profile %s", profile)
+	}
+	co.config = &configState{
+		Path:     path,
+		Loaded:   loaded,
+		Profile:  profile,
+		Context:  kubeContext,
+		Settings: settings,
+	}
+	return nil
+}
+
+// kubeContext returns the name of the kubeconfig context
+// used by the invocation, or an empty string if unknown.
+func (co *CommandOptions) kubeContext() string {
+	if ctx := co.ClientFlags.Context; ctx != nil && *ctx != "" {
+		return *ctx
+	}
+	raw, err := co.ClientFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	return raw.CurrentContext
+}
+
+// configViewOptions represents the options of the config view command.
+type configViewOptions struct {
+	*CommandOptions
+
+	Output string
+}
+
+// newConfigCmd returns a new command that groups
+// the commands related to the configuration file.
+func newConfigCmd(co *CommandOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration of the plugin",
+		Long: fmt.Sprintf(`Inspect the configuration of the plugin.
+
+The settings of the configuration file supply the defaults of the flags,
+keyed by flag name. The file is read from $XDG_CONFIG_HOME/%[1]s/%[2]s
+by default, or from the path given by the --config flag or the
+%[3]sCONFIG environment variable. Named profiles override the
+settings of the file, and are selected with the --profile flag, the
+%[3]sPROFILE environment variable, or by kubeconfig context.
+Each flag can also be set with an environment variable, such as
+%[3]sWARNING_THRESHOLD.
+
+The precedence is: flag > env > profile > file.`, configDirName, configFileName, configEnvPrefix),
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.AddCommand(newConfigViewCmd(co))
+
+	return cmd
+}
+
+// newConfigViewCmd returns a new command that
+// prints the effective settings of the flags.
+func newConfigViewCmd(co *CommandOptions) *cobra.Command {
+	cvo := &configViewOptions{CommandOptions: co}
+
+	cmd := &cobra.Command{
+		Use:   "view [options]",
+		Short: "Print the effective settings merged from the flags, environment, profile and file",
+		Long: `Print the effective settings merged from the flags, environment, profile and file.
+
+The source of each setting is one of: flag, env, profile, file or default.
+With the yaml output, the settings are printed as a configuration file.`,
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		Run:                   cvo.Run,
+	}
+	cmd.Flags().StringVarP(&cvo.Output, flagOutput, flagOutputShorthand, cvo.Output,
+		"Output format. One of 'json', 'yaml'")
+
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (cvo *configViewOptions) Run(_ *cobra.Command, _ []string) {
+	cmdutil.CheckErr(cvo.Validate())
+	cmdutil.CheckErr(cvo.Execute())
+}
+
+// Validate ensure that required options to run the
+// command are set and valid.
+func (cvo *configViewOptions) Validate() error {
+	switch cvo.Output {
+	case "", jsonOutput, yamlOutput:
+	default:
+		return fmt.Errorf("unsupported output format: %s", cvo.Output)
+	}
+	return nil
+}
+
+// Execute runs the command.
+func (cvo *configViewOptions) Execute() error {
+	state := cvo.config
+	if state == nil {
+		return fmt.Errorf("configuration not applied")
+	}
+	switch cvo.Output {
+	case jsonOutput:
+		enc := json.NewEncoder(cvo.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(state)
+	case yamlOutput:
+		cfg := config{Settings: make(map[string]interface{}, len(state.Settings))}
+		for _, s := range state.Settings {
+			cfg.Settings[s.Name] = s.Value
+		}
+		b, err := yaml.Marshal(cfg)
+		if err != nil {
+			return err
+		}
+		_, err = cvo.Out.Write(b)
+		return err
+	}
+	printConfigState(cvo.Out, state, cvo.Flags)
+
+	return nil
+}
+
+func printConfigState(w io.Writer, state *configState, flags *Flags) {
+	file := state.Path
+	if !state.Loaded {
+		file += " (not found)"
+	}
+	fmt.Fprintf(w, "Config File:\t%s\n", orUnset(file))
+	fmt.Fprintf(w, "Context:\t%s\n", orUnset(state.Context))
+	fmt.Fprintf(w, "Profile:\t%s\n", orUnset(state.Profile))
+	fmt.Fprintln(w)
+
+	tw := newKubectlTableWriter(w)
+	if !flags.NoHeaders {
+		tw.SetHeader([]string{"Setting", "Value", "Source"})
+	}
+	for _, s := range state.Settings {
+		tw.Append([]string{s.Name, orUnset(s.Value), string(s.Source)})
+	}
+	tw.Render()
+}
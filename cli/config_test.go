@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	cfg, loaded, err := loadConfig(path, false)
+	if err != nil || loaded || cfg == nil {
+		t.Fatalf("got %v, %t, %v, want an empty config", cfg, loaded, err)
+	}
+	if _, _, err := loadConfig(path, true); err == nil {
+		t.Error("expected error for missing file")
+	}
+	if err := os.WriteFile(path, []byte("settings:\n  top: 5\nunknown: true\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := loadConfig(path, false); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestConfigResolve(t *testing.T) {
+	cfg := &config{
+		Settings: map[string]interface{}{
+			"warning-threshold": float64(25),
+			"sort-columns":      []interface{}{"cpu-waste", "name"},
+			"show-delta":        false,
+		},
+		Profiles: map[string]map[string]interface{}{
+			"finops": {"show-delta": true},
+		},
+		Contexts: map[string]string{"prod": "finops"},
+	}
+	for _, tt := range []struct {
+		profile, context string
+		wantProfile      string
+		wantDelta        string
+		wantSource       settingSource
+	}{
+		{"", "", "", "false", sourceFile},
+		{"", "dev", "", "false", sourceFile},
+		{"", "prod", "finops", "true", sourceProfile},
+		{"finops", "", "finops", "true", sourceProfile},
+	} {
+		values, sources, profile, err := cfg.resolve(tt.profile, tt.context)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile != tt.wantProfile {
+			t.Errorf("got profile %q, want %q", profile, tt.wantProfile)
+		}
+		if values["show-delta"] != tt.wantDelta || sources["show-delta"] != tt.wantSource {
+			t.Errorf("got show-delta %s from %s, want %s from %s", values["show-delta"], sources["show-delta"], tt.wantDelta, tt.wantSource)
+		}
+		if values["warning-threshold"] != "25" || values["sort-columns"] != "cpu-waste,name" {
+			t.Errorf("unexpected values: %v", values)
+		}
+	}
+	if _, _, _, err := cfg.resolve("unknown", ""); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestApplySettings(t *testing.T) {
+	flags := DefaultFlags()
+	fs := flags.flagSet()
+
+	values := map[string]string{
+		"warning-threshold":  "25",
+		"critical-threshold": "60",
+		"top":                "10",
+	}
+	sources := map[string]settingSource{
+		"warning-threshold":  sourceFile,
+		"critical-threshold": sourceProfile,
+		"top":                sourceFile,
+	}
+	env := map[string]string{
+		"KUBECTL_VPA_RECOMMENDATION_TOP":          "3",
+		"KUBECTL_VPA_RECOMMENDATION_SORT_COLUMNS": "cpu-waste,name",
+	}
+	lookupEnv := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+	changed := func(name string) bool { return name == "critical-threshold" }
+
+	settings, err := applySettings(fs, values, sources, lookupEnv, changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flags.WarningThreshold != 25 {
+		t.Errorf("got warning threshold %g, want 25", flags.WarningThreshold)
+	}
+	if flags.CriticalThreshold != 50 {
+		t.Errorf("got critical threshold %g, want the default 50 as the flag is changed", flags.CriticalThreshold)
+	}
+	if flags.Top != 3 {
+		t.Errorf("got top %d, want 3 from env", flags.Top)
+	}
+	if len(flags.SortColumns) != 2 || flags.SortColumns[0] != "cpu-waste" {
+		t.Errorf("got sort columns %v", flags.SortColumns)
+	}
+	want := map[string]setting{
+		"warning-threshold":  {"warning-threshold", "25", sourceFile},
+		"critical-threshold": {"critical-threshold", "50", sourceFlag},
+		"top":                {"top", "3", sourceEnv},
+		"sort-columns":       {"sort-columns", "cpu-waste,name", sourceEnv},
+		"percentiles":        {"percentiles", "75,90,99", sourceDefault},
+	}
+	for _, s := range settings {
+		if w, ok := want[s.Name]; ok && s != w {
+			t.Errorf("got setting %+v, want %+v", s, w)
+		}
+	}
+	noEnv := func(string) (string, bool) { return "", false }
+
+	if _, err := applySettings(DefaultFlags().flagSet(), map[string]string{"unknown": "1"}, nil, noEnv, changed); err == nil {
+		t.Error("expected error for unknown setting")
+	}
+	if _, err := applySettings(DefaultFlags().flagSet(), map[string]string{"top": "x"}, map[string]settingSource{"top": sourceFile}, noEnv, changed); err == nil {
+		t.Error("expected error for invalid value")
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if got := envName("warning-threshold"); got != "KUBECTL_VPA_RECOMMENDATION_WARNING_THRESHOLD" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,393 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/utils/pointer"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/client"
+	"github.com/wI2L/kubectl-vpa-recommendation/pkg/analysis"
+)
+
+// coverageKinds is the list of workload kinds
+// considered by the coverage report.
+var coverageKinds = []schema.GroupKind{
+	{Group: "apps", Kind: "Deployment"},
+	{Group: "apps", Kind: "StatefulSet"},
+	{Group: "apps", Kind: "DaemonSet"},
+	{Group: "batch", Kind: "CronJob"},
+	{Group: "batch", Kind: "Job"},
+	{Group: "argoproj.io", Kind: "Rollout"},
+}
+
+const (
+	coverageCovered   = "covered"
+	coverageUncovered = "uncovered"
+	coverageMultiple  = "multiple"
+)
+
+// coverageReport represents the coverage of
+// workloads by VPA resources.
+type coverageReport struct {
+	Workloads  []coverageWorkload  `json:"workloads"`
+	Orphans    []coverageOrphan    `json:"orphans"`
+	Namespaces []coverageNamespace `json:"namespaces"`
+
+	// vpas are all the VPA resources of the
+	// selected namespaces, covering or not.
+	vpas []*vpav1.VerticalPodAutoscaler
+}
+
+// coverageWorkload represents a workload and the
+// VPA resources whose target reference it.
+type coverageWorkload struct {
+	Namespace  string   `json:"namespace"`
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Name       string   `json:"name"`
+	Status     string   `json:"status"`
+	VPAs       []string `json:"vpas,omitempty"`
+}
+
+// coverageOrphan represents a VPA resource whose target doesn't exist.
+type coverageOrphan struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Target    string `json:"target,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// coverageNamespace represents the coverage of the workloads of a namespace.
+type coverageNamespace struct {
+	Namespace  string  `json:"namespace"`
+	Workloads  int     `json:"workloads"`
+	Covered    int     `json:"covered"`
+	Percentage float64 `json:"percentage"`
+}
+
+type coverageKey struct {
+	namespace, kind, name string
+}
+
+// newCoverageReport returns the coverage of the workloads by
+// the VPA resources in the list. The resolve function is called
+// to find out whether the target of a VPA that doesn't reference
+// a listed workload exists.
+func newCoverageReport(
+	workloads []coverageWorkload,
+	vpas []*vpav1.VerticalPodAutoscaler,
+	listedKinds map[string]bool,
+	resolve func(*vpav1.VerticalPodAutoscaler) error,
+) *coverageReport {
+	r := &coverageReport{
+		Workloads: workloads,
+		Orphans:   []coverageOrphan{},
+		vpas:      vpas,
+	}
+	index := make(map[coverageKey]*coverageWorkload, len(workloads))
+	for i, w := range r.Workloads {
+		index[coverageKey{w.Namespace, w.Kind, w.Name}] = &r.Workloads[i]
+	}
+	for _, v := range vpas {
+		ref := v.Spec.TargetRef
+		if ref == nil {
+			r.Orphans = append(r.Orphans, coverageOrphan{
+				Namespace: v.Namespace,
+				Name:      v.Name,
+				Reason:    "no target reference",
+			})
+			continue
+		}
+		target := fmt.Sprintf("%s/%s", strings.ToLower(ref.Kind), ref.Name)
+
+		if w, ok := index[coverageKey{v.Namespace, ref.Kind, ref.Name}]; ok {
+			w.VPAs = append(w.VPAs, v.Name)
+			continue
+		}
+		reason := "target not found"
+		if !listedKinds[ref.Kind] {
+			err := resolve(v)
+			if err == nil {
+				continue
+			}
+			reason = err.Error()
+		}
+		r.Orphans = append(r.Orphans, coverageOrphan{
+			Namespace: v.Namespace,
+			Name:      v.Name,
+			Target:    target,
+			Reason:    reason,
+		})
+	}
+	namespaces := make(map[string]*coverageNamespace)
+
+	for i, w := range r.Workloads {
+		switch len(w.VPAs) {
+		case 0:
+			r.Workloads[i].Status = coverageUncovered
+		case 1:
+			r.Workloads[i].Status = coverageCovered
+		default:
+			r.Workloads[i].Status = coverageMultiple
+		}
+		ns, ok := namespaces[w.Namespace]
+		if !ok {
+			ns = &coverageNamespace{Namespace: w.Namespace}
+			namespaces[w.Namespace] = ns
+		}
+		ns.Workloads++
+		if len(w.VPAs) != 0 {
+			ns.Covered++
+		}
+	}
+	for _, ns := range namespaces {
+		ns.Percentage = float64(ns.Covered) / float64(ns.Workloads) * 100
+		r.Namespaces = append(r.Namespaces, *ns)
+	}
+	sort.Slice(r.Namespaces, func(i, j int) bool {
+		return r.Namespaces[i].Namespace < r.Namespaces[j].Namespace
+	})
+	sort.SliceStable(r.Workloads, func(i, j int) bool {
+		wi, wj := r.Workloads[i], r.Workloads[j]
+		if wi.Namespace != wj.Namespace {
+			return wi.Namespace < wj.Namespace
+		}
+		if wi.Kind != wj.Kind {
+			return wi.Kind < wj.Kind
+		}
+		return wi.Name < wj.Name
+	})
+	return r
+}
+
+// coverageOptions represents the options of the coverage command.
+type coverageOptions struct {
+	*CommandOptions
+
+	Output        string
+	UncoveredOnly bool
+}
+
+// newCoverageCmd returns a new command that reports
+// the coverage of the workloads by VPA resources.
+func newCoverageCmd(co *CommandOptions) *cobra.Command {
+	cvo := &coverageOptions{CommandOptions: co}
+
+	cmd := &cobra.Command{
+		Use:   "coverage [options]",
+		Short: "Report the workloads without a VPA and the VPAs without a workload",
+		Long: `Report the workloads without a VPA and the VPAs without a workload.
+
+The workloads are the Deployments, StatefulSets, DaemonSets, CronJobs,
+Jobs and Rollouts of the selected namespaces. Jobs owned by another
+controller, such as a CronJob, are ignored. The label selector applies
+to the workloads.`,
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		Run:                   cvo.Run,
+	}
+	cmd.Flags().StringVarP(&cvo.Output, flagOutput, flagOutputShorthand, cvo.Output,
+		"Output format. One of 'json'")
+	cmd.Flags().BoolVar(&cvo.UncoveredOnly, "uncovered", cvo.UncoveredOnly,
+		"Only list the workloads that are not covered by a VPA")
+
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (cvo *coverageOptions) Run(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(cvo.Complete(c, args))
+	cmdutil.CheckErr(cvo.Validate(c, args))
+	cmdutil.CheckErr(cvo.Execute())
+}
+
+// Validate ensure that required options to run the
+// command are set and valid.
+func (cvo *coverageOptions) Validate(c *cobra.Command, args []string) error {
+	switch cvo.Output {
+	case "", jsonOutput:
+	default:
+		return fmt.Errorf("unsupported output format: %s", cvo.Output)
+	}
+	return cvo.CommandOptions.Validate(c, args)
+}
+
+// Execute runs the command.
+func (cvo *coverageOptions) Execute() error {
+	r, err := cvo.newCoverage(context.Background())
+	if err != nil {
+		return err
+	}
+	if cvo.UncoveredOnly {
+		r.Workloads = r.uncoveredWorkloads()
+	}
+	if cvo.Output == jsonOutput {
+		enc := json.NewEncoder(cvo.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return r.Print(cvo.Out, cvo.Flags)
+}
+
+// newCoverage lists the workloads and the VPA resources of the
+// selected namespaces, and returns the coverage report.
+func (co *CommandOptions) newCoverage(ctx context.Context) (*coverageReport, error) {
+	if err := analysis.CheckAvailable(co.Client); err != nil {
+		return nil, err
+	}
+	ns := co.Namespace
+	if co.Flags.AllNamespaces {
+		ns = ""
+	}
+	vpas, err := co.Client.ListVPAResources(ctx, client.ListOptions{
+		Namespace:      co.Namespace,
+		AllNamespaces:  co.Flags.AllNamespaces,
+		TimeoutSeconds: pointer.Int64(int64(defaultTimeout.Seconds())),
+		Limit:          250,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var workloads []coverageWorkload
+
+	listedKinds := make(map[string]bool)
+
+	for _, gk := range coverageKinds {
+		list, err := co.Client.ListResources(ctx, gk, ns, co.Flags.LabelSelector)
+		if err != nil {
+			// Some kinds, such as Argo Rollouts, are optional,
+			// but the other errors make the report incomplete.
+			if isMissingKind(err) {
+				klog.V(4).Infof("couldn't list %s: %s", gk, err)
+			} else {
+				klog.Warningf("couldn't list %s, the uncovered workloads of this kind aren't reported: %s", gk, err)
+			}
+			continue
+		}
+		// When the workloads are filtered by labels, the target
+		// of a VPA might exist even though it wasn't listed.
+		if co.Flags.LabelSelector == "" {
+			listedKinds[gk.Kind] = true
+		}
+		for _, obj := range list {
+			if gk.Kind == "Job" && len(obj.GetOwnerReferences()) != 0 {
+				continue
+			}
+			workloads = append(workloads, coverageWorkload{
+				Namespace:  obj.GetNamespace(),
+				APIVersion: obj.GetAPIVersion(),
+				Kind:       gk.Kind,
+				Name:       obj.GetName(),
+			})
+		}
+	}
+	r := newCoverageReport(workloads, vpas, listedKinds, func(v *vpav1.VerticalPodAutoscaler) error {
+		_, err := co.Client.GetVPATarget(ctx, v.Spec.TargetRef, v.Namespace)
+		return err
+	})
+	return r, nil
+}
+
+// isMissingKind returns whether the error of the list of a
+// kind of workloads is due to the kind not being served by
+// the cluster, such as a CRD that isn't installed.
+func isMissingKind(err error) bool {
+	var (
+		kindErr     *meta.NoKindMatchError
+		resourceErr *meta.NoResourceMatchError
+	)
+	return errors.As(err, &kindErr) || errors.As(err, &resourceErr) || apierrors.IsNotFound(err)
+}
+
+// uncoveredWorkloads returns the workloads
+// that are not covered by a VPA resource.
+func (r *coverageReport) uncoveredWorkloads() []coverageWorkload {
+	var ret []coverageWorkload
+	for _, w := range r.Workloads {
+		if w.Status == coverageUncovered {
+			ret = append(ret, w)
+		}
+	}
+	return ret
+}
+
+// Print writes the coverage report as tables to w.
+func (r *coverageReport) Print(w io.Writer, flags *Flags) error {
+	if len(r.Workloads) == 0 {
+		fmt.Fprintln(w, "No workloads found.")
+	} else {
+		tw := newKubectlTableWriter(w)
+		tw.SetHeader([]string{hdrNamespace, "Kind", hdrName, "Status", "VPAs"})
+
+		for _, wl := range r.Workloads {
+			vpas := tableUnsetCell
+			if len(wl.VPAs) != 0 {
+				vpas = strings.Join(wl.VPAs, ",")
+			}
+			tw.Append([]string{wl.Namespace, wl.Kind, wl.Name, formatCoverageStatus(wl.Status, flags), vpas})
+		}
+		tw.Render()
+	}
+	if len(r.Orphans) != 0 {
+		fmt.Fprintln(w)
+		tw := newKubectlTableWriter(w)
+		tw.SetHeader([]string{hdrNamespace, "Orphan VPA", hdrTarget, "Reason"})
+
+		for _, o := range r.Orphans {
+			target := o.Target
+			if target == "" {
+				target = tableUnsetCell
+			}
+			tw.Append([]string{o.Namespace, o.Name, target, o.Reason})
+		}
+		tw.Render()
+	}
+	if len(r.Namespaces) != 0 {
+		fmt.Fprintln(w)
+		tw := newKubectlTableWriter(w)
+		tw.SetHeader([]string{hdrNamespace, "Workloads", "Covered", "% Coverage"})
+
+		for _, ns := range r.Namespaces {
+			tw.Append([]string{
+				ns.Namespace,
+				fmt.Sprintf("%d", ns.Workloads),
+				fmt.Sprintf("%d", ns.Covered),
+				fmt.Sprintf("%.2f", ns.Percentage),
+			})
+		}
+		tw.Render()
+	}
+	return nil
+}
+
+func formatCoverageStatus(status string, flags *Flags) string {
+	if termenv.EnvNoColor() || flags.NoColors {
+		return status
+	}
+	p := termenv.ColorProfile()
+	s := termenv.String(status)
+
+	switch status {
+	case coverageCovered:
+		s = s.Foreground(p.Color("#A8CC8C"))
+	case coverageMultiple:
+		s = s.Foreground(p.Color("#DBAB79"))
+	default:
+		s = s.Foreground(p.Color("#E88388"))
+	}
+	return s.String()
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+func TestNewCoverageReport(t *testing.T) {
+	newVPA := func(ns, name, kind, target string) *vpav1.VerticalPodAutoscaler {
+		v := &vpav1.VerticalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		}
+		if kind != "" {
+			v.Spec.TargetRef = &autoscalingv1.CrossVersionObjectReference{Kind: kind, Name: target}
+		}
+		return v
+	}
+	workloads := []coverageWorkload{
+		{Namespace: "athens", Kind: "Deployment", Name: "zeus"},
+		{Namespace: "athens", Kind: "StatefulSet", Name: "athena"},
+		{Namespace: "sparta", Kind: "Deployment", Name: "hera"},
+		{Namespace: "sparta", Kind: "DaemonSet", Name: "hermes"},
+	}
+	vpas := []*vpav1.VerticalPodAutoscaler{
+		newVPA("athens", "zeus", "Deployment", "zeus"),
+		newVPA("sparta", "hera-1", "Deployment", "hera"),
+		newVPA("sparta", "hera-2", "Deployment", "hera"),
+		newVPA("sparta", "ares", "Deployment", "ares"),
+		newVPA("sparta", "apollo", "ReplicaSet", "apollo"),
+		newVPA("sparta", "artemis", "ReplicaSet", "artemis"),
+		newVPA("sparta", "orphan", "", ""),
+	}
+	listedKinds := map[string]bool{"Deployment": true, "StatefulSet": true, "DaemonSet": true}
+
+	r := newCoverageReport(workloads, vpas, listedKinds, func(v *vpav1.VerticalPodAutoscaler) error {
+		if v.Name == "artemis" {
+			return errors.New("resource not found")
+		}
+		return nil
+	})
+	for i, want := range []string{
+		coverageCovered,   // athens/zeus
+		coverageUncovered, // athens/athena
+		coverageUncovered, // sparta/hermes
+		coverageMultiple,  // sparta/hera
+	} {
+		if got := r.Workloads[i].Status; got != want {
+			t.Errorf("workload %s: got status %s, want %s", r.Workloads[i].Name, got, want)
+		}
+	}
+	if len(r.Orphans) != 3 {
+		t.Fatalf("got %d orphans, want 3", len(r.Orphans))
+	}
+	for i, want := range []string{"ares", "artemis", "orphan"} {
+		if got := r.Orphans[i].Name; got != want {
+			t.Errorf("got orphan %s, want %s", got, want)
+		}
+	}
+	if len(r.Namespaces) != 2 {
+		t.Fatalf("got %d namespaces, want 2", len(r.Namespaces))
+	}
+	for i, want := range []float64{50, 50} {
+		if got := r.Namespaces[i].Percentage; got != want {
+			t.Errorf("namespace %s: got coverage %.2f, want %.2f", r.Namespaces[i].Namespace, got, want)
+		}
+	}
+}
+
+func TestIsMissingKind(t *testing.T) {
+	gr := schema.GroupResource{Group: "argoproj.io", Resource: "rollouts"}
+
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("couldn't find mapping: %w", &meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "argoproj.io", Kind: "Rollout"}}), true},
+		{fmt.Errorf("couldn't list: %w", apierrors.NewNotFound(gr, "")), true},
+		{fmt.Errorf("couldn't list: %w", apierrors.NewForbidden(gr, "", errors.New("denied"))), false},
+		{fmt.Errorf("couldn't list: %w", apierrors.NewTimeoutError("timeout", 1)), false},
+	} {
+		if got := isMissingKind(tt.err); got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/duration"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"k8s.io/kubectl/pkg/cmd/get"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/pkg/analysis"
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+// explainOptions represents the options of the explain command.
+type explainOptions struct {
+	*CommandOptions
+}
+
+// newExplainCmd returns a new command that prints
+// a detailed report of the recommendation of a VPA.
+func newExplainCmd(co *CommandOptions, f cmdutil.Factory) *cobra.Command {
+	eo := &explainOptions{CommandOptions: co}
+
+	cmd := &cobra.Command{
+		Use:   "explain NAME [options]",
+		Short: "Print a detailed report of the recommendation of a VPA",
+		Long: `Print a detailed report of the recommendation of a VPA.
+
+The report covers the spec of the VPA (update policy, resource policy and
+recommenders), its conditions, the four bounds of the recommendation of
+each container, the resolved target and the pods inspected to read the
+requests, the source of the requests compared with the recommendation,
+and the computation of the differences.`,
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		Run:                   eo.Run,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, tc string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			comps := get.CompGetResource(f, cmd, vpaPlural, tc)
+			return comps, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (eo *explainOptions) Run(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(eo.Complete(c, args))
+	cmdutil.CheckErr(eo.Validate(c, args))
+	cmdutil.CheckErr(eo.Execute())
+}
+
+// Execute runs the command.
+func (eo *explainOptions) Execute() error {
+	ctx := context.Background()
+
+	vpas, err := eo.listVPAs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(vpas) == 0 {
+		eo.printNoResources()
+		return nil
+	}
+	v := vpas[0]
+	rt := eo.Flags.RecommendationType
+
+	writeVPASpec(eo.Out, v)
+	writeVPAConditions(eo.Out, v)
+	writeVPARecommendations(eo.Out, v)
+
+	fmt.Fprintln(eo.Out)
+	fmt.Fprintln(eo.Out, "Target:")
+
+	ref := v.Spec.TargetRef
+	if ref == nil {
+		fmt.Fprintln(eo.Out, "  The VPA has no target reference.")
+		return nil
+	}
+	fmt.Fprintf(eo.Out, "  Reference:\t%s %s/%s\n", ref.APIVersion, ref.Kind, ref.Name)
+
+	tc, err := vpa.NewTargetController(ctx, eo.Client, ref, v.Namespace, eo.Flags.RequestsSource)
+	if err != nil {
+		fmt.Fprintf(eo.Out, "  Resolution:\tfailed: %s\n", err)
+		return nil
+	}
+	writeTargetResolution(eo.Out, tc)
+	writeComparison(eo.Out, v, tc, rt, eo.Flags)
+
+	return nil
+}
+
+func writeVPASpec(w io.Writer, v *vpav1.VerticalPodAutoscaler) {
+	fmt.Fprintf(w, "Name:\t\t%s\n", v.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", v.Namespace)
+
+	fmt.Fprintln(w, "Update Policy:")
+	fmt.Fprintf(w, "  Mode:\t\t%s\n", updateModeFromSpec(v.Spec.UpdatePolicy))
+	minReplicas := tableUnsetCell
+	if p := v.Spec.UpdatePolicy; p != nil && p.MinReplicas != nil {
+		minReplicas = fmt.Sprintf("%d", *p.MinReplicas)
+	}
+	fmt.Fprintf(w, "  Min Replicas:\t%s\n", minReplicas)
+
+	fmt.Fprintf(w, "Recommenders:\t%s\n", strings.Join(analysis.RecommenderNames(v), ", "))
+
+	fmt.Fprintln(w, "Resource Policy:")
+	if v.Spec.ResourcePolicy == nil || len(v.Spec.ResourcePolicy.ContainerPolicies) == 0 {
+		fmt.Fprintln(w, "  <none>")
+		return
+	}
+	tw := newKubectlTableWriter(w)
+	tw.SetHeader([]string{"Container", "Mode", "Controlled Resources", "Controlled Values", "Min Allowed", "Max Allowed"})
+
+	for _, p := range v.Spec.ResourcePolicy.ContainerPolicies {
+		mode := string(vpav1.ContainerScalingModeAuto)
+		if p.Mode != nil {
+			mode = string(*p.Mode)
+		}
+		resources := "cpu, memory"
+		if p.ControlledResources != nil {
+			names := make([]string, 0, len(*p.ControlledResources))
+			for _, name := range *p.ControlledResources {
+				names = append(names, string(name))
+			}
+			resources = strings.Join(names, ", ")
+		}
+		values := string(vpav1.ContainerControlledValuesRequestsAndLimits)
+		if p.ControlledValues != nil {
+			values = string(*p.ControlledValues)
+		}
+		tw.Append([]string{
+			p.ContainerName,
+			mode,
+			resources,
+			values,
+			formatResourceList(p.MinAllowed),
+			formatResourceList(p.MaxAllowed),
+		})
+	}
+	tw.Render()
+}
+
+func writeVPAConditions(w io.Writer, v *vpav1.VerticalPodAutoscaler) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Conditions:")
+
+	if len(v.Status.Conditions) == 0 {
+		fmt.Fprintln(w, "  <none>")
+		return
+	}
+	tw := newKubectlTableWriter(w)
+	tw.SetHeader([]string{"Type", "Status", "Reason", "Message", "Last Transition"})
+
+	for _, c := range v.Status.Conditions {
+		last := tableUnsetCell
+		if !c.LastTransitionTime.IsZero() {
+			last = duration.HumanDuration(time.Since(c.LastTransitionTime.Time)) + " ago"
+		}
+		tw.Append([]string{
+			string(c.Type),
+			string(c.Status),
+			orUnset(c.Reason),
+			orUnset(c.Message),
+			last,
+		})
+	}
+	tw.Render()
+}
+
+func writeVPARecommendations(w io.Writer, v *vpav1.VerticalPodAutoscaler) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Recommendations:")
+
+	if v.Status.Recommendation == nil || len(v.Status.Recommendation.ContainerRecommendations) == 0 {
+		fmt.Fprintln(w, "  <none>")
+		return
+	}
+	tw := newKubectlTableWriter(w)
+	tw.SetHeader([]string{"Container", "Resource", "Lower Bound", "Target", "Uncapped Target", "Upper Bound"})
+
+	for _, cr := range v.Status.Recommendation.ContainerRecommendations {
+		seen := make(map[corev1.ResourceName]bool)
+		var names []corev1.ResourceName
+
+		for _, rl := range []corev1.ResourceList{cr.LowerBound, cr.Target, cr.UncappedTarget, cr.UpperBound} {
+			for name := range rl {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+		vpa.SortResourceNames(names)
+
+		for _, name := range names {
+			row := []string{cr.ContainerName, string(name)}
+			for _, rl := range []corev1.ResourceList{cr.LowerBound, cr.Target, cr.UncappedTarget, cr.UpperBound} {
+				row = append(row, formatQuantity(quantityOf(rl, name)))
+			}
+			tw.Append(row)
+		}
+	}
+	tw.Render()
+}
+
+func writeTargetResolution(w io.Writer, tc *vpa.TargetController) {
+	fmt.Fprintf(w, "  Resolved:\t%s %s/%s\n", tc.GroupVersionKind.GroupVersion(), tc.GroupVersionKind.Kind, tc.Name)
+
+	replicas := "1 (no replicas field)"
+	if n, err := tc.ReplicasCount(); err == nil {
+		replicas = fmt.Sprintf("%d", n)
+	}
+	fmt.Fprintf(w, "  Replicas:\t%s\n", replicas)
+	fmt.Fprintf(w, "  Selector:\t%s\n", orUnset(tc.Selector()))
+	fmt.Fprintln(w, "  The target was fetched from its reference, and its pods were listed with")
+	fmt.Fprintln(w, "  its label selector, keeping those owned by a controller of the target.")
+
+	pods := tc.Pods()
+	_, sourcePod := tc.RequestsSource()
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Pods (%d):\n", len(pods))
+	if len(pods) == 0 {
+		fmt.Fprintln(w, "  <none>")
+		return
+	}
+	tw := newKubectlTableWriter(w)
+	tw.SetHeader([]string{"Pod", "Age", "Node", "Phase", "Container", hdrCPURequest, hdrMemRequest})
+
+	for _, pod := range pods {
+		name := pod.Name
+		if name == sourcePod {
+			name += " (source)"
+		}
+		age := duration.HumanDuration(time.Since(pod.CreationTimestamp.Time))
+
+		containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for i, c := range containers {
+			row := []string{"", "", "", "", c.Name, formatQuantity(c.Resources.Requests.Cpu()), formatQuantity(c.Resources.Requests.Memory())}
+			if i == 0 {
+				row[0], row[1], row[2], row[3] = name, age, orUnset(pod.Spec.NodeName), string(pod.Status.Phase)
+			}
+			tw.Append(row)
+		}
+	}
+	tw.Render()
+}
+
+func writeComparison(w io.Writer, v *vpav1.VerticalPodAutoscaler, tc *vpa.TargetController, rt vpa.RecommendationType, flags *Flags) {
+	source, sourcePod := tc.RequestsSource()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Comparison:")
+	if sourcePod != "" {
+		fmt.Fprintf(w, "  Requests Source:\t%s (pod %s)\n", source, sourcePod)
+	} else {
+		fmt.Fprintf(w, "  Requests Source:\t%s\n", source)
+	}
+	if tc.HasMixedRequests() {
+		fmt.Fprintln(w, "  The pods of the target disagree on their requests.")
+	}
+	fmt.Fprintf(w, "  Recommendation:\t%s\n", rt)
+
+	recs := make(map[string]vpa.ResourceQuantities)
+	if v.Status.Recommendation != nil {
+		for _, cr := range v.Status.Recommendation.ContainerRecommendations {
+			recs[cr.ContainerName] = vpa.ContainerRecommendations(cr, rt)
+		}
+	}
+	names := flags.resourceNames()
+
+	tw := newKubectlTableWriter(w)
+	headers := []string{"Container", "Type"}
+	for _, name := range names {
+		req, rec, _ := resourceHeaders(name)
+		headers = append(headers, req, rec)
+	}
+	tw.SetHeader(headers)
+
+	for _, c := range tc.Containers() {
+		row := []string{c.Name, string(c.Type)}
+		rec, ok := recs[c.Name]
+		for _, name := range names {
+			recCell := tableUnsetCell
+			switch {
+			case ok:
+				recCell = formatQuantity(rec.Get(name))
+			case c.Type != vpa.ContainerRegular:
+				recCell = "(request kept)"
+			}
+			row = append(row, formatQuantity(c.Requests.Get(name)), recCell)
+		}
+		tw.Append(row)
+	}
+	tw.Render()
+
+	if overhead := tc.Overhead(); len(overhead) != 0 {
+		fmt.Fprintf(w, "  Pod Overhead:\t%s\n", formatResourceList(overhead))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Differences:")
+	fmt.Fprintln(w, "  The effective requests are the sum of the requests of the regular and sidecar")
+	fmt.Fprintln(w, "  containers, or of the largest init container and the sidecars started before")
+	fmt.Fprintln(w, "  it if greater, plus the pod overhead.")
+
+	rqs := tc.GetRequests()
+	rcs := tc.GetRecommendations(v, rt)
+
+	for _, name := range names {
+		req, rec := rqs.Get(name), rcs.Get(name)
+		fmt.Fprintf(w, "  %s:\t%s\n", name, formatDiffArithmetic(req, rec, vpa.DiffQuantitiesAsPercent(req, rec)))
+	}
+}
+
+// formatDiffArithmetic formats the computation of the
+// difference between a request and a recommendation.
+func formatDiffArithmetic(req, rec *resource.Quantity, diff *float64) string {
+	switch {
+	case rec == nil || rec.IsZero():
+		return fmt.Sprintf("request %s, no recommendation", formatQuantity(req))
+	case req == nil || req.IsZero():
+		return fmt.Sprintf("no request, recommendation %s", formatQuantity(rec))
+	case diff == nil:
+		return tableUnsetCell
+	}
+	return fmt.Sprintf("(%s - %s) / %s * 100 = %+.2f%%", req, rec, rec, *diff)
+}
+
+// formatResourceList formats a resource list as
+// comma-separated name=quantity pairs, sorted by name.
+func formatResourceList(rl corev1.ResourceList) string {
+	if len(rl) == 0 {
+		return tableUnsetCell
+	}
+	names := make([]corev1.ResourceName, 0, len(rl))
+	for name := range rl {
+		names = append(names, name)
+	}
+	vpa.SortResourceNames(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		q := rl[name]
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, q.String()))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func orUnset(s string) string {
+	if s == "" {
+		return tableUnsetCell
+	}
+	return s
+}
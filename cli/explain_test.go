@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func TestFormatDiffArithmetic(t *testing.T) {
+	q := func(s string) *resource.Quantity {
+		v := resource.MustParse(s)
+		return &v
+	}
+	for _, tt := range []struct {
+		req, rec *resource.Quantity
+		want     string
+	}{
+		{q("500m"), q("250m"), "(500m - 250m) / 250m * 100 = +100.00%"},
+		{q("128Mi"), q("256Mi"), "(128Mi - 256Mi) / 256Mi * 100 = -50.00%"},
+		{q("1"), nil, "request 1, no recommendation"},
+		{nil, q("1"), "no request, recommendation 1"},
+	} {
+		diff := vpa.DiffQuantitiesAsPercent(tt.req, tt.rec)
+		if got := formatDiffArithmetic(tt.req, tt.rec, diff); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestFormatResourceList(t *testing.T) {
+	rl := corev1.ResourceList{
+		corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+		corev1.ResourceMemory:           resource.MustParse("64Mi"),
+		corev1.ResourceCPU:              resource.MustParse("100m"),
+	}
+	want := "cpu=100m, memory=64Mi, ephemeral-storage=1Gi"
+	if got := formatResourceList(rl); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := formatResourceList(nil); got != tableUnsetCell {
+		t.Errorf("got %q, want %q", got, tableUnsetCell)
+	}
+}
+
+func TestWriteVPASpec(t *testing.T) {
+	mode := vpav1.UpdateModeAuto
+	off := vpav1.ContainerScalingModeOff
+
+	v := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{UpdateMode: &mode},
+			ResourcePolicy: &vpav1.PodResourcePolicy{
+				ContainerPolicies: []vpav1.ContainerResourcePolicy{
+					{ContainerName: "sidecar", Mode: &off},
+				},
+			},
+			Recommenders: []*vpav1.VerticalPodAutoscalerRecommenderSelector{{Name: "custom"}},
+		},
+		Status: vpav1.VerticalPodAutoscalerStatus{
+			Conditions: []vpav1.VerticalPodAutoscalerCondition{{
+				Type:   vpav1.RecommendationProvided,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+	}
+	var buf bytes.Buffer
+	writeVPASpec(&buf, v)
+	writeVPAConditions(&buf, v)
+	writeVPARecommendations(&buf, v)
+
+	out := buf.String()
+	for _, s := range []string{"Auto", "custom", "sidecar", "Off", "RecommendationProvided", "<none>"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected output to contain %q:\n%s", s, out)
+		}
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
 
 	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
 )
@@ -31,18 +33,41 @@ const (
 	flagRecommendationType      = "recommendation-type"
 	flagWarningThreshold        = "warning-threshold"
 	flagCriticalThreshold       = "critical-threshold"
+	flagBaseline                = "baseline"
+	flagShowBaselined           = "show-baselined"
+	flagRequestsSource          = "requests-source"
+	flagShowVariance            = "show-variance"
+	flagResources               = "resources"
+	flagRoundingRules           = "rounding-rules"
+	flagShowSuggested           = "show-suggested"
+	flagShowDelta               = "show-delta"
+	flagTop                     = "top"
+	flagGroupBy                 = "group-by"
+	flagGroupTotals             = "group-totals"
+	flagPercentiles             = "percentiles"
+	flagShowHistogram           = "show-histogram"
+	flagShowRisk                = "show-risk"
+	flagRiskWindow              = "risk-window"
+	flagRestartThreshold        = "restart-threshold"
+	flagRecommender             = "recommender"
+	flagMaxAge                  = "max-age"
+	flagThresholdRules          = "threshold-rules"
 )
 
 const (
 	wideOutput      = "wide"
 	splitOutput     = "split"
 	splitWideOutput = "split-wide"
+	jsonOutput      = "json"
 )
 
 var (
 	defaultSortOrder          = orderAsc
 	defaultSortColumns        = []string{"namespace", "name"}
 	defaultRecommendationType = vpa.RecommendationTarget
+	defaultRequestsSource     = vpa.SourceMajority
+	defaultResources          = []string{"cpu", "memory"}
+	defaultPercentiles        = []float64{75, 90, 99}
 )
 
 // Flags represents the common command flags.
@@ -62,9 +87,30 @@ type Flags struct {
 	RecommendationType vpa.RecommendationType
 	WarningThreshold   float64
 	CriticalThreshold  float64
+	Baseline           string
+	ShowBaselined      bool
+	RequestsSource     vpa.RequestsSource
+	ShowVariance       bool
+	Resources          []string
+	RoundingRules      string
+	ShowSuggested      bool
+	ShowDelta          bool
+	Top                int
+	GroupBy            string
+	GroupTotals        bool
+	Percentiles        []float64
+	ShowHistogram      bool
+	ShowRisk           bool
+	RiskWindow         time.Duration
+	RestartThreshold   int32
+	Recommender        string
+	MaxAge             time.Duration
+	ThresholdRules     string
 
-	wide  bool
-	split bool
+	wide        bool
+	split       bool
+	groupBy     *groupBy
+	thresholder *vpa.Thresholder
 }
 
 // DefaultFlags returns default command flags.
@@ -73,17 +119,50 @@ func DefaultFlags() *Flags {
 		SortOrder:          defaultSortOrder,
 		SortColumns:        defaultSortColumns,
 		RecommendationType: defaultRecommendationType,
+		RequestsSource:     defaultRequestsSource,
+		Resources:          defaultResources,
+		Percentiles:        defaultPercentiles,
+		RiskWindow:         7 * 24 * time.Hour,
+		RestartThreshold:   5,
 		WarningThreshold:   20,
 		CriticalThreshold:  50,
 	}
 	return f
 }
 
-// AddFlags binds the command flags to the given pflag.FlagSet.
-func (f *Flags) AddFlags(flags *pflag.FlagSet) {
+// AddPersistentFlags binds the flags shared by the command
+// and its subcommands to the given pflag.FlagSet.
+func (f *Flags) AddPersistentFlags(flags *pflag.FlagSet) {
 	flags.BoolVarP(&f.AllNamespaces, flagAllNamespaces, flagAllNamespacesShorthand, f.AllNamespaces,
 		"List VPA resources in all namespaces")
 
+	flags.StringVarP(&f.LabelSelector, flagLabelSelector, flagLabelSelectorShorthand, f.LabelSelector,
+		"Selector (label query) to filter on, supports '=', '==', and '!=' (e.g. -l key1=value1,key2=value2)")
+
+	flags.StringVar(&f.FieldSelector, flagFieldSelector, f.FieldSelector,
+		"Selector (field query) to filter on, supports '=', '==', and '!=' (e.g. --field-selector key1=value1,key2=value2)")
+
+	flags.Var(&f.RecommendationType, flagRecommendationType,
+		fmt.Sprintf("The type of recommendation to use in comparisons. One of: %s", strings.Join(recommendationTypeFlagValues(), ", ")))
+
+	flags.BoolVar(&f.NoColors, flagNoColors, f.NoColors,
+		"Do not use colors to highlight increase/decrease percentage values")
+
+	flags.Var(&f.RequestsSource, flagRequestsSource,
+		"The source of the requests compared to the recommendations when the pods of a target disagree. One of: 'template', 'oldest', 'newest', 'majority'")
+
+	flags.StringVar(&f.RoundingRules, flagRoundingRules, f.RoundingRules,
+		"Path to a file of rules to round the recommendations into suggested values")
+
+	flags.StringVar(&f.ThresholdRules, flagThresholdRules, f.ThresholdRules,
+		"Path to a file of rules of the warning and critical thresholds per namespace, label selector or target kind")
+
+	flags.StringVar(&f.Recommender, flagRecommender, f.Recommender,
+		"Only list the VPA resources that select the named recommender, 'default' matching those that select none")
+}
+
+// AddFlags binds the command flags to the given pflag.FlagSet.
+func (f *Flags) AddFlags(flags *pflag.FlagSet) {
 	flags.BoolVar(&f.ShowNamespace, flagShowNamespace, f.ShowNamespace,
 		"Show namespace as the first column")
 
@@ -93,9 +172,6 @@ func (f *Flags) AddFlags(flags *pflag.FlagSet) {
 	flags.BoolVarP(&f.ShowContainers, flagShowContainers, flagShowContainersShorthand, f.ShowContainers,
 		"Display containers recommendations for each VPA resource")
 
-	flags.BoolVar(&f.NoColors, flagNoColors, f.NoColors,
-		"Do not use colors to highlight increase/decrease percentage values")
-
 	flags.BoolVar(&f.NoHeaders, flagNoHeaders, f.NoHeaders,
 		"Do not print table headers")
 
@@ -105,18 +181,9 @@ func (f *Flags) AddFlags(flags *pflag.FlagSet) {
 	flags.StringSliceVar(&f.SortColumns, flagSortColumns, f.SortColumns,
 		fmt.Sprintf("Comma-separated list of column names for sorting the table. Any of: %s", strings.Join(sortColumnsFlagValues(), ", ")))
 
-	flags.StringVarP(&f.LabelSelector, flagLabelSelector, flagLabelSelectorShorthand, f.LabelSelector,
-		"Selector (label query) to filter on, supports '=', '==', and '!=' (e.g. -l key1=value1,key2=value2)")
-
-	flags.StringVar(&f.FieldSelector, flagFieldSelector, f.FieldSelector,
-		"Selector (field query) to filter on, supports '=', '==', and '!=' (e.g. --field-selector key1=value1,key2=value2)")
-
 	flags.StringVarP(&f.Output, flagOutput, flagOutputShorthand, f.Output,
 		"Output format. One of 'wide', 'split', 'split-wide'")
 
-	flags.Var(&f.RecommendationType, flagRecommendationType,
-		fmt.Sprintf("The type of recommendation to use in comparisons. One of: %s", strings.Join(recommendationTypeFlagValues(), ", ")))
-
 	flags.BoolVar(&f.ShowStats, flagShowStats, f.ShowStats,
 		"Show statistics about all VPA recommendations and requests")
 
@@ -125,6 +192,57 @@ func (f *Flags) AddFlags(flags *pflag.FlagSet) {
 
 	flags.Float64Var(&f.CriticalThreshold, flagCriticalThreshold, f.CriticalThreshold,
 		"Critical threshold of percentage difference for colored output")
+
+	flags.StringVar(&f.Baseline, flagBaseline, f.Baseline,
+		"Path to a baseline file of accepted differences to hide from the output and statistics")
+
+	flags.BoolVar(&f.ShowBaselined, flagShowBaselined, f.ShowBaselined,
+		"Show the rows accepted by the baseline file as dimmed instead of hiding them")
+
+	flags.DurationVar(&f.MaxAge, flagMaxAge, f.MaxAge,
+		"Hide the VPA resources whose recommendation wasn't updated for longer than the duration")
+
+	flags.BoolVar(&f.ShowVariance, flagShowVariance, f.ShowVariance,
+		"Show the variance of the containers requests across the pods of the targets whose pods disagree")
+
+	flags.StringSliceVar(&f.Resources, flagResources, f.Resources,
+		"Comma-separated list of the resources to compare, such as cpu, memory, ephemeral-storage, or an extended resource name")
+
+	flags.BoolVar(&f.ShowSuggested, flagShowSuggested, f.ShowSuggested,
+		"Show the recommendations rounded by the rounding rules, or rounded up to 50m of CPU and 64Mi of memory by default")
+
+	flags.BoolVar(&f.ShowDelta, flagShowDelta, f.ShowDelta,
+		"Show the absolute difference between the requests and the recommendations, per pod and multiplied by the replicas")
+
+	flags.IntVar(&f.Top, flagTop, f.Top,
+		"Only print the first N rows of each table after sorting")
+
+	flags.StringVar(&f.GroupBy, flagGroupBy, f.GroupBy,
+		"Aggregate the requests and recommendations per group. One of: 'namespace', 'kind', 'label:<key>', 'annotation:<key>'")
+
+	flags.BoolVar(&f.GroupTotals, flagGroupTotals, f.GroupTotals,
+		"Add a row of the totals weighted by the replicas to each table in split mode")
+
+	flags.BoolVar(&f.ShowHistogram, flagShowHistogram, f.ShowHistogram,
+		"Show the distribution of the differences as a histogram with the statistics")
+
+	flags.BoolVar(&f.ShowRisk, flagShowRisk, f.ShowRisk,
+		"Show the risk of applying the recommendations, from the OOM kills, restarts and back-off events of the containers")
+
+	flags.DurationVar(&f.RiskWindow, flagRiskWindow, f.RiskWindow,
+		"The period of time during which the OOM kills and events of the containers are considered recent")
+
+	flags.Int32Var(&f.RestartThreshold, flagRestartThreshold, f.RestartThreshold,
+		"The number of restarts of a container across its pods from which the risk is raised")
+
+	f.AddStatsFlags(flags)
+}
+
+// AddStatsFlags binds the flags of the statistics
+// to the given pflag.FlagSet.
+func (f *Flags) AddStatsFlags(flags *pflag.FlagSet) {
+	flags.Float64SliceVar(&f.Percentiles, flagPercentiles, f.Percentiles,
+		"Comma-separated list of the percentiles of the requests and recommendations shown with the statistics")
 }
 
 // Tidy post-processes the flags.
@@ -132,6 +250,32 @@ func (f *Flags) Tidy() error {
 	if f.CriticalThreshold <= f.WarningThreshold {
 		return fmt.Errorf("critical threshold must be strictly greater than warning")
 	}
+	for _, p := range f.Percentiles {
+		if p < 0 || p > 100 {
+			return fmt.Errorf("percentile %g must be between 0 and 100", p)
+		}
+	}
+	if f.Top < 0 {
+		return fmt.Errorf("top must be a positive number")
+	}
+	if f.MaxAge < 0 {
+		return fmt.Errorf("max age must be a positive duration")
+	}
+	for _, r := range f.Resources {
+		if strings.TrimSpace(r) == "" {
+			return fmt.Errorf("resource names cannot be empty")
+		}
+	}
+	gb, err := parseGroupBy(f.GroupBy)
+	if err != nil {
+		return err
+	}
+	f.groupBy = gb
+
+	if f.thresholder, err = newThresholder(f); err != nil {
+		return err
+	}
+
 	switch f.Output {
 	case wideOutput:
 		f.wide = true
@@ -144,6 +288,25 @@ func (f *Flags) Tidy() error {
 	return nil
 }
 
+// resourceNames returns the names of the resources to compare.
+func (f *Flags) resourceNames() []corev1.ResourceName {
+	resources := f.Resources
+	if len(resources) == 0 {
+		resources = defaultResources
+	}
+	names := make([]corev1.ResourceName, 0, len(resources))
+	for _, r := range resources {
+		names = append(names, corev1.ResourceName(strings.TrimSpace(r)))
+	}
+	return names
+}
+
+// suggested returns whether the suggested
+// recommendations columns are displayed.
+func (f *Flags) suggested() bool {
+	return f.ShowSuggested || f.RoundingRules != ""
+}
+
 func sortColumnsFlagValues() []string {
 	keys := make([]string, 0, len(columnLessFunc))
 	for k := range columnLessFunc {
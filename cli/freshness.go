@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"time"
+
+	"github.com/muesli/termenv"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+// olderThan returns whether the recommendation of the row is known
+// to be older than maxAge. Rows of unknown age are never older, nor
+// are the rows whose age comes from the condition of the VPA, since
+// the recommendation may have been updated after its transition.
+func (tr tableRow) olderThan(maxAge time.Duration, now time.Time) bool {
+	return tr.RecommendedAtSource == vpa.RecommendationTimeCheckpoint &&
+		tr.RecommendedAt != nil && now.Sub(tr.RecommendedAt.Time) > maxAge
+}
+
+// withMaxAge returns the rows of the table whose
+// recommendation isn't older than maxAge.
+func (t table) withMaxAge(maxAge time.Duration, now time.Time) table {
+	ret := make(table, 0, len(t))
+	for _, row := range t {
+		if !row.olderThan(maxAge, now) {
+			ret = append(ret, row)
+		}
+	}
+	return ret
+}
+
+// compareAgesMissingLast compares the ages of two recommendations,
+// from their update times. The rows of unknown age are placed last.
+func compareAgesMissingLast(t1, t2 *metav1.Time) int {
+	switch {
+	case t1 == nil && t2 == nil:
+		return 0
+	case t1 == nil:
+		return placeLast
+	case t2 == nil:
+		return placeFirst
+	case t1.Equal(t2):
+		return 0
+	case t2.Before(t1):
+		// The most recent update is the youngest.
+		return -1
+	}
+	return 1
+}
+
+// formatAge formats the age of a recommendation. The recommendations
+// updated before the rollout of the current pod template are marked,
+// and highlighted in red.
+func formatAge(recommendedAt *metav1.Time, outdated bool, now time.Time, flags *Flags) string {
+	if recommendedAt == nil {
+		return tableUnsetCell
+	}
+	s := duration.HumanDuration(now.Sub(recommendedAt.Time))
+	if !outdated {
+		return s
+	}
+	s += " (template changed)"
+
+	if termenv.EnvNoColor() || flags.NoColors {
+		return s
+	}
+	p := termenv.ColorProfile()
+
+	return termenv.String(s).Foreground(p.Color("#E88388")).String()
+}
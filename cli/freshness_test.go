@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func TestTableWithMaxAge(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	at := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(now.Add(-d))
+		return &t
+	}
+	tbl := table{
+		{Name: "fresh", RecommendedAt: at(time.Hour), RecommendedAtSource: vpa.RecommendationTimeCheckpoint},
+		{Name: "stale", RecommendedAt: at(30 * 24 * time.Hour), RecommendedAtSource: vpa.RecommendationTimeCheckpoint},
+		{Name: "condition", RecommendedAt: at(30 * 24 * time.Hour), RecommendedAtSource: vpa.RecommendationTimeCondition},
+		{Name: "unknown"},
+	}
+	got := tbl.withMaxAge(24*time.Hour, now)
+	if len(got) != 3 || got[0].Name != "fresh" || got[1].Name != "condition" || got[2].Name != "unknown" {
+		var names []string
+		for _, r := range got {
+			names = append(names, r.Name)
+		}
+		t.Errorf("got rows %v, want [fresh condition unknown]", names)
+	}
+}
+
+func TestSortTableByAge(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	at := func(d time.Duration) *metav1.Time {
+		t := metav1.NewTime(now.Add(-d))
+		return &t
+	}
+	tbl := table{
+		{Name: "unknown"},
+		{Name: "old", RecommendedAt: at(48 * time.Hour)},
+		{Name: "young", RecommendedAt: at(time.Minute)},
+	}
+	for _, tt := range []struct {
+		order sortOrder
+		want  []string
+	}{
+		{orderAsc, []string{"young", "old", "unknown"}},
+		{orderDesc, []string{"old", "young", "unknown"}},
+	} {
+		tbl.SortBy(tt.order, "age")
+		for i, name := range tt.want {
+			if tbl[i].Name != name {
+				t.Errorf("%s: row #%d: got %s, want %s", tt.order, i, tbl[i].Name, name)
+			}
+		}
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	flags := &Flags{NoColors: true}
+	at := metav1.NewTime(now.Add(-3 * time.Hour))
+
+	for _, tt := range []struct {
+		at       *metav1.Time
+		outdated bool
+		want     string
+	}{
+		{nil, false, tableUnsetCell},
+		{&at, false, "3h"},
+		{&at, true, "3h (template changed)"},
+	} {
+		if got := formatAge(tt.at, tt.outdated, now, flags); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	unstructuredv1 "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/yaml"
+)
+
+const defaultNameTemplate = "{{ .Name }}"
+
+// generateOptions represents the options of the generate command.
+type generateOptions struct {
+	*CommandOptions
+
+	UpdateMode     string
+	ResourcePolicy string
+	NameTemplate   string
+	Labels         map[string]string
+	OutputDir      string
+	Apply          bool
+	IncludeCovered bool
+	Targets        []string
+}
+
+// generateTemplateData represents the data
+// available to the name and label templates.
+type generateTemplateData struct {
+	Namespace string
+	Kind      string // lowercased
+	Name      string
+}
+
+// vpaGenerator generates VPA resources for workloads.
+type vpaGenerator struct {
+	updateMode     vpav1.UpdateMode
+	resourcePolicy *vpav1.PodResourcePolicy
+	nameTemplate   *template.Template
+	labelTemplates map[string]*template.Template
+}
+
+func newVPAGenerator(mode string, policy *vpav1.PodResourcePolicy, name string, labels map[string]string) (*vpaGenerator, error) {
+	g := &vpaGenerator{
+		updateMode:     vpav1.UpdateMode(mode),
+		resourcePolicy: policy,
+		labelTemplates: make(map[string]*template.Template, len(labels)),
+	}
+	switch g.updateMode {
+	case vpav1.UpdateModeOff, vpav1.UpdateModeInitial, vpav1.UpdateModeRecreate, vpav1.UpdateModeAuto:
+	default:
+		return nil, fmt.Errorf("invalid update mode %q, must be one of: %s, %s, %s or %s", mode,
+			vpav1.UpdateModeOff,
+			vpav1.UpdateModeInitial,
+			vpav1.UpdateModeRecreate,
+			vpav1.UpdateModeAuto,
+		)
+	}
+	var err error
+
+	g.nameTemplate, err = template.New("name").Option("missingkey=error").Parse(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %w", err)
+	}
+	for k, v := range labels {
+		g.labelTemplates[k], err = template.New(k).Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for label %s: %w", k, err)
+		}
+	}
+	return g, nil
+}
+
+// Generate returns a new VPA resource that targets the workload.
+func (g *vpaGenerator) Generate(w coverageWorkload) (*vpav1.VerticalPodAutoscaler, error) {
+	data := generateTemplateData{
+		Namespace: w.Namespace,
+		Kind:      strings.ToLower(w.Kind),
+		Name:      w.Name,
+	}
+	name, err := executeTemplate(g.nameTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) != 0 {
+		return nil, fmt.Errorf("invalid name %q: %s", name, strings.Join(errs, ", "))
+	}
+	var labels map[string]string
+
+	for k, t := range g.labelTemplates {
+		v, err := executeTemplate(t, data)
+		if err != nil {
+			return nil, err
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) != 0 {
+			return nil, fmt.Errorf("invalid value %q for label %s: %s", v, k, strings.Join(errs, ", "))
+		}
+		if labels == nil {
+			labels = make(map[string]string, len(g.labelTemplates))
+		}
+		labels[k] = v
+	}
+	mode := g.updateMode
+
+	v := &vpav1.VerticalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: vpav1.SchemeGroupVersion.String(),
+			Kind:       "VerticalPodAutoscaler",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: w.Namespace,
+			Labels:    labels,
+		},
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			TargetRef: &autoscalingv1.CrossVersionObjectReference{
+				APIVersion: w.APIVersion,
+				Kind:       w.Kind,
+				Name:       w.Name,
+			},
+			UpdatePolicy: &vpav1.PodUpdatePolicy{
+				UpdateMode: &mode,
+			},
+		},
+	}
+	if g.resourcePolicy != nil {
+		v.Spec.ResourcePolicy = g.resourcePolicy.DeepCopy()
+	}
+	return v, nil
+}
+
+func executeTemplate(t *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("couldn't execute template %s: %w", t.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// marshalVPA returns the YAML representation of a VPA resource,
+// without its status and server-populated fields.
+func marshalVPA(v *vpav1.VerticalPodAutoscaler) ([]byte, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(v)
+	if err != nil {
+		return nil, err
+	}
+	unstructuredv1.RemoveNestedField(obj, "status")
+	unstructuredv1.RemoveNestedField(obj, "metadata", "creationTimestamp")
+
+	return yaml.Marshal(obj)
+}
+
+// newGenerateCmd returns a new command that generates
+// VPA resources for the workloads without one.
+func newGenerateCmd(co *CommandOptions) *cobra.Command {
+	gno := &generateOptions{
+		CommandOptions: co,
+		UpdateMode:     string(vpav1.UpdateModeOff),
+		NameTemplate:   defaultNameTemplate,
+	}
+	cmd := &cobra.Command{
+		Use:   "generate [[NAMESPACE/]KIND/NAME...] [options]",
+		Short: "Generate VerticalPodAutoscaler resources for workloads without one",
+		Long: `Generate VerticalPodAutoscaler resources for workloads without one.
+
+If no workload is given as argument, a resource is generated for each
+workload of the selected namespaces that is not covered by a VPA. The
+workloads given as arguments that are already covered are skipped, unless
+--include-covered is set. With --all-namespaces, a workload name that
+matches workloads of several namespaces must be prefixed by its namespace,
+as in NAMESPACE/KIND/NAME.
+
+The name and label templates are Go templates that can reference the
+fields {{ .Namespace }}, {{ .Kind }} and {{ .Name }} of the workload. The
+command fails before generating anything if two generated resources, or a
+generated and an existing resource, have the same name.`,
+		Example: `  # Print the VPAs of the uncovered workloads of the current namespace
+  generate
+
+  # Generate a VPA in Initial mode for the deployment foo, with a resource policy
+  generate deployment/foo --update-mode Initial --resource-policy policy.yaml
+
+  # Print the VPA of the deployment foo of the namespace bar
+  generate -A bar/deployment/foo
+
+  # Create the VPAs of the uncovered workloads in all namespaces, with a custom name and label
+  generate -A --name-template '{{ .Kind }}-{{ .Name }}' --labels 'app={{ .Name }}' --apply`,
+		Args:                  cobra.ArbitraryArgs,
+		DisableFlagsInUseLine: true,
+		Run:                   gno.Run,
+	}
+	flags := cmd.Flags()
+
+	flags.StringVar(&gno.UpdateMode, "update-mode", gno.UpdateMode,
+		"The update mode of the generated resources. One of 'Off', 'Initial', 'Recreate', 'Auto'")
+	flags.StringVar(&gno.ResourcePolicy, "resource-policy", gno.ResourcePolicy,
+		"Path to a YAML file of the resourcePolicy to set in the generated resources")
+	flags.StringVar(&gno.NameTemplate, "name-template", gno.NameTemplate,
+		"Template of the names of the generated resources")
+	flags.StringToStringVar(&gno.Labels, "labels", gno.Labels,
+		"Comma-separated list of key=template labels to set on the generated resources")
+	flags.StringVar(&gno.OutputDir, "output-dir", gno.OutputDir,
+		"Write each generated resource to a file in this directory instead of the standard output")
+	flags.BoolVar(&gno.Apply, "apply", gno.Apply,
+		"Create the generated resources in the cluster")
+	flags.BoolVar(&gno.IncludeCovered, "include-covered", gno.IncludeCovered,
+		"Generate resources for the workloads given as arguments that are already covered by a VPA")
+
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (gno *generateOptions) Run(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(gno.Complete(c, args))
+	cmdutil.CheckErr(gno.Validate(c, args))
+	cmdutil.CheckErr(gno.Execute())
+}
+
+// Validate ensure that required options to run the
+// command are set and valid.
+func (gno *generateOptions) Validate(_ *cobra.Command, args []string) error {
+	if gno.Apply && gno.OutputDir != "" {
+		return fmt.Errorf("--apply and --output-dir are mutually exclusive")
+	}
+	for _, arg := range args {
+		arg = strings.TrimSpace(arg)
+		if _, err := parseWorkloadRef(arg); err != nil {
+			return err
+		}
+		gno.Targets = append(gno.Targets, arg)
+	}
+	return nil
+}
+
+// Execute runs the command.
+func (gno *generateOptions) Execute() error {
+	ctx := context.Background()
+
+	var policy *vpav1.PodResourcePolicy
+	if gno.ResourcePolicy != "" {
+		b, err := os.ReadFile(gno.ResourcePolicy)
+		if err != nil {
+			return fmt.Errorf("couldn't read resource policy: %w", err)
+		}
+		policy = &vpav1.PodResourcePolicy{}
+		if err := yaml.UnmarshalStrict(b, policy); err != nil {
+			return fmt.Errorf("couldn't decode resource policy %s: %w", gno.ResourcePolicy, err)
+		}
+	}
+	g, err := newVPAGenerator(gno.UpdateMode, policy, gno.NameTemplate, gno.Labels)
+	if err != nil {
+		return err
+	}
+	r, err := gno.newCoverage(ctx)
+	if err != nil {
+		return err
+	}
+	workloads, err := gno.selectWorkloads(r)
+	if err != nil {
+		return err
+	}
+	if len(workloads) == 0 {
+		fmt.Fprintln(gno.ErrOut, "No uncovered workloads found.")
+		return nil
+	}
+	vpas := make([]*vpav1.VerticalPodAutoscaler, 0, len(workloads))
+
+	for _, w := range workloads {
+		v, err := g.Generate(w)
+		if err != nil {
+			return fmt.Errorf("couldn't generate VPA for %s %s/%s: %w", w.Kind, w.Namespace, w.Name, err)
+		}
+		vpas = append(vpas, v)
+	}
+	if err := checkNameCollisions(vpas, r.vpas); err != nil {
+		return err
+	}
+	for i, v := range vpas {
+		if gno.Apply {
+			if err := gno.Client.CreateVPAResource(ctx, v); err != nil {
+				return err
+			}
+			fmt.Fprintf(gno.Out, "verticalpodautoscaler.autoscaling.k8s.io/%s created\n", v.Name)
+			continue
+		}
+		b, err := marshalVPA(v)
+		if err != nil {
+			return err
+		}
+		if gno.OutputDir != "" {
+			path := filepath.Join(gno.OutputDir, fmt.Sprintf("%s-%s.yaml", v.Namespace, v.Name))
+			if err := os.WriteFile(path, b, 0o600); err != nil {
+				return fmt.Errorf("couldn't write resource: %w", err)
+			}
+			klog.V(2).Infof("resource written to %s", path)
+			continue
+		}
+		if i != 0 {
+			fmt.Fprintln(gno.Out, "---")
+		}
+		if _, err := gno.Out.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNameCollisions returns an error that lists the generated
+// resources whose name is already used by another generated
+// resource, or by an existing resource of the same namespace.
+func checkNameCollisions(generated, existing []*vpav1.VerticalPodAutoscaler) error {
+	taken := make(map[string]bool, len(existing))
+	for _, v := range existing {
+		taken[v.Namespace+"/"+v.Name] = true
+	}
+	seen := make(map[string]bool, len(generated))
+
+	var collisions []string
+	for _, v := range generated {
+		key := v.Namespace + "/" + v.Name
+		target := fmt.Sprintf("%s/%s", strings.ToLower(v.Spec.TargetRef.Kind), v.Spec.TargetRef.Name)
+
+		switch {
+		case taken[key]:
+			collisions = append(collisions, fmt.Sprintf("%s for %s already exists", key, target))
+		case seen[key]:
+			collisions = append(collisions, fmt.Sprintf("%s for %s is generated more than once", key, target))
+		}
+		seen[key] = true
+	}
+	if len(collisions) != 0 {
+		return fmt.Errorf("the names of some generated resources collide, use another --name-template: %s", strings.Join(collisions, "; "))
+	}
+	return nil
+}
+
+// workloadRef represents a workload given as argument,
+// formatted as KIND/NAME or NAMESPACE/KIND/NAME.
+type workloadRef struct {
+	Namespace string
+	Kind      string
+	Name      string
+}
+
+func parseWorkloadRef(s string) (workloadRef, error) {
+	parts := strings.Split(s, "/")
+	for _, p := range parts {
+		if p == "" {
+			parts = nil
+			break
+		}
+	}
+	switch len(parts) {
+	case 2:
+		return workloadRef{Kind: parts[0], Name: parts[1]}, nil
+	case 3:
+		return workloadRef{Namespace: parts[0], Kind: parts[1], Name: parts[2]}, nil
+	default:
+		return workloadRef{}, fmt.Errorf("invalid workload %q, must be formatted as KIND/NAME or NAMESPACE/KIND/NAME", s)
+	}
+}
+
+func (ref workloadRef) matches(w coverageWorkload) bool {
+	return strings.EqualFold(w.Kind, ref.Kind) && w.Name == ref.Name &&
+		(ref.Namespace == "" || w.Namespace == ref.Namespace)
+}
+
+// selectWorkloads returns the workloads of the report that
+// match the targets given as arguments, or all the uncovered
+// workloads if there are none. The targets already covered
+// are skipped, unless they are explicitly included.
+func (gno *generateOptions) selectWorkloads(r *coverageReport) ([]coverageWorkload, error) {
+	if len(gno.Targets) == 0 {
+		return r.uncoveredWorkloads(), nil
+	}
+	var ret []coverageWorkload
+
+	for _, t := range gno.Targets {
+		ref, err := parseWorkloadRef(t)
+		if err != nil {
+			return nil, err
+		}
+		var matches []coverageWorkload
+		for _, w := range r.Workloads {
+			if ref.matches(w) {
+				matches = append(matches, w)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("workload not found: %s", t)
+		case 1:
+		default:
+			namespaces := make([]string, 0, len(matches))
+			for _, w := range matches {
+				namespaces = append(namespaces, w.Namespace)
+			}
+			return nil, fmt.Errorf("workload %s matches workloads of several namespaces (%s), use NAMESPACE/KIND/NAME", t, strings.Join(namespaces, ", "))
+		}
+		w := matches[0]
+
+		if w.Status != coverageUncovered {
+			if !gno.IncludeCovered {
+				klog.Warningf("%s %s/%s is already covered by %s, skipping", w.Kind, w.Namespace, w.Name, strings.Join(w.VPAs, ", "))
+				continue
+			}
+			klog.Warningf("%s %s/%s is already covered by %s", w.Kind, w.Namespace, w.Name, strings.Join(w.VPAs, ", "))
+		}
+		ret = append(ret, w)
+	}
+	return ret, nil
+}
@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestGenerateVPA(t *testing.T) {
+	policy := &vpav1.PodResourcePolicy{}
+	err := yaml.UnmarshalStrict([]byte(`
+containerPolicies:
+  - containerName: "*"
+    minAllowed:
+      cpu: 10m
+    controlledValues: RequestsOnly
+`), policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := newVPAGenerator("Initial", policy, "{{ .Kind }}-{{ .Name }}", map[string]string{
+		"app":  "{{ .Name }}",
+		"team": "platform",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := g.Generate(coverageWorkload{
+		Namespace:  "athens",
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Name:       "zeus",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := marshalVPA(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `apiVersion: autoscaling.k8s.io/v1
+kind: VerticalPodAutoscaler
+metadata:
+  labels:
+    app: zeus
+    team: platform
+  name: deployment-zeus
+  namespace: athens
+spec:
+  resourcePolicy:
+    containerPolicies:
+    - containerName: '*'
+      controlledValues: RequestsOnly
+      minAllowed:
+        cpu: 10m
+  targetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: zeus
+  updatePolicy:
+    updateMode: Initial
+`
+	if got := string(b); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	// The policy of the generated resources must be a copy.
+	v.Spec.ResourcePolicy.ContainerPolicies[0].MinAllowed["cpu"] = resource.MustParse("1")
+	if policy.ContainerPolicies[0].MinAllowed.Cpu().String() != "10m" {
+		t.Error("expected generator policy to be unchanged")
+	}
+}
+
+func TestNewVPAGeneratorErrors(t *testing.T) {
+	for _, tc := range []struct {
+		mode, name string
+		labels     map[string]string
+		err        string
+	}{
+		{"Sometimes", defaultNameTemplate, nil, "invalid update mode"},
+		{"Off", "{{ .Name", nil, "invalid name template"},
+		{"Off", defaultNameTemplate, map[string]string{"app": "{{ .Foo"}, "invalid template for label"},
+	} {
+		_, err := newVPAGenerator(tc.mode, nil, tc.name, tc.labels)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("got error %v, want %q", err, tc.err)
+		}
+	}
+	g, err := newVPAGenerator("Off", nil, "{{ .Name }}_vpa", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Generate(coverageWorkload{Kind: "Deployment", Name: "zeus"}); err == nil {
+		t.Error("expected an error for an invalid name")
+	}
+}
+
+func TestSelectWorkloads(t *testing.T) {
+	r := &coverageReport{Workloads: []coverageWorkload{
+		{Namespace: "athens", Kind: "Deployment", Name: "zeus", Status: coverageUncovered},
+		{Namespace: "sparta", Kind: "Deployment", Name: "zeus", Status: coverageUncovered},
+		{Namespace: "athens", Kind: "StatefulSet", Name: "hera", Status: coverageCovered, VPAs: []string{"hera"}},
+	}}
+	names := func(workloads []coverageWorkload) []string {
+		var ret []string
+		for _, w := range workloads {
+			ret = append(ret, w.Namespace+"/"+w.Name)
+		}
+		return ret
+	}
+	for _, tt := range []struct {
+		targets        []string
+		includeCovered bool
+		want           []string
+		err            string
+	}{
+		{nil, false, []string{"athens/zeus", "sparta/zeus"}, ""},
+		{[]string{"sparta/deployment/zeus"}, false, []string{"sparta/zeus"}, ""},
+		{[]string{"deployment/zeus"}, false, nil, "several namespaces"},
+		{[]string{"statefulset/hera"}, false, nil, ""},
+		{[]string{"statefulset/hera"}, true, []string{"athens/hera"}, ""},
+		{[]string{"thebes/deployment/zeus"}, false, nil, "not found"},
+	} {
+		gno := &generateOptions{Targets: tt.targets, IncludeCovered: tt.includeCovered}
+
+		got, err := gno.selectWorkloads(r)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%v: got error %v, want %q", tt.targets, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %s", tt.targets, err)
+			continue
+		}
+		if g := strings.Join(names(got), ","); g != strings.Join(tt.want, ",") {
+			t.Errorf("%v: got %s, want %s", tt.targets, g, strings.Join(tt.want, ","))
+		}
+	}
+}
+
+func TestCheckNameCollisions(t *testing.T) {
+	g, err := newVPAGenerator("Off", nil, "{{ .Name }}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var generated []*vpav1.VerticalPodAutoscaler
+	for _, w := range []coverageWorkload{
+		{Namespace: "athens", Kind: "Deployment", Name: "zeus"},
+		{Namespace: "athens", Kind: "StatefulSet", Name: "zeus"},
+		{Namespace: "sparta", Kind: "Deployment", Name: "zeus"},
+		{Namespace: "sparta", Kind: "Deployment", Name: "hera"},
+	} {
+		v, err := g.Generate(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generated = append(generated, v)
+	}
+	existing := &vpav1.VerticalPodAutoscaler{}
+	existing.Namespace, existing.Name = "sparta", "hera"
+
+	if err := checkNameCollisions(generated[2:3], []*vpav1.VerticalPodAutoscaler{existing}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err = checkNameCollisions(generated, []*vpav1.VerticalPodAutoscaler{existing})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		"athens/zeus for statefulset/zeus is generated more than once",
+		"sparta/hera for deployment/hera already exists",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("got error %q, want it to contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "sparta/zeus") {
+		t.Errorf("got error %q, want no collision across namespaces", err)
+	}
+}
+
+func TestParseWorkloadRef(t *testing.T) {
+	for _, s := range []string{"deployment", "a/b/c/d", "/zeus", "athens//zeus"} {
+		if _, err := parseWorkloadRef(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+	ref, err := parseWorkloadRef("athens/deployment/zeus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != (workloadRef{Namespace: "athens", Kind: "deployment", Name: "zeus"}) {
+		t.Errorf("got %+v", ref)
+	}
+}
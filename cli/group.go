@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/pkg/analysis"
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+const (
+	groupByNamespace  = "namespace"
+	groupByKind       = "kind"
+	groupByLabel      = "label"
+	groupByAnnotation = "annotation"
+
+	groupNone = "<none>"
+)
+
+// groupBy represents the key used to aggregate the rows of a table.
+type groupBy struct {
+	kind string
+	key  string // the label or annotation key
+}
+
+// parseGroupBy parses a group-by flag value, formatted
+// as namespace, kind, label:<key> or annotation:<key>.
+func parseGroupBy(s string) (*groupBy, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(s, ":", 2)
+
+	switch parts[0] {
+	case groupByNamespace, groupByKind:
+		if len(parts) == 1 {
+			return &groupBy{kind: parts[0]}, nil
+		}
+	case groupByLabel, groupByAnnotation:
+		if len(parts) == 2 && parts[1] != "" {
+			return &groupBy{kind: parts[0], key: parts[1]}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid group-by %q, must be one of: namespace, kind, label:<key>, annotation:<key>", s)
+}
+
+// keyOf returns the group of a VPA resource. The labels and
+// annotations are looked up on the target controller first,
+// and on the VPA resource if the target doesn't have the key.
+func (g *groupBy) keyOf(item *analysis.Item) string {
+	var lookup func(map[string]string) (string, bool)
+
+	switch g.kind {
+	case groupByNamespace:
+		return item.Namespace
+	case groupByKind:
+		return item.Target.Kind
+	case groupByLabel:
+		lookup = func(m map[string]string) (string, bool) { s, ok := m[g.key]; return s, ok }
+		if s, ok := lookup(item.TargetController().Labels()); ok {
+			return s
+		}
+		if s, ok := lookup(item.Labels); ok {
+			return s
+		}
+	case groupByAnnotation:
+		lookup = func(m map[string]string) (string, bool) { s, ok := m[g.key]; return s, ok }
+		if s, ok := lookup(item.TargetController().Annotations()); ok {
+			return s
+		}
+		if s, ok := lookup(item.Annotations); ok {
+			return s
+		}
+	}
+	return groupNone
+}
+
+// groupAggregate represents the aggregated
+// requests and recommendations of a group.
+type groupAggregate struct {
+	Name                 string
+	VPAs                 int
+	Replicas             int64
+	Requests             vpa.ResourceQuantities // sum of the requests of a single pod of each target
+	Recommendations      vpa.ResourceQuantities // sum of the recommendations of a single pod of each target
+	TotalRequests        vpa.ResourceQuantities // weighted by the replicas of each target
+	TotalRecommendations vpa.ResourceQuantities // weighted by the replicas of each target
+}
+
+// aggregate returns the aggregates of the rows of the tables,
+// grouped by the group of each row, and sorted by name. The
+// rows accepted by a baseline are ignored.
+func aggregate(tables []table, names []corev1.ResourceName) []*groupAggregate {
+	groups := make(map[string]*groupAggregate)
+
+	for _, t := range tables {
+		for _, row := range t.withoutBaselined() {
+			g, ok := groups[row.Group]
+			if !ok {
+				g = &groupAggregate{Name: row.Group}
+				groups[row.Group] = g
+			}
+			g.add(row, names)
+		}
+	}
+	ret := make([]*groupAggregate, 0, len(groups))
+	for _, g := range groups {
+		ret = append(ret, g)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+func (g *groupAggregate) add(row *tableRow, names []corev1.ResourceName) {
+	replicas := int64(row.TargetReplicas)
+
+	g.VPAs++
+	g.Replicas += replicas
+
+	for _, name := range names {
+		addQuantity(&g.Requests, name, row.Requests.Get(name))
+		rec := recommendationOrRequest(row.Recommendations, row.Requests, name)
+		addQuantity(&g.Recommendations, name, rec)
+		addQuantity(&g.TotalRequests, name, multiplyQuantity(row.Requests.Get(name), replicas))
+		addQuantity(&g.TotalRecommendations, name, multiplyQuantity(rec, replicas))
+	}
+}
+
+// recommendationOrRequest returns the recommendation of the named
+// resource, or its request if there is no recommendation, so that
+// the sums of the requests and recommendations cover the same rows.
+func recommendationOrRequest(recs, requests vpa.ResourceQuantities, name corev1.ResourceName) *resource.Quantity {
+	if q := recs.Get(name); q != nil && !q.IsZero() {
+		return q
+	}
+	return requests.Get(name)
+}
+
+// addQuantity adds q to the quantity of the named resource of
+// rq, without mutating q, which may be shared with a table row.
+func addQuantity(rq *vpa.ResourceQuantities, name corev1.ResourceName, q *resource.Quantity) {
+	if q == nil {
+		return
+	}
+	sum := rq.Get(name)
+	if sum == nil {
+		sum = &resource.Quantity{}
+		rq.Set(name, sum)
+	}
+	sum.Add(*q)
+}
+
+// totalRow returns a row that represents the totals of
+// the group, weighted by the replicas of each target.
+func (g *groupAggregate) totalRow() *tableRow {
+	row := &tableRow{
+		Name:            fmt.Sprintf("TOTAL (%d)", g.VPAs),
+		Namespace:       tableUnsetCell,
+		Mode:            tableUnsetCell,
+		TargetName:      tableUnsetCell,
+		TargetReplicas:  1,
+		Requests:        g.TotalRequests,
+		Recommendations: g.TotalRecommendations,
+	}
+	row.Differences = vpa.DiffResourcesAsPercent(row.Requests, row.Recommendations)
+
+	return row
+}
+
+// partitionByGroup splits the rows of the table into
+// a table for each group, sorted by the group name.
+func partitionByGroup(t table) []table {
+	var (
+		names  []string
+		groups = make(map[string]table)
+	)
+	for _, row := range t {
+		if _, ok := groups[row.Group]; !ok {
+			names = append(names, row.Group)
+		}
+		groups[row.Group] = append(groups[row.Group], row)
+	}
+	sort.Strings(names)
+
+	tables := make([]table, 0, len(names))
+	for _, name := range names {
+		tables = append(tables, groups[name])
+	}
+	return tables
+}
+
+// printGroups writes the aggregates of the groups to w.
+func printGroups(w io.Writer, groups []*groupAggregate, flags *Flags) error {
+	tw := newKubectlTableWriter(w)
+
+	names := flags.resourceNames()
+
+	if !flags.NoHeaders {
+		headers := []string{"Group", "VPAs", "Replicas"}
+		for _, name := range names {
+			req, rec, diff := resourceHeaders(name)
+			if flags.wide {
+				headers = append(headers, req, rec)
+			}
+			headers = append(headers, "Total "+req, "Total "+rec, diff)
+		}
+		tw.SetHeader(headers)
+	}
+	for _, g := range groups {
+		rowData := []string{g.Name, fmt.Sprintf("%d", g.VPAs), fmt.Sprintf("%d", g.Replicas)}
+
+		for _, name := range names {
+			if flags.wide {
+				rowData = append(rowData,
+					formatQuantity(g.Requests.Get(name)),
+					formatResourceRecommendation(name, g.Recommendations.Get(name), g.Requests.Get(name)),
+				)
+			}
+			req, rec := g.TotalRequests.Get(name), g.TotalRecommendations.Get(name)
+			rowData = append(rowData,
+				formatQuantity(req),
+				formatResourceRecommendation(name, rec, req),
+				formatPercentage(vpa.DiffQuantitiesAsPercent(req, rec), nil, name, flags),
+			)
+		}
+		tw.Append(rowData)
+	}
+	tw.Render()
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	for _, tt := range []struct {
+		value string
+		want  *groupBy
+		err   bool
+	}{
+		{"", nil, false},
+		{"namespace", &groupBy{kind: groupByNamespace}, false},
+		{"kind", &groupBy{kind: groupByKind}, false},
+		{"label:team", &groupBy{kind: groupByLabel, key: "team"}, false},
+		{"annotation:example.com/owner", &groupBy{kind: groupByAnnotation, key: "example.com/owner"}, false},
+		{"label:", nil, true},
+		{"label", nil, true},
+		{"namespace:foo", nil, true},
+		{"pod", nil, true},
+	} {
+		got, err := parseGroupBy(tt.value)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: got error %v, want error %t", tt.value, err, tt.err)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	newRow := func(group string, replicas int32, cpuReq, cpuRec, memReq, memRec string) *tableRow {
+		req := resource.MustParse(cpuReq)
+		rec := resource.MustParse(cpuRec)
+		mreq := resource.MustParse(memReq)
+		mrec := resource.MustParse(memRec)
+		return &tableRow{
+			Group:           group,
+			TargetReplicas:  replicas,
+			Requests:        vpa.ResourceQuantities{CPU: &req, Memory: &mreq},
+			Recommendations: vpa.ResourceQuantities{CPU: &rec, Memory: &mrec},
+		}
+	}
+	tables := []table{
+		{
+			newRow("backend", 3, "100m", "50m", "128Mi", "64Mi"),
+			newRow("frontend", 1, "200m", "400m", "256Mi", "256Mi"),
+		},
+		{
+			newRow("backend", 2, "1", "500m", "1Gi", "512Mi"),
+		},
+	}
+	accepted := newRow("backend", 10, "10", "1", "10Gi", "1Gi")
+	accepted.Baseline = baselineAccepted
+	tables[1] = append(tables[1], accepted)
+
+	pending := newRow("frontend", 2, "300m", "0", "64Mi", "0")
+	pending.Recommendations = vpa.ResourceQuantities{}
+	tables[1] = append(tables[1], pending)
+
+	names := []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory}
+
+	groups := aggregate(tables, names)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+	g := groups[0]
+	if g.Name != "backend" || g.VPAs != 2 || g.Replicas != 5 {
+		t.Errorf("unexpected group: %s, %d VPAs, %d replicas", g.Name, g.VPAs, g.Replicas)
+	}
+	for _, tt := range []struct {
+		name string
+		got  *resource.Quantity
+		want string
+	}{
+		{"cpu requests", g.Requests.CPU, "1100m"},
+		{"cpu recommendations", g.Recommendations.CPU, "550m"},
+		{"total cpu requests", g.TotalRequests.CPU, "2300m"},
+		{"total cpu recommendations", g.TotalRecommendations.CPU, "1150m"},
+		{"total memory requests", g.TotalRequests.Memory, "2432Mi"},
+		{"total memory recommendations", g.TotalRecommendations.Memory, "1216Mi"},
+	} {
+		if tt.got == nil || tt.got.Cmp(resource.MustParse(tt.want)) != 0 {
+			t.Errorf("%s: got %v, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+	// The quantities of the rows must not be mutated.
+	if q := tables[0][0].Requests.CPU; q.Cmp(resource.MustParse("100m")) != 0 {
+		t.Errorf("row request mutated: got %s, want 100m", q)
+	}
+	total := g.totalRow()
+	if d := total.Differences[corev1.ResourceCPU]; d == nil {
+		t.Error("expected total cpu difference")
+	} else if *d != 100 {
+		t.Errorf("got total cpu difference %v, want 100", *d)
+	}
+	if groups[1].Name != "frontend" || groups[1].Replicas != 3 {
+		t.Errorf("unexpected group: %s, %d replicas", groups[1].Name, groups[1].Replicas)
+	}
+	// The row without recommendation counts with its requests.
+	if q := groups[1].TotalRecommendations.CPU; q == nil || q.Cmp(resource.MustParse("1")) != 0 {
+		t.Errorf("total frontend cpu recommendations: got %v, want 1", q)
+	}
+}
+
+func TestPartitionByGroup(t *testing.T) {
+	tables := partitionByGroup(table{
+		{Name: "a", Group: "web"},
+		{Name: "b", Group: groupNone},
+		{Name: "c", Group: "api"},
+		{Name: "d", Group: "web"},
+	})
+	var got []string
+	for _, t := range tables {
+		var s string
+		for _, row := range t {
+			s += row.Name
+		}
+		got = append(got, s)
+	}
+	want := []string{"b", "c", "ad"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+const metricsNamespace = "vpa_recommendation"
+
+var (
+	vpaLabels       = []string{"namespace", "vpa", "target_kind", "target"}
+	containerLabels = append(vpaLabels[:len(vpaLabels):len(vpaLabels)], "container", "resource")
+)
+
+var (
+	descRequests = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "container", "requests"),
+		"Resource requests of a container of the target's pods, in cores or bytes.",
+		containerLabels, nil,
+	)
+	descRecommendation = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "container", "recommendation"),
+		"Resource recommendation of a container by type, in cores or bytes.",
+		append(containerLabels[:len(containerLabels):len(containerLabels)], "type"), nil,
+	)
+	descDifference = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "container", "difference_percent"),
+		"Percentage difference between the requests of a container and its recommendation.",
+		append(containerLabels[:len(containerLabels):len(containerLabels)], "type"), nil,
+	)
+	descReplicas = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "target", "replicas"),
+		"Number of replicas declared by the spec of the target controller.",
+		vpaLabels, nil,
+	)
+	descUpdateMode = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "update_mode"),
+		"Update mode of the VPA resource, always 1.",
+		append(vpaLabels[:len(vpaLabels):len(vpaLabels)], "mode"), nil,
+	)
+	descCondition = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "condition"),
+		"Status of the conditions of the VPA resource, 1 if true, 0 otherwise.",
+		append(vpaLabels[:len(vpaLabels):len(vpaLabels)], "condition"), nil,
+	)
+	descScrapeDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "scrape", "duration_seconds"),
+		"Duration of the last comparison of the VPA resources.",
+		nil, nil,
+	)
+	descScrapeTimestamp = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "scrape", "last_success_timestamp_seconds"),
+		"Timestamp of the last successful comparison of the VPA resources.",
+		nil, nil,
+	)
+	descScrapeErrors = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "scrape", "errors_total"),
+		"Total number of failed comparisons of the VPA resources.",
+		nil, nil,
+	)
+	descSkippedTargets = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "scrape", "skipped_targets"),
+		"Number of VPA resources whose target couldn't be resolved during the last comparison.",
+		nil, nil,
+	)
+)
+
+var recommendationTypes = []vpa.RecommendationType{
+	vpa.RecommendationTarget,
+	vpa.RecommendationLowerBound,
+	vpa.RecommendationUpperBound,
+	vpa.RecommendationUncappedTarget,
+}
+
+// exporter is a prometheus.Collector that exposes
+// the metrics of the last report of a comparison.
+type exporter struct {
+	mu          sync.RWMutex
+	report      *report
+	skipped     int
+	duration    time.Duration
+	lastSuccess time.Time
+	errors      int
+}
+
+var _ prometheus.Collector = (*exporter)(nil)
+
+// Describe implements the prometheus.Collector interface.
+func (e *exporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		descRequests,
+		descRecommendation,
+		descDifference,
+		descReplicas,
+		descUpdateMode,
+		descCondition,
+		descScrapeDuration,
+		descScrapeTimestamp,
+		descScrapeErrors,
+		descSkippedTargets,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements the prometheus.Collector interface.
+func (e *exporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	ch <- prometheus.MustNewConstMetric(descScrapeDuration, prometheus.GaugeValue, e.duration.Seconds())
+	ch <- prometheus.MustNewConstMetric(descScrapeErrors, prometheus.CounterValue, float64(e.errors))
+
+	if e.report == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(descScrapeTimestamp, prometheus.GaugeValue, float64(e.lastSuccess.Unix()))
+	ch <- prometheus.MustNewConstMetric(descSkippedTargets, prometheus.GaugeValue, float64(e.skipped))
+
+	for _, item := range e.report.Items {
+		lv := []string{item.Namespace, item.Name, item.Target.Kind, item.Target.Name}
+
+		if item.Replicas != nil {
+			ch <- prometheus.MustNewConstMetric(descReplicas, prometheus.GaugeValue, float64(*item.Replicas), lv...)
+		}
+		// The report marks an unset mode as unset for display,
+		// the metric leaves the label empty instead.
+		mode := item.Mode
+		if mode == tableUnsetCell {
+			mode = ""
+		}
+		ch <- prometheus.MustNewConstMetric(descUpdateMode, prometheus.GaugeValue, 1, append(lv, mode)...)
+
+		for _, c := range item.Conditions {
+			var v float64
+			if c.Status == corev1.ConditionTrue {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(descCondition, prometheus.GaugeValue, v, append(lv, string(c.Type))...)
+		}
+		for _, c := range item.Containers {
+			e.collectContainer(ch, lv, c)
+		}
+	}
+}
+
+func (e *exporter) collectContainer(ch chan<- prometheus.Metric, lv []string, c reportContainer) {
+	names := make(map[corev1.ResourceName]bool)
+	for _, rl := range []corev1.ResourceList{c.Requests, c.Target, c.LowerBound, c.UpperBound, c.UncappedTarget} {
+		for name := range rl {
+			names[name] = true
+		}
+	}
+	for rn := range names {
+		clv := append(lv[:len(lv):len(lv)], c.Name, string(rn))
+
+		if q, ok := c.Requests[rn]; ok {
+			ch <- prometheus.MustNewConstMetric(descRequests, prometheus.GaugeValue, q.AsApproximateFloat64(), clv...)
+		}
+		for _, rt := range recommendationTypes {
+			rec, ok := containerRecommendationByType(c, rt)[rn]
+			if !ok {
+				continue
+			}
+			tlv := append(clv[:len(clv):len(clv)], rt.String())
+			ch <- prometheus.MustNewConstMetric(descRecommendation, prometheus.GaugeValue, rec.AsApproximateFloat64(), tlv...)
+
+			if req, ok := c.Requests[rn]; ok {
+				if p := vpa.DiffQuantitiesAsPercent(&req, &rec); p != nil {
+					ch <- prometheus.MustNewConstMetric(descDifference, prometheus.GaugeValue, *p, tlv...)
+				}
+			}
+		}
+	}
+}
+
+// update replaces the report exposed by the exporter.
+func (e *exporter) update(r *report, skipped int, d time.Duration, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.duration = d
+	if err != nil {
+		e.errors++
+		return
+	}
+	e.report = r
+	e.skipped = skipped
+	e.lastSuccess = r.Timestamp.Time
+}
+
+// lastReport returns the last report, if any.
+func (e *exporter) lastReport() *report {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.report
+}
+
+func containerRecommendationByType(c reportContainer, rt vpa.RecommendationType) corev1.ResourceList {
+	switch rt {
+	case vpa.RecommendationLowerBound:
+		return c.LowerBound
+	case vpa.RecommendationUpperBound:
+		return c.UpperBound
+	case vpa.RecommendationUncappedTarget:
+		return c.UncappedTarget
+	}
+	return c.Target
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+func TestExporterCollect(t *testing.T) {
+	replicas := int64(3)
+
+	e := &exporter{}
+	e.update(&report{
+		Timestamp: metav1.NewTime(time.Unix(1640995200, 0)),
+		Items: []reportItem{{
+			Namespace: "athens",
+			Name:      "zeus",
+			Mode:      "Off",
+			Target:    reportTarget{Kind: "Deployment", Name: "zeus"},
+			Replicas:  &replicas,
+			Conditions: []vpav1.VerticalPodAutoscalerCondition{
+				{Type: vpav1.RecommendationProvided, Status: corev1.ConditionTrue},
+			},
+			Containers: []reportContainer{{
+				Name: "thunder",
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("400m"),
+				},
+				Target: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("100m"),
+				},
+			}},
+		}, {
+			Namespace: "athens",
+			Name:      "hermes",
+			Mode:      tableUnsetCell,
+			Target:    reportTarget{Kind: "Deployment", Name: "hermes"},
+		}},
+	}, 1, time.Second, nil)
+	e.update(nil, 0, 2*time.Second, errors.New("unreachable"))
+
+	const want = `
+# HELP vpa_recommendation_container_difference_percent Percentage difference between the requests of a container and its recommendation.
+# TYPE vpa_recommendation_container_difference_percent gauge
+vpa_recommendation_container_difference_percent{container="thunder",namespace="athens",resource="cpu",target="zeus",target_kind="Deployment",type="target",vpa="zeus"} 300
+# HELP vpa_recommendation_container_recommendation Resource recommendation of a container by type, in cores or bytes.
+# TYPE vpa_recommendation_container_recommendation gauge
+vpa_recommendation_container_recommendation{container="thunder",namespace="athens",resource="cpu",target="zeus",target_kind="Deployment",type="target",vpa="zeus"} 0.1
+# HELP vpa_recommendation_container_requests Resource requests of a container of the target's pods, in cores or bytes.
+# TYPE vpa_recommendation_container_requests gauge
+vpa_recommendation_container_requests{container="thunder",namespace="athens",resource="cpu",target="zeus",target_kind="Deployment",vpa="zeus"} 0.4
+# HELP vpa_recommendation_condition Status of the conditions of the VPA resource, 1 if true, 0 otherwise.
+# TYPE vpa_recommendation_condition gauge
+vpa_recommendation_condition{condition="RecommendationProvided",namespace="athens",target="zeus",target_kind="Deployment",vpa="zeus"} 1
+# HELP vpa_recommendation_scrape_duration_seconds Duration of the last comparison of the VPA resources.
+# TYPE vpa_recommendation_scrape_duration_seconds gauge
+vpa_recommendation_scrape_duration_seconds 2
+# HELP vpa_recommendation_scrape_errors_total Total number of failed comparisons of the VPA resources.
+# TYPE vpa_recommendation_scrape_errors_total counter
+vpa_recommendation_scrape_errors_total 1
+# HELP vpa_recommendation_scrape_last_success_timestamp_seconds Timestamp of the last successful comparison of the VPA resources.
+# TYPE vpa_recommendation_scrape_last_success_timestamp_seconds gauge
+vpa_recommendation_scrape_last_success_timestamp_seconds 1.6409952e+09
+# HELP vpa_recommendation_scrape_skipped_targets Number of VPA resources whose target couldn't be resolved during the last comparison.
+# TYPE vpa_recommendation_scrape_skipped_targets gauge
+vpa_recommendation_scrape_skipped_targets 1
+# HELP vpa_recommendation_target_replicas Number of replicas declared by the spec of the target controller.
+# TYPE vpa_recommendation_target_replicas gauge
+vpa_recommendation_target_replicas{namespace="athens",target="zeus",target_kind="Deployment",vpa="zeus"} 3
+# HELP vpa_recommendation_update_mode Update mode of the VPA resource, always 1.
+# TYPE vpa_recommendation_update_mode gauge
+vpa_recommendation_update_mode{mode="",namespace="athens",target="hermes",target_kind="Deployment",vpa="hermes"} 1
+vpa_recommendation_update_mode{mode="Off",namespace="athens",target="zeus",target_kind="Deployment",vpa="zeus"} 1
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
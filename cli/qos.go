@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/muesli/termenv"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+// isQOSDowngrade returns whether the recommended
+// QoS class has a lower priority than the current.
+func isQOSDowngrade(current, recommended corev1.PodQOSClass) bool {
+	if current == "" || recommended == "" {
+		return false
+	}
+	return vpa.QOSClassRank(recommended) < vpa.QOSClassRank(current)
+}
+
+// formatQOSClasses formats the current QoS class of the pods
+// and, if different, the class once the recommendation is
+// applied. Downgrades are highlighted.
+func formatQOSClasses(current, recommended corev1.PodQOSClass, flags *Flags) string {
+	if current == "" {
+		return tableUnsetCell
+	}
+	if recommended == "" || recommended == current {
+		return string(current)
+	}
+	s := fmt.Sprintf("%s -> %s", current, recommended)
+
+	if !isQOSDowngrade(current, recommended) || termenv.EnvNoColor() || flags.NoColors {
+		return s
+	}
+	p := termenv.ColorProfile()
+
+	return termenv.String(s).Foreground(p.Color("#E88388")).String()
+}
+
+// warnQOSDowngrades logs a warning for each row whose
+// recommendation would downgrade the QoS class of the
+// pods of its target.
+func warnQOSDowngrades(tables []table) {
+	for _, t := range tables {
+		for _, row := range t {
+			if isQOSDowngrade(row.QOSClass, row.RecommendedQOSClass) {
+				klog.Warningf("applying the recommendation of vpa %s/%s would downgrade the QoS class of its pods from %s to %s",
+					row.Namespace, row.Name, row.QOSClass, row.RecommendedQOSClass,
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestFormatQOSClasses(t *testing.T) {
+	flags := &Flags{NoColors: true}
+
+	for _, tt := range []struct {
+		current, recommended corev1.PodQOSClass
+		want                 string
+		downgrade            bool
+	}{
+		{"", "", tableUnsetCell, false},
+		{corev1.PodQOSGuaranteed, corev1.PodQOSGuaranteed, "Guaranteed", false},
+		{corev1.PodQOSGuaranteed, corev1.PodQOSBurstable, "Guaranteed -> Burstable", true},
+		{corev1.PodQOSBestEffort, corev1.PodQOSBurstable, "BestEffort -> Burstable", false},
+	} {
+		if got := formatQOSClasses(tt.current, tt.recommended, flags); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+		if got := isQOSDowngrade(tt.current, tt.recommended); got != tt.downgrade {
+			t.Errorf("%s -> %s: got downgrade %t, want %t", tt.current, tt.recommended, got, tt.downgrade)
+		}
+	}
+}
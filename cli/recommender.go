@@ -0,0 +1,11 @@
+package cli
+
+import "strings"
+
+// formatRecommenders formats the recommenders of a VPA.
+func formatRecommenders(names []string) string {
+	if len(names) == 0 {
+		return tableUnsetCell
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,18 @@
+package cli
+
+import "testing"
+
+func TestFormatRecommenders(t *testing.T) {
+	for _, tt := range []struct {
+		names []string
+		want  string
+	}{
+		{nil, "-"},
+		{[]string{"default"}, "default"},
+		{[]string{"custom", "default"}, "custom, default"},
+	} {
+		if got := formatRecommenders(tt.names); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
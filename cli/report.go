@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/pkg/analysis"
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+// report represents the serializable result of the
+// comparison of the recommendations of VPA resources
+// with the requests of their targets.
+type report struct {
+	Timestamp          metav1.Time            `json:"timestamp"`
+	Cluster            string                 `json:"cluster,omitempty"`
+	RecommendationType vpa.RecommendationType `json:"recommendationType"`
+	Items              []reportItem           `json:"items"`
+}
+
+// reportItem represents the comparison of a single VPA resource.
+type reportItem struct {
+	Namespace           string                                 `json:"namespace"`
+	Name                string                                 `json:"name"`
+	Mode                string                                 `json:"mode"`
+	Recommenders        []string                               `json:"recommenders"`
+	Target              reportTarget                           `json:"target"`
+	Replicas            *int64                                 `json:"replicas,omitempty"`
+	Conditions          []vpav1.VerticalPodAutoscalerCondition `json:"conditions,omitempty"`
+	RecommendedAt       *metav1.Time                           `json:"recommendedAt,omitempty"`
+	RecommendedAtSource vpa.RecommendationTimeSource           `json:"recommendedAtSource,omitempty"`
+	OutdatedTemplate    bool                                   `json:"outdatedTemplate"`
+	RequestsSource      vpa.RequestsSource                     `json:"requestsSource"`
+	SourcePod           string                                 `json:"sourcePod,omitempty"`
+	MixedRequests       bool                                   `json:"mixedRequests"`
+	Variance            []vpa.RequestsVariance                 `json:"variance,omitempty"`
+	QOSClass            corev1.PodQOSClass                     `json:"qosClass,omitempty"`
+	RecommendedQOSClass corev1.PodQOSClass                     `json:"recommendedQOSClass,omitempty"`
+	Requests            corev1.ResourceList                    `json:"requests"`
+	Recommendations     corev1.ResourceList                    `json:"recommendations"`
+	Suggested           corev1.ResourceList                    `json:"suggested,omitempty"`
+	Differences         map[corev1.ResourceName]*float64       `json:"differences"`
+	Containers          []reportContainer                      `json:"containers,omitempty"`
+}
+
+// reportTarget represents the target controller of a VPA resource.
+type reportTarget struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+}
+
+// reportContainer represents the comparison of
+// a single container recommendation of a VPA.
+type reportContainer struct {
+	Name           string                           `json:"name"`
+	Type           vpa.ContainerType                `json:"type,omitempty"`
+	Requests       corev1.ResourceList              `json:"requests"`
+	Target         corev1.ResourceList              `json:"target,omitempty"`
+	LowerBound     corev1.ResourceList              `json:"lowerBound,omitempty"`
+	UpperBound     corev1.ResourceList              `json:"upperBound,omitempty"`
+	UncappedTarget corev1.ResourceList              `json:"uncappedTarget,omitempty"`
+	Suggested      corev1.ResourceList              `json:"suggested,omitempty"`
+	Differences    map[corev1.ResourceName]*float64 `json:"differences"`
+}
+
+// newReport returns the report of the VPA resources in the list.
+// The resources whose target cannot be resolved are skipped, and
+// their number is returned alongside the report.
+func (co *CommandOptions) newReport(ctx context.Context, list []*vpav1.VerticalPodAutoscaler) (*report, int) {
+	ar := analysis.AnalyzeVPAs(ctx, co.Client, list, co.analysisOptions())
+
+	r := &report{
+		Timestamp:          ar.Timestamp,
+		Cluster:            co.clusterName(),
+		RecommendationType: ar.RecommendationType,
+		Items:              make([]reportItem, 0, len(ar.Items)),
+	}
+	for i := range ar.Items {
+		r.Items = append(r.Items, newReportItem(&ar.Items[i]))
+	}
+	return r, ar.Skipped
+}
+
+// newReportItem returns the report of a single item. The suggested
+// recommendations are set only if a rounding rule matched the VPA.
+func newReportItem(ai *analysis.Item) reportItem {
+	item := reportItem{
+		Namespace:    ai.Namespace,
+		Name:         ai.Name,
+		Mode:         orUnset(ai.Mode),
+		Recommenders: ai.Recommenders,
+		Target: reportTarget{
+			APIVersion: ai.Target.APIVersion,
+			Kind:       ai.Target.Kind,
+			Name:       ai.Target.Name,
+		},
+		Replicas:            ai.Replicas,
+		Conditions:          ai.Conditions,
+		RecommendedAt:       ai.RecommendedAt,
+		RecommendedAtSource: ai.RecommendedAtSource,
+		OutdatedTemplate:    ai.OutdatedTemplate,
+		RequestsSource:      ai.RequestsSource,
+		SourcePod:           ai.SourcePod,
+		MixedRequests:       ai.MixedRequests,
+		Variance:            ai.Variance,
+		QOSClass:            ai.QOSClass,
+		RecommendedQOSClass: ai.RecommendedQOSClass,
+		Requests:            toResourceList(ai.Requests),
+		Recommendations:     toResourceList(ai.Recommendations),
+		Differences:         ai.Differences,
+	}
+	if ai.Rounding != nil {
+		item.Suggested = toResourceList(ai.Suggested)
+	}
+	for _, c := range ai.Containers {
+		// The containers without a recommendation,
+		// such as sidecars, aren't reported.
+		if c.Recommendation == nil {
+			continue
+		}
+		rc := reportContainer{
+			Name:           c.Name,
+			Type:           c.Type,
+			Requests:       toResourceList(c.Requests),
+			Target:         c.Recommendation.Target,
+			LowerBound:     c.Recommendation.LowerBound,
+			UpperBound:     c.Recommendation.UpperBound,
+			UncappedTarget: c.Recommendation.UncappedTarget,
+			Differences:    c.Differences,
+		}
+		if ai.Rounding != nil {
+			rc.Suggested = toResourceList(c.Suggested)
+		}
+		item.Containers = append(item.Containers, rc)
+	}
+	return item
+}
+
+// clusterName returns the name of the cluster of the
+// current context of the client configuration.
+func (co *CommandOptions) clusterName() string {
+	if co.ClientFlags.ClusterName != nil && *co.ClientFlags.ClusterName != "" {
+		return *co.ClientFlags.ClusterName
+	}
+	raw, err := co.ClientFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	name := raw.CurrentContext
+	if co.ClientFlags.Context != nil && *co.ClientFlags.Context != "" {
+		name = *co.ClientFlags.Context
+	}
+	if ctx, ok := raw.Contexts[name]; ok {
+		return ctx.Cluster
+	}
+	return ""
+}
+
+func toResourceList(rq vpa.ResourceQuantities) corev1.ResourceList {
+	rl := corev1.ResourceList{}
+	for _, name := range rq.Names() {
+		rl[name] = *rq.Get(name)
+	}
+	return rl
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/muesli/termenv"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/pkg/analysis"
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+const (
+	riskLow    = "low"
+	riskMedium = "medium"
+	riskHigh   = "high"
+)
+
+// backOffReason is the reason of the events of the
+// containers that the kubelet fails to restart.
+const backOffReason = "BackOff"
+
+// risk represents the risk of applying a recommendation
+// to a container, or to the containers of a VPA.
+type risk struct {
+	Level   string   `json:"level"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+func riskRank(level string) int {
+	switch level {
+	case riskHigh:
+		return 2
+	case riskMedium:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// assessContainerRisk returns the risk of applying the memory
+// recommendation of a container. The risk is high when the
+// recommendation is lower than the request despite recent OOM
+// kills, and medium when the container was OOM killed, has
+// restarted at least restartThreshold times, or is in back-off.
+func assessContainerRisk(h *vpa.ContainerHealth, req, rec *resource.Quantity, restartThreshold int32) risk {
+	r := risk{Level: riskLow}
+	if h == nil {
+		return r
+	}
+	raise := func(level, reason string) {
+		if riskRank(level) > riskRank(r.Level) {
+			r.Level = level
+		}
+		r.Reasons = append(r.Reasons, reason)
+	}
+	if h.OOMKills > 0 {
+		if req != nil && rec != nil && !rec.IsZero() && rec.Cmp(*req) < 0 {
+			raise(riskHigh, fmt.Sprintf("%d OOM kill(s), memory lowered", h.OOMKills))
+		} else {
+			raise(riskMedium, fmt.Sprintf("%d OOM kill(s)", h.OOMKills))
+		}
+	}
+	if restartThreshold > 0 && h.Restarts >= restartThreshold {
+		raise(riskMedium, fmt.Sprintf("%d restarts", h.Restarts))
+	}
+	if n := h.Events[backOffReason]; n > 0 {
+		raise(riskMedium, fmt.Sprintf("%d back-off event(s)", n))
+	}
+	return r
+}
+
+// assessRisks returns the risk of each container that the VPA
+// has a recommendation for, and the highest risk of all, whose
+// reasons are prefixed by the name of their container.
+func assessRisks(
+	item *analysis.Item,
+	health []*vpa.ContainerHealth,
+	rt vpa.RecommendationType,
+	restartThreshold int32,
+) (risk, map[string]risk) {
+	overall := risk{Level: riskLow}
+	containers := make(map[string]risk)
+
+	byName := make(map[string]*vpa.ContainerHealth, len(health))
+	for _, h := range health {
+		byName[h.Container] = h
+	}
+	for _, c := range item.Containers {
+		if c.Recommendation == nil {
+			continue
+		}
+		req := item.TargetController().GetContainerRequests(c.Name).Memory
+		rec := vpa.ContainerRecommendations(*c.Recommendation, rt).Memory
+
+		r := assessContainerRisk(byName[c.Name], req, rec, restartThreshold)
+		containers[c.Name] = r
+
+		if riskRank(r.Level) > riskRank(overall.Level) {
+			overall.Level = r.Level
+		}
+		for _, reason := range r.Reasons {
+			overall.Reasons = append(overall.Reasons, fmt.Sprintf("%s: %s", c.Name, reason))
+		}
+	}
+	return overall, containers
+}
+
+// warningEvents returns the warning events of the pods of a
+// namespace. The events of each namespace are fetched once.
+func (co *CommandOptions) warningEvents(namespace string) []*corev1.Event {
+	if events, ok := co.events[namespace]; ok {
+		return events
+	}
+	events, err := co.Client.ListEvents(context.Background(), namespace, "type=Warning,involvedObject.kind=Pod")
+	if err != nil {
+		klog.Warningf("couldn't list events of namespace %s: %s", namespace, err)
+	}
+	if co.events == nil {
+		co.events = make(map[string][]*corev1.Event)
+	}
+	co.events[namespace] = events
+
+	return events
+}
+
+func formatRisk(r *risk, flags *Flags) string {
+	if r == nil {
+		return tableUnsetCell
+	}
+	s := r.Level
+	if flags.wide && len(r.Reasons) != 0 {
+		s = fmt.Sprintf("%s (%s)", s, strings.Join(r.Reasons, ", "))
+	}
+	if termenv.EnvNoColor() || flags.NoColors {
+		return s
+	}
+	p := termenv.ColorProfile()
+	ts := termenv.String(s)
+
+	switch r.Level {
+	case riskLow:
+		ts = ts.Foreground(p.Color("#A8CC8C"))
+	case riskMedium:
+		ts = ts.Foreground(p.Color("#DBAB79"))
+	case riskHigh:
+		ts = ts.Foreground(p.Color("#E88388"))
+	}
+	return ts.String()
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func TestAssessContainerRisk(t *testing.T) {
+	req := resource.MustParse("512Mi")
+	lower := resource.MustParse("256Mi")
+	higher := resource.MustParse("1Gi")
+
+	for _, tt := range []struct {
+		name    string
+		health  *vpa.ContainerHealth
+		rec     *resource.Quantity
+		level   string
+		reasons int
+	}{
+		{"no health", nil, &lower, riskLow, 0},
+		{"healthy", &vpa.ContainerHealth{Restarts: 1}, &lower, riskLow, 0},
+		{"oom lowered", &vpa.ContainerHealth{OOMKills: 2}, &lower, riskHigh, 1},
+		{"oom raised", &vpa.ContainerHealth{OOMKills: 2}, &higher, riskMedium, 1},
+		{"restarts", &vpa.ContainerHealth{Restarts: 5}, &lower, riskMedium, 1},
+		{"back-off", &vpa.ContainerHealth{Events: map[string]int32{backOffReason: 3}}, &lower, riskMedium, 1},
+		{
+			"all",
+			&vpa.ContainerHealth{OOMKills: 1, Restarts: 12, Events: map[string]int32{backOffReason: 3}},
+			&lower, riskHigh, 3,
+		},
+	} {
+		r := assessContainerRisk(tt.health, &req, tt.rec, 5)
+		if r.Level != tt.level || len(r.Reasons) != tt.reasons {
+			t.Errorf("%s: got %s with %d reason(s) %v, want %s with %d", tt.name, r.Level, len(r.Reasons), r.Reasons, tt.level, tt.reasons)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+// roundingRules represents the file of the rounding
+// rules applied to compute suggested recommendations.
+type roundingRules struct {
+	Default *vpa.RoundingRule  `json:"default,omitempty"`
+	Rules   []vpa.RoundingRule `json:"rules,omitempty"`
+}
+
+// newRounder returns the rounder configured by the flags,
+// or nil if the suggested recommendations are disabled.
+func newRounder(flags *Flags) (*vpa.Rounder, error) {
+	if flags.RoundingRules == "" && !flags.ShowSuggested {
+		return nil, nil
+	}
+	r := &vpa.Rounder{Default: vpa.DefaultRoundingRule()}
+
+	if flags.RoundingRules != "" {
+		b, err := os.ReadFile(flags.RoundingRules)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read rounding rules: %w", err)
+		}
+		rr := &roundingRules{}
+		if err := yaml.UnmarshalStrict(b, rr); err != nil {
+			return nil, fmt.Errorf("couldn't decode rounding rules %s: %w", flags.RoundingRules, err)
+		}
+		if rr.Default != nil {
+			r.Default = *rr.Default
+		}
+		r.Rules = rr.Rules
+	}
+	if err := r.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rounding rules: %w", err)
+	}
+	return r, nil
+}
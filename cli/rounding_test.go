@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestNewRounder(t *testing.T) {
+	r, err := newRounder(DefaultFlags())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != nil {
+		t.Fatal("expected no rounder when suggestions are disabled")
+	}
+	path := filepath.Join(t.TempDir(), "rounding.yaml")
+	err = os.WriteFile(path, []byte(`
+rules:
+  - namespaces: ["batch-*"]
+    selector: team=data
+    headroom: 25
+    resources:
+      memory:
+        powerOfTwo: true
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags := DefaultFlags()
+	flags.RoundingRules = path
+
+	r, err = newRounder(flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := r.RuleFor("batch-etl", map[string]string{"team": "data"}, "Deployment")
+	if rule.Headroom != 25 {
+		t.Fatalf("got headroom %v, want 25", rule.Headroom)
+	}
+	q := resource.MustParse("1Gi")
+	if got := rule.RoundQuantity(corev1.ResourceMemory, &q); got.String() != "2Gi" {
+		t.Errorf("got %s, want 2Gi", got)
+	}
+	// Without a default rule in the file, the
+	// built-in default rule applies.
+	q = resource.MustParse("587m")
+	if got := r.RuleFor("payments", nil, "Deployment").RoundQuantity(corev1.ResourceCPU, &q); got.String() != "600m" {
+		t.Errorf("got %s, want 600m", got)
+	}
+	if err := os.WriteFile(path, []byte("rules:\n  - headroom: -5\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newRounder(flags); err == nil {
+		t.Error("expected error for negative headroom")
+	}
+}
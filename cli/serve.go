@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const (
+	defaultListenAddress  = ":8080"
+	defaultScrapeInterval = 5 * time.Minute
+	serverShutdownTimeout = 10 * time.Second
+)
+
+// serveOptions represents the options of the serve command.
+type serveOptions struct {
+	*CommandOptions
+
+	ListenAddress string
+	Interval      time.Duration
+
+	exporter *exporter
+}
+
+// newServeCmd returns a new command that periodically runs
+// the comparison and exposes the results as Prometheus metrics.
+func newServeCmd(co *CommandOptions) *cobra.Command {
+	so := &serveOptions{
+		CommandOptions: co,
+		ListenAddress:  defaultListenAddress,
+		Interval:       defaultScrapeInterval,
+		exporter:       &exporter{},
+	}
+	cmd := &cobra.Command{
+		Use:   "serve [options]",
+		Short: "Periodically compare recommendations and expose the results as Prometheus metrics",
+		Long: `Periodically compare recommendations and expose the results as Prometheus metrics.
+
+The server exposes the following endpoints:
+  /metrics          Prometheus metrics
+  /recommendations  JSON report of the last comparison, filtered by the optional 'namespace' and 'name' query parameters
+  /healthz          Liveness probe
+  /readyz           Readiness probe, successful once a first comparison completed`,
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		Run:                   so.Run,
+	}
+	flags := cmd.Flags()
+
+	flags.StringVar(&so.ListenAddress, "listen-address", so.ListenAddress,
+		"The address on which the HTTP server listens")
+	flags.DurationVar(&so.Interval, "interval", so.Interval,
+		"The interval between two comparisons")
+
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (so *serveOptions) Run(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(so.Complete(c, args))
+	cmdutil.CheckErr(so.Validate(c, args))
+	cmdutil.CheckErr(so.Execute())
+}
+
+// Validate ensure that required options to run the
+// command are set and valid.
+func (so *serveOptions) Validate(c *cobra.Command, args []string) error {
+	if so.Interval <= 0 {
+		return fmt.Errorf("interval must be strictly positive")
+	}
+	return so.CommandOptions.Validate(c, args)
+}
+
+// Execute runs the command.
+func (so *serveOptions) Execute() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		so.exporter,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/recommendations", so.serveReport)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if so.exporter.lastReport() == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := &http.Server{
+		Addr:              so.ListenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		klog.Infof("listening on %s", so.ListenAddress)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+	go so.loop(ctx)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	sctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+
+	return srv.Shutdown(sctx)
+}
+
+// loop runs the comparison at each interval
+// until the context is done.
+func (so *serveOptions) loop(ctx context.Context) {
+	ticker := time.NewTicker(so.Interval)
+	defer ticker.Stop()
+
+	for {
+		so.scrape(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (so *serveOptions) scrape(ctx context.Context) {
+	start := time.Now()
+
+	vpas, err := so.listVPAs(ctx)
+	if err != nil {
+		klog.Errorf("couldn't list VPA resources: %s", err)
+		so.exporter.update(nil, 0, time.Since(start), err)
+		return
+	}
+	r, skipped := so.newReport(ctx, vpas)
+	d := time.Since(start)
+
+	klog.V(4).Infof("compared %d VPA(s) in %s, %d skipped", len(r.Items), d, skipped)
+	so.exporter.update(r, skipped, d, nil)
+}
+
+// serveReport writes the last report as JSON, filtered by the
+// optional namespace and name query parameters.
+func (so *serveOptions) serveReport(w http.ResponseWriter, req *http.Request) {
+	r := so.exporter.lastReport()
+	if r == nil {
+		http.Error(w, "no comparison completed yet", http.StatusServiceUnavailable)
+		return
+	}
+	ns := req.URL.Query().Get("namespace")
+	name := req.URL.Query().Get("name")
+
+	filtered := *r
+	filtered.Items = make([]reportItem, 0, len(r.Items))
+
+	for _, item := range r.Items {
+		if (ns == "" || item.Namespace == ns) && (name == "" || item.Name == name) {
+			filtered.Items = append(filtered.Items, item)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(filtered); err != nil {
+		klog.Errorf("couldn't write report: %s", err)
+	}
+}
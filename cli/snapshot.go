@@ -0,0 +1,388 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+	"k8s.io/kubectl/pkg/cmd/get"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/yaml"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+const snapshotFileTimeLayout = "20060102T150405Z"
+
+// snapshotDiffStatus represents the status of
+// a container between two snapshots.
+type snapshotDiffStatus string
+
+const (
+	snapshotAdded     snapshotDiffStatus = "added"
+	snapshotRemoved   snapshotDiffStatus = "removed"
+	snapshotChanged   snapshotDiffStatus = "changed"
+	snapshotUnchanged snapshotDiffStatus = "unchanged"
+)
+
+// snapshotDiffRow represents the changes of the requests and
+// recommendation of a container between two snapshots.
+type snapshotDiffRow struct {
+	Namespace         string
+	Name              string
+	Container         string
+	Status            snapshotDiffStatus
+	OldRequests       corev1.ResourceList
+	NewRequests       corev1.ResourceList
+	OldRecommendation corev1.ResourceList
+	NewRecommendation corev1.ResourceList
+	CPUChange         *float64 // % change of the CPU recommendation
+	MemoryChange      *float64 // % change of the memory recommendation
+}
+
+type snapshotContainerKey struct {
+	namespace, name, container string
+}
+
+// diffReports returns the changes of each container between two
+// reports, using the given type of recommendation. The rows are
+// sorted by namespace, name and container.
+func diffReports(old, cur *report, rt vpa.RecommendationType) []snapshotDiffRow {
+	index := func(r *report) map[snapshotContainerKey]reportContainer {
+		m := make(map[snapshotContainerKey]reportContainer)
+		for _, item := range r.Items {
+			for _, c := range item.Containers {
+				m[snapshotContainerKey{item.Namespace, item.Name, c.Name}] = c
+			}
+		}
+		return m
+	}
+	oldIndex, curIndex := index(old), index(cur)
+
+	var rows []snapshotDiffRow
+
+	for k, oc := range oldIndex {
+		row := snapshotDiffRow{
+			Namespace:         k.namespace,
+			Name:              k.name,
+			Container:         k.container,
+			Status:            snapshotRemoved,
+			OldRequests:       oc.Requests,
+			OldRecommendation: containerRecommendationByType(oc, rt),
+		}
+		if cc, ok := curIndex[k]; ok {
+			row.NewRequests = cc.Requests
+			row.NewRecommendation = containerRecommendationByType(cc, rt)
+			row.CPUChange = diffResourceAsPercent(row.NewRecommendation, row.OldRecommendation, corev1.ResourceCPU)
+			row.MemoryChange = diffResourceAsPercent(row.NewRecommendation, row.OldRecommendation, corev1.ResourceMemory)
+
+			if resourceListsEqual(row.OldRequests, row.NewRequests) && resourceListsEqual(row.OldRecommendation, row.NewRecommendation) {
+				row.Status = snapshotUnchanged
+			} else {
+				row.Status = snapshotChanged
+			}
+		}
+		rows = append(rows, row)
+	}
+	for k, cc := range curIndex {
+		if _, ok := oldIndex[k]; ok {
+			continue
+		}
+		rows = append(rows, snapshotDiffRow{
+			Namespace:         k.namespace,
+			Name:              k.name,
+			Container:         k.container,
+			Status:            snapshotAdded,
+			NewRequests:       cc.Requests,
+			NewRecommendation: containerRecommendationByType(cc, rt),
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Namespace != rows[j].Namespace {
+			return rows[i].Namespace < rows[j].Namespace
+		}
+		if rows[i].Name != rows[j].Name {
+			return rows[i].Name < rows[j].Name
+		}
+		return rows[i].Container < rows[j].Container
+	})
+	return rows
+}
+
+// filterSnapshotDiffRows returns the rows that changed. If minChange
+// is strictly positive, only the rows whose recommendation shifted by
+// more than minChange percent, or that were added or removed, are kept.
+func filterSnapshotDiffRows(rows []snapshotDiffRow, minChange float64) []snapshotDiffRow {
+	exceeds := func(f *float64) bool {
+		return f != nil && math.Abs(*f) > minChange
+	}
+	ret := make([]snapshotDiffRow, 0, len(rows))
+
+	for _, row := range rows {
+		switch {
+		case row.Status == snapshotUnchanged:
+			continue
+		case row.Status == snapshotChanged && minChange > 0:
+			if !exceeds(row.CPUChange) && !exceeds(row.MemoryChange) {
+				continue
+			}
+		}
+		ret = append(ret, row)
+	}
+	return ret
+}
+
+func diffResourceAsPercent(cur, old corev1.ResourceList, name corev1.ResourceName) *float64 {
+	c, ok1 := cur[name]
+	o, ok2 := old[name]
+	if !ok1 || !ok2 {
+		return nil
+	}
+	return vpa.DiffQuantitiesAsPercent(&c, &o)
+}
+
+func resourceListsEqual(a, b corev1.ResourceList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, qa := range a {
+		qb, ok := b[name]
+		if !ok || qa.Cmp(qb) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// snapshotOptions represents the options of the snapshot commands.
+type snapshotOptions struct {
+	*CommandOptions
+
+	File      string
+	Directory string
+	MinChange float64
+}
+
+// newSnapshotCmd returns a new command that groups
+// the commands to save and compare snapshots.
+func newSnapshotCmd(co *CommandOptions, f cmdutil.Factory) *cobra.Command {
+	so := &snapshotOptions{CommandOptions: co}
+
+	cmd := &cobra.Command{
+		Use:                   "snapshot",
+		Short:                 "Save and compare snapshots of recommendations and requests",
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+	save := &cobra.Command{
+		Use:                   "save [NAME...] [options]",
+		Short:                 "Save a snapshot of the comparison to a file",
+		Args:                  cobra.ArbitraryArgs,
+		DisableFlagsInUseLine: true,
+		Run:                   so.RunSave,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, tc string) ([]string, cobra.ShellCompDirective) {
+			comps := get.CompGetResource(f, cmd, vpaPlural, tc)
+			return comps, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	save.Flags().StringVarP(&so.File, "file", "f", so.File,
+		"The file to write the snapshot to. Defaults to the standard output")
+	save.Flags().StringVar(&so.Directory, "dir", so.Directory,
+		"The directory to write the snapshot to, in a file named after the cluster and the current time. The directory is created if missing")
+
+	diff := &cobra.Command{
+		Use:   "diff OLD [NEW] [options]",
+		Short: "Compare two snapshots, or a snapshot with the live recommendations",
+		Long: `Compare two snapshots, or a snapshot with the live recommendations.
+
+If only one snapshot file is given, it is compared with the live
+recommendations of the VPA resources selected with the namespace
+and selector flags. The type of recommendation to compare is set
+with the --recommendation-type flag.`,
+		Args:                  cobra.RangeArgs(1, 2),
+		DisableFlagsInUseLine: true,
+		Run:                   so.RunDiff,
+	}
+	diff.Flags().Float64Var(&so.MinChange, "min-change", so.MinChange,
+		"Only list the containers whose recommendation shifted by more than this percentage")
+
+	cmd.AddCommand(save, diff)
+
+	return cmd
+}
+
+// RunSave is the method called by cobra to run the save command.
+func (so *snapshotOptions) RunSave(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(so.Complete(c, args))
+	cmdutil.CheckErr(so.Validate(c, args))
+	cmdutil.CheckErr(so.ExecuteSave())
+}
+
+// ExecuteSave runs the save command.
+func (so *snapshotOptions) ExecuteSave() error {
+	if so.File != "" && so.Directory != "" {
+		return fmt.Errorf("--file and --dir are mutually exclusive")
+	}
+	r, err := so.liveReport()
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := so.File
+	if so.Directory != "" {
+		name := r.Timestamp.UTC().Format(snapshotFileTimeLayout) + ".json"
+		if r.Cluster != "" {
+			name = r.Cluster + "-" + name
+		}
+		if err := os.MkdirAll(so.Directory, 0o700); err != nil {
+			return fmt.Errorf("couldn't create snapshot directory: %w", err)
+		}
+		path = filepath.Join(so.Directory, name)
+	}
+	if path == "" {
+		_, err = fmt.Fprintln(so.Out, string(b))
+		return err
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o600); err != nil {
+		return fmt.Errorf("couldn't write snapshot: %w", err)
+	}
+	klog.V(2).Infof("snapshot written to %s", path)
+
+	return nil
+}
+
+// RunDiff is the method called by cobra to run the diff command.
+func (so *snapshotOptions) RunDiff(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(so.ExecuteDiff(c, args))
+}
+
+// ExecuteDiff runs the diff command.
+func (so *snapshotOptions) ExecuteDiff(c *cobra.Command, args []string) error {
+	old, err := loadSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+	var cur *report
+
+	if len(args) == 2 {
+		cur, err = loadSnapshot(args[1])
+	} else {
+		// The arguments are the snapshot files, the VPA
+		// resources are only selected with the flags.
+		if err := so.Complete(c, nil); err != nil {
+			return err
+		}
+		cur, err = so.liveReport()
+	}
+	if err != nil {
+		return err
+	}
+	if old.Cluster != cur.Cluster {
+		klog.Warningf("comparing snapshots of different clusters: %q and %q", old.Cluster, cur.Cluster)
+	}
+	rows := filterSnapshotDiffRows(diffReports(old, cur, so.Flags.RecommendationType), so.MinChange)
+	if len(rows) == 0 {
+		fmt.Fprintln(so.Out, "No changes found.")
+		return nil
+	}
+	return printSnapshotDiff(so.Out, rows, so.Flags)
+}
+
+func (so *snapshotOptions) liveReport() (*report, error) {
+	ctx := context.Background()
+
+	vpas, err := so.listVPAs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, _ := so.newReport(ctx, vpas)
+
+	return r, nil
+}
+
+// loadSnapshot reads and decodes the snapshot file at path.
+func loadSnapshot(path string) (*report, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read snapshot: %w", err)
+	}
+	r := &report{}
+	if err := yaml.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("couldn't decode snapshot %s: %w", path, err)
+	}
+	return r, nil
+}
+
+func printSnapshotDiff(w io.Writer, rows []snapshotDiffRow, flags *Flags) error {
+	tw := newKubectlTableWriter(w)
+
+	if !flags.NoHeaders {
+		rt := flags.RecommendationType.String()
+		tw.SetHeader([]string{
+			hdrNamespace,
+			hdrName,
+			"Container",
+			"Status",
+			hdrCPURequest,
+			"CPU " + rt,
+			"% CPU Change",
+			hdrMemRequest,
+			"Memory " + rt,
+			"% Memory Change",
+		})
+	}
+	for _, row := range rows {
+		oldMemReq, newMemReq := quantityOf(row.OldRequests, corev1.ResourceMemory), quantityOf(row.NewRequests, corev1.ResourceMemory)
+
+		tw.Append([]string{
+			row.Namespace,
+			row.Name,
+			row.Container,
+			string(row.Status),
+			formatQuantityChange(
+				formatQuantity(quantityOf(row.OldRequests, corev1.ResourceCPU)),
+				formatQuantity(quantityOf(row.NewRequests, corev1.ResourceCPU)),
+			),
+			formatQuantityChange(
+				formatQuantity(quantityOf(row.OldRecommendation, corev1.ResourceCPU)),
+				formatQuantity(quantityOf(row.NewRecommendation, corev1.ResourceCPU)),
+			),
+			formatPercentage(row.CPUChange, nil, corev1.ResourceCPU, flags),
+			formatQuantityChange(formatQuantity(oldMemReq), formatQuantity(newMemReq)),
+			formatQuantityChange(
+				formatMemoryRecommendation(quantityOf(row.OldRecommendation, corev1.ResourceMemory), oldMemReq),
+				formatMemoryRecommendation(quantityOf(row.NewRecommendation, corev1.ResourceMemory), newMemReq),
+			),
+			formatPercentage(row.MemoryChange, nil, corev1.ResourceMemory, flags),
+		})
+	}
+	tw.Render()
+
+	return nil
+}
+
+func quantityOf(rl corev1.ResourceList, name corev1.ResourceName) *resource.Quantity {
+	if q, ok := rl[name]; ok {
+		return &q
+	}
+	return nil
+}
+
+func formatQuantityChange(old, cur string) string {
+	if old == cur {
+		return cur
+	}
+	return fmt.Sprintf("%s → %s", old, cur)
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func TestDiffReports(t *testing.T) {
+	container := func(name, req, rec string) reportContainer {
+		return reportContainer{
+			Name:     name,
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(req)},
+			Target:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(rec)},
+		}
+	}
+	old := &report{Items: []reportItem{
+		{Namespace: "athens", Name: "zeus", Containers: []reportContainer{
+			container("thunder", "1", "500m"),
+			container("lightning", "1", "500m"),
+		}},
+		{Namespace: "sparta", Name: "hera", Containers: []reportContainer{
+			container("peacock", "100m", "100m"),
+		}},
+		{Namespace: "thebes", Name: "artemis", Containers: []reportContainer{
+			container("bow", "200m", "100m"),
+		}},
+	}}
+	cur := &report{Items: []reportItem{
+		{Namespace: "athens", Name: "zeus", Containers: []reportContainer{
+			container("thunder", "1", "750m"),
+			container("lightning", "1", "510m"),
+		}},
+		{Namespace: "sparta", Name: "hera", Containers: []reportContainer{
+			container("peacock", "100m", "100m"),
+		}},
+		{Namespace: "olympia", Name: "apollo", Containers: []reportContainer{
+			container("lyre", "50m", "10m"),
+		}},
+	}}
+	rows := diffReports(old, cur, vpa.RecommendationTarget)
+
+	want := []struct {
+		name, container string
+		status          snapshotDiffStatus
+	}{
+		{"zeus", "lightning", snapshotChanged},
+		{"zeus", "thunder", snapshotChanged},
+		{"apollo", "lyre", snapshotAdded},
+		{"hera", "peacock", snapshotUnchanged},
+		{"artemis", "bow", snapshotRemoved},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		if rows[i].Name != w.name || rows[i].Container != w.container || rows[i].Status != w.status {
+			t.Errorf("row %d: got %s/%s (%s), want %s/%s (%s)",
+				i, rows[i].Name, rows[i].Container, rows[i].Status, w.name, w.container, w.status)
+		}
+	}
+	if c := rows[1].CPUChange; c == nil || *c != 50 {
+		t.Errorf("expected a CPU change of 50%%, got %v", c)
+	}
+	if n := len(filterSnapshotDiffRows(rows, 0)); n != 4 {
+		t.Errorf("got %d changed rows, want 4", n)
+	}
+	if n := len(filterSnapshotDiffRows(rows, 10)); n != 3 {
+		t.Errorf("got %d rows with a change above 10%%, want 3", n)
+	}
+}
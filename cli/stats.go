@@ -0,0 +1,338 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/inf.v0"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/kubectl/pkg/cmd/get"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/internal/humanize"
+	"github.com/wI2L/kubectl-vpa-recommendation/stats"
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+const histogramWidth = 40
+
+// statsReport represents the statistics of
+// the rows of a table, for each resource.
+type statsReport struct {
+	Rows      int             `json:"rows"`
+	Resources []resourceStats `json:"resources"`
+}
+
+// resourceStats represents the statistics of the requests,
+// recommendations and differences of a resource.
+type resourceStats struct {
+	Resource        corev1.ResourceName `json:"resource"`
+	Requests        quantityStats       `json:"requests"`
+	Recommendations quantityStats       `json:"recommendations"`
+	Bands           stats.Bands         `json:"bands"`
+	Histogram       stats.Histogram     `json:"histogram"`
+}
+
+// quantityStats represents the statistics of
+// the quantities of a column of a table.
+type quantityStats struct {
+	Count       int                `json:"count"`
+	Total       *resource.Quantity `json:"total"`
+	Mean        *resource.Quantity `json:"mean,omitempty"`
+	Median      *resource.Quantity `json:"median,omitempty"`
+	Percentiles []percentileStat   `json:"percentiles,omitempty"`
+	Min         *resource.Quantity `json:"min,omitempty"`
+	Max         *resource.Quantity `json:"max,omitempty"`
+	StdDev      *resource.Quantity `json:"stddev,omitempty"`
+}
+
+type percentileStat struct {
+	Percentile float64            `json:"percentile"`
+	Value      *resource.Quantity `json:"value,omitempty"`
+}
+
+// newStatsReport returns the statistics of the rows of the
+// table. The requests and recommendations are scaled by the
+// number of replicas of the targets.
+func newStatsReport(t table, flags *Flags) statsReport {
+	r := statsReport{Rows: len(t)}
+
+	for _, name := range flags.resourceNames() {
+		var (
+			reqs  = make([]*resource.Quantity, 0, len(t))
+			recs  = make([]*resource.Quantity, 0, len(t))
+			diffs = make([]float64, 0, len(t))
+			bands stats.Bands
+		)
+		for _, row := range t {
+			replicas := int64(row.TargetReplicas)
+			reqs = append(reqs, multiplyQuantity(row.Requests.Get(name), replicas))
+			recs = append(recs, multiplyQuantity(row.Recommendations.Get(name), replicas))
+
+			if d := row.Differences[name]; d != nil {
+				diffs = append(diffs, *d)
+
+				th := thresholdsFor(row.Thresholds, name, *d, flags)
+				bands.Add(*d, th.Warning, th.Critical)
+			}
+		}
+		r.Resources = append(r.Resources, resourceStats{
+			Resource:        name,
+			Requests:        newQuantityStats(stats.NewSample(reqs), flags.Percentiles),
+			Recommendations: newQuantityStats(stats.NewSample(recs), flags.Percentiles),
+			Bands:           bands,
+			Histogram:       stats.NewHistogram(diffs, histogramBounds(name, flags)),
+		})
+	}
+	return r
+}
+
+func newQuantityStats(s *stats.Sample, percentiles []float64) quantityStats {
+	qs := quantityStats{
+		Count:  s.Len(),
+		Total:  s.Sum(),
+		Mean:   s.Mean(),
+		Median: s.Median(),
+		Min:    s.Min(),
+		Max:    s.Max(),
+		StdDev: s.StdDev(),
+	}
+	for _, p := range percentiles {
+		qs.Percentiles = append(qs.Percentiles, percentileStat{
+			Percentile: p,
+			Value:      s.Percentile(p),
+		})
+	}
+	return qs
+}
+
+// histogramBounds returns the bounds of the buckets of the
+// histogram of the differences of the named resource, which
+// are the thresholds of the default rule in both directions,
+// and +100%, where the request is twice the recommendation.
+func histogramBounds(name corev1.ResourceName, flags *Flags) []float64 {
+	under := thresholdsFor(nil, name, -1, flags)
+	over := thresholdsFor(nil, name, 1, flags)
+
+	bounds := []float64{-under.Critical, -under.Warning, 0, over.Warning, over.Critical}
+	if over.Critical < 100 {
+		bounds = append(bounds, 100)
+	}
+	return bounds
+}
+
+// bandHeaders returns the headers of the table of the bands.
+// With threshold rules, the thresholds may vary per workload,
+// and the bands are named after their severity instead.
+func bandHeaders(flags *Flags) []string {
+	if flags.ThresholdRules != "" {
+		return []string{"Resource", "Under Critical", "Under Warning", "Within", "Over Warning", "Over Critical"}
+	}
+	warn, crit := flags.WarningThreshold, flags.CriticalThreshold
+
+	return []string{
+		"Resource",
+		fmt.Sprintf("Under <= -%g%%", crit),
+		fmt.Sprintf("Under <= -%g%%", warn),
+		"Within",
+		fmt.Sprintf("Over >= %g%%", warn),
+		fmt.Sprintf("Over >= %g%%", crit),
+	}
+}
+
+// printStats writes the statistics of the report to w.
+func printStats(w io.Writer, r statsReport, flags *Flags) error {
+	tw := newKubectlTableWriter(w)
+
+	headers := []string{"Description", "Total", "Mean", "Median"}
+	for _, p := range flags.Percentiles {
+		headers = append(headers, "P"+strconv.FormatFloat(p, 'f', -1, 64))
+	}
+	headers = append(headers, "Min", "Max", "StdDev")
+
+	for _, rs := range r.Resources {
+		var desc string
+
+		switch {
+		case rs.Resource == corev1.ResourceCPU:
+			desc = "CPU %s (# cores)"
+		case rs.Resource == corev1.ResourceMemory:
+			desc = "MEM %s (IEC/SI)"
+		case vpa.IsBytesResource(rs.Resource):
+			desc = string(rs.Resource) + " %s (IEC/SI)"
+		default:
+			desc = string(rs.Resource) + " %s"
+		}
+		asBytes := vpa.IsBytesResource(rs.Resource)
+
+		for _, s := range []struct {
+			name string
+			qs   quantityStats
+		}{
+			{"Requests", rs.Requests},
+			{"Recommendations", rs.Recommendations},
+		} {
+			row := []string{
+				fmt.Sprintf(desc, s.name),
+				formatStat(s.qs.Total, asBytes),
+				formatStat(s.qs.Mean, asBytes),
+				formatStat(s.qs.Median, asBytes),
+			}
+			for _, p := range s.qs.Percentiles {
+				row = append(row, formatStat(p.Value, asBytes))
+			}
+			row = append(row,
+				formatStat(s.qs.Min, asBytes),
+				formatStat(s.qs.Max, asBytes),
+				formatStat(s.qs.StdDev, asBytes),
+			)
+			tw.Append(row)
+		}
+	}
+	tw.SetHeader(headers)
+	tw.Render()
+
+	fmt.Fprintln(w)
+
+	tw = newKubectlTableWriter(w)
+	tw.SetHeader(bandHeaders(flags))
+	for _, rs := range r.Resources {
+		tw.Append([]string{
+			string(rs.Resource),
+			strconv.Itoa(rs.Bands.UnderCritical),
+			strconv.Itoa(rs.Bands.UnderWarning),
+			strconv.Itoa(rs.Bands.Within),
+			strconv.Itoa(rs.Bands.OverWarning),
+			strconv.Itoa(rs.Bands.OverCritical),
+		})
+	}
+	tw.Render()
+
+	if !flags.ShowHistogram {
+		return nil
+	}
+	for _, rs := range r.Resources {
+		fmt.Fprintf(w, "\n%% %s difference distribution:\n", rs.Resource)
+		if err := rs.Histogram.Render(w, histogramWidth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatStat formats a statistic with two decimals, or as
+// a number of bytes in both IEC and SI units if asBytes is
+// true. The quantity isn't mutated.
+func formatStat(q *resource.Quantity, asBytes bool) string {
+	if q == nil {
+		return tableUnsetCell
+	}
+	c := q.DeepCopy()
+	d := inf.Dec{}
+
+	if asBytes {
+		d.Round(c.AsDec(), 0, inf.RoundUp)
+		b := d.UnscaledBig()
+		s := humanize.BigIBytes(b, 2) + "/" + humanize.BigBytes(b, 2)
+		return strings.ReplaceAll(s, " ", "")
+	}
+	return d.Round(c.AsDec(), 2, inf.RoundCeil).String()
+}
+
+// statsOptions represents the options of the stats command.
+type statsOptions struct {
+	*CommandOptions
+
+	Output string
+}
+
+// newStatsCmd returns a new command that prints the
+// statistics of the requests and recommendations.
+func newStatsCmd(co *CommandOptions, f cmdutil.Factory) *cobra.Command {
+	so := &statsOptions{CommandOptions: co}
+
+	cmd := &cobra.Command{
+		Use:   "stats [NAME...] [options]",
+		Short: "Print the statistics of the requests and recommendations",
+		Long: `Print the statistics of the requests and recommendations.
+
+For each resource, the requests and recommendations of the targets are
+multiplied by their replicas, and summarized with their total, mean,
+median, percentiles, min, max and standard deviation. The differences
+are counted per band of the warning and critical thresholds, and their
+distribution is drawn as a histogram.`,
+		Args:                  cobra.ArbitraryArgs,
+		DisableFlagsInUseLine: true,
+		Run:                   so.Run,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, tc string) ([]string, cobra.ShellCompDirective) {
+			comps := get.CompGetResource(f, cmd, vpaPlural, tc)
+			return comps, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+	cmd.Flags().StringVarP(&so.Output, flagOutput, flagOutputShorthand, so.Output,
+		"Output format. One of 'json'")
+	cmd.Flags().StringSliceVar(&so.Flags.Resources, flagResources, so.Flags.Resources,
+		"Comma-separated list of the resources to summarize")
+	cmd.Flags().StringVar(&so.Flags.Baseline, flagBaseline, so.Flags.Baseline,
+		"Path to a baseline file of accepted differences to exclude from the statistics")
+	so.Flags.AddStatsFlags(cmd.Flags())
+
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (so *statsOptions) Run(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(so.Complete(c, args))
+	cmdutil.CheckErr(so.Validate(c, args))
+	cmdutil.CheckErr(so.Execute())
+}
+
+// Validate ensure that required options to run the
+// command are set and valid.
+func (so *statsOptions) Validate(c *cobra.Command, args []string) error {
+	switch so.Output {
+	case "", jsonOutput:
+	default:
+		return fmt.Errorf("unsupported output format: %s", so.Output)
+	}
+	return so.CommandOptions.Validate(c, args)
+}
+
+// Execute runs the command.
+func (so *statsOptions) Execute() error {
+	vpas, err := so.listVPAs(context.Background())
+	if err != nil {
+		return err
+	}
+	if len(vpas) == 0 {
+		so.printNoResources()
+		return nil
+	}
+	t := so.bindRecommendationsAndRequests(vpas)
+
+	if so.Flags.Baseline != "" {
+		bl, err := loadBaseline(so.Flags.Baseline)
+		if err != nil {
+			return err
+		}
+		t = bl.Apply(t, so.Flags.resourceNames(), time.Now(), false)
+	}
+	r := newStatsReport(t.withoutBaselined(), so.Flags)
+
+	if so.Output == jsonOutput {
+		enc := json.NewEncoder(so.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	f := *so.Flags
+	f.ShowHistogram = true
+
+	return printStats(so.Out, r, &f)
+}
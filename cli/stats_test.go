@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/pointer"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func TestNewStatsReport(t *testing.T) {
+	newRow := func(replicas int32, req, rec string, diff float64) *tableRow {
+		rq, rc := resource.MustParse(req), resource.MustParse(rec)
+		return &tableRow{
+			TargetReplicas:  replicas,
+			Requests:        vpa.ResourceQuantities{CPU: &rq},
+			Recommendations: vpa.ResourceQuantities{CPU: &rc},
+			Differences:     map[corev1.ResourceName]*float64{corev1.ResourceCPU: pointer.Float64(diff)},
+		}
+	}
+	tbl := table{
+		newRow(1, "100m", "50m", 100),
+		newRow(2, "200m", "200m", 0),
+		newRow(1, "300m", "1", -70),
+	}
+	flags := DefaultFlags()
+	flags.Resources = []string{"cpu"}
+
+	r := newStatsReport(tbl, flags)
+	if r.Rows != 3 || len(r.Resources) != 1 {
+		t.Fatalf("got %d rows and %d resources, want 3 and 1", r.Rows, len(r.Resources))
+	}
+	rs := r.Resources[0]
+
+	// The requests are scaled by the replicas: 100m, 400m, 300m.
+	for _, tt := range []struct {
+		name string
+		got  *resource.Quantity
+		want string
+	}{
+		{"total", rs.Requests.Total, "800m"},
+		{"median", rs.Requests.Median, "300m"},
+		{"max", rs.Requests.Max, "400m"},
+		{"p90", rs.Requests.Percentiles[1].Value, "380m"},
+		{"recommendations total", rs.Recommendations.Total, "1450m"},
+	} {
+		if tt.got == nil || tt.got.Cmp(resource.MustParse(tt.want)) != 0 {
+			t.Errorf("%s: got %v, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+	if b := rs.Bands; b.OverCritical != 1 || b.Within != 1 || b.UnderCritical != 1 {
+		t.Errorf("unexpected bands: %+v", b)
+	}
+	// The quantities of the rows must not be mutated.
+	if q := tbl[1].Requests.CPU; q.Cmp(resource.MustParse("200m")) != 0 {
+		t.Errorf("row request mutated: got %s, want 200m", q)
+	}
+}
+
+func TestHistogramBounds(t *testing.T) {
+	flags := DefaultFlags()
+	flags.WarningThreshold, flags.CriticalThreshold = 20, 50
+	flags.thresholder = vpa.NewThresholder(20, 50)
+	flags.thresholder.Default.Resources = map[corev1.ResourceName]vpa.DirectionalThresholds{
+		corev1.ResourceMemory: {
+			Under: &vpa.Thresholds{Warning: 5, Critical: 10},
+			Over:  &vpa.Thresholds{Warning: 100, Critical: 200},
+		},
+	}
+	for _, tt := range []struct {
+		name corev1.ResourceName
+		want []float64
+	}{
+		{corev1.ResourceCPU, []float64{-50, -20, 0, 20, 50, 100}},
+		{corev1.ResourceMemory, []float64{-10, -5, 0, 100, 200}},
+	} {
+		if got := histogramBounds(tt.name, flags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormatStat(t *testing.T) {
+	q := resource.MustParse("1536Mi")
+
+	if got, want := formatStat(&q, true), "1.50Gi/1.61GB"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	c := resource.MustParse("1234m")
+	if got, want := formatStat(&c, false), "1.24"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := formatStat(nil, false); got != tableUnsetCell {
+		t.Errorf("got %q, want %q", got, tableUnsetCell)
+	}
+}
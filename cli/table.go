@@ -7,11 +7,14 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/muesli/termenv"
 	"github.com/olekukonko/tablewriter"
 	"gopkg.in/inf.v0"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/wI2L/kubectl-vpa-recommendation/internal/humanize"
@@ -50,27 +53,56 @@ func (so *sortOrder) Set(s string) error {
 
 // tableRow represents a single row of a table.
 type tableRow struct {
-	Name             string
-	Namespace        string
-	GVK              schema.GroupVersionKind
-	Mode             string
-	Target           *vpa.TargetController
-	TargetName       string
-	TargetGVK        schema.GroupVersionKind
-	TargetReplicas   int32
-	Requests         vpa.ResourceQuantities
-	Recommendations  vpa.ResourceQuantities
-	CPUDifference    *float64
-	MemoryDifference *float64
-	Children         []*tableRow
+	Name                string
+	ContainerName       string
+	ContainerType       vpa.ContainerType
+	Namespace           string
+	GVK                 schema.GroupVersionKind
+	Mode                string
+	Recommenders        []string
+	Target              *vpa.TargetController
+	TargetName          string
+	TargetGVK           schema.GroupVersionKind
+	TargetReplicas      int32
+	MixedRequests       bool
+	Group               string
+	QOSClass            corev1.PodQOSClass
+	RecommendedQOSClass corev1.PodQOSClass
+	Risk                *risk
+	Thresholds          *vpa.ThresholdRule
+	RecommendedAt       *metav1.Time
+	RecommendedAtSource vpa.RecommendationTimeSource
+	OutdatedTemplate    bool
+	Requests            vpa.ResourceQuantities
+	Recommendations     vpa.ResourceQuantities
+	Suggested           vpa.ResourceQuantities
+	Differences         map[corev1.ResourceName]*float64
+	Baseline            baselineState
+	Children            []*tableRow
 }
 
 func (tr tableRow) toTableData(flags *Flags, isChild bool) []string {
+	if tr.Baseline == baselineAccepted && !termenv.EnvNoColor() && !flags.NoColors {
+		// Rows accepted by a baseline are dimmed, so
+		// the percentages are formatted without colors.
+		f := *flags
+		f.NoColors = true
+		rowData := tr.toTableData(&f, isChild)
+		for i, v := range rowData {
+			rowData[i] = termenv.String(v).Faint().String()
+		}
+		return rowData
+	}
 	rowData := make([]string, 0, 9)
 
 	name := tr.Name
+	if tr.Baseline == baselineExpired || tr.Baseline == baselineDrifted {
+		name = fmt.Sprintf("%s (baseline %s)", name, tr.Baseline)
+	}
 	targetName := tr.TargetName
-
+	if tr.MixedRequests {
+		targetName = fmt.Sprintf("%s (mixed)", targetName)
+	}
 	if flags.ShowKind && !isChild {
 		name = fmt.Sprintf(
 			"%s/%s",
@@ -86,45 +118,59 @@ func (tr tableRow) toTableData(flags *Flags, isChild bool) []string {
 	if flags.ShowNamespace {
 		rowData = append(rowData, tr.Namespace)
 	}
-	rowData = append(rowData, name, tr.Mode, targetName)
-
+	rowData = append(rowData, name, tr.Mode, targetName, formatAge(tr.RecommendedAt, tr.OutdatedTemplate, time.Now(), flags))
 	if flags.wide {
 		rowData = append(rowData,
-			formatQuantity(tr.Requests.CPU), formatQuantity(tr.Recommendations.CPU),
+			formatRecommenders(tr.Recommenders),
+			formatQOSClasses(tr.QOSClass, tr.RecommendedQOSClass, flags),
 		)
 	}
-	rowData = append(rowData, formatPercentage(tr.CPUDifference, flags))
 
-	if flags.wide {
-		recoStr := tableUnsetCell
-		if tr.Recommendations.Memory != nil {
-			d := inf.Dec{}
-			d.Round(tr.Recommendations.Memory.AsDec(), 0, inf.RoundUp)
-			b := d.UnscaledBig()
-
-			if tr.Requests.Memory != nil {
-				switch tr.Requests.Memory.Format {
-				case resource.DecimalSI:
-					recoStr = humanize.BigBytes(b, 2)
-				case resource.BinarySI:
-					recoStr = humanize.BigIBytes(b, 2)
-				default:
-					recoStr = tr.Recommendations.Memory.String()
-				}
-			} else {
-				recoStr = tr.Recommendations.Memory.String()
-			}
+	for _, name := range flags.resourceNames() {
+		if flags.wide {
+			rowData = append(rowData,
+				formatQuantity(tr.Requests.Get(name)),
+				formatResourceRecommendation(name, tr.Recommendations.Get(name), tr.Requests.Get(name)),
+			)
 		}
-		rowData = append(rowData,
-			formatQuantity(tr.Requests.Memory),
-			recoStr,
-		)
-	}
-	rowData = append(rowData, formatPercentage(tr.MemoryDifference, flags))
+		rowData = append(rowData, formatPercentage(tr.Differences[name], tr.Thresholds, name, flags))
 
+		if flags.ShowDelta {
+			rowData = append(rowData,
+				formatDelta(name, tr.delta(name)),
+				formatDelta(name, tr.waste(name)),
+			)
+		}
+		if flags.suggested() {
+			rowData = append(rowData, formatQuantity(tr.Suggested.Get(name)))
+		}
+	}
+	if flags.ShowRisk {
+		rowData = append(rowData, formatRisk(tr.Risk, flags))
+	}
 	return rowData
 }
 
+// delta returns the absolute difference between the request
+// and the recommendation of the named resource, for a single
+// pod. A positive value means that the request is higher.
+func (tr tableRow) delta(name corev1.ResourceName) *resource.Quantity {
+	req, rec := tr.Requests.Get(name), tr.Recommendations.Get(name)
+	if req == nil || rec == nil || rec.IsZero() {
+		return nil
+	}
+	d := req.DeepCopy()
+	d.Sub(*rec)
+
+	return &d
+}
+
+// waste returns the delta of the named resource
+// multiplied by the number of replicas of the target.
+func (tr tableRow) waste(name corev1.ResourceName) *resource.Quantity {
+	return multiplyQuantity(tr.delta(name), int64(tr.TargetReplicas))
+}
+
 type (
 	table    []*tableRow
 	lessFunc func(r1, r2 *tableRow) int
@@ -146,6 +192,8 @@ func (t table) SortBy(order sortOrder, cols ...string) {
 	for _, c := range cols {
 		if fn, ok := columnLessFunc[c]; ok {
 			mts.less = append(mts.less, fn)
+		} else if fn := resourceColumnLessFunc(c); fn != nil {
+			mts.less = append(mts.less, fn)
 		}
 	}
 	sort.Stable(mts)
@@ -156,6 +204,10 @@ const (
 	hdrName          = "Name"           // the [type].name of the VPA resource
 	hdrMode          = "Mode"           // the mode of the VPA resource
 	hdrTarget        = "Target"         // the [type].name of the target controller
+	hdrAge           = "Age"            // the age of the recommendation
+	hdrRecommender   = "Recommender"    // the recommenders selected by the VPA resource
+	hdrQOS           = "QoS"            // the current and recommended QoS class of the pods
+	hdrRisk          = "Risk"           // the risk of applying the recommendation
 	hdrCPURequest    = "CPU Request"    // the CPU request of the pod
 	hdrCPUTarget     = "CPU Target"     // the CPU recommendation target
 	hdrCPUDifference = "% CPU Diff"     // the % difference between CPU request/recommendation
@@ -166,6 +218,12 @@ const (
 
 // Print writes the table to w.
 func (t table) Print(w io.Writer, flags *Flags) error {
+	return t.printWithTotal(w, flags, nil)
+}
+
+// printWithTotal writes the table to w, followed
+// by the total row, if not nil.
+func (t table) printWithTotal(w io.Writer, flags *Flags, total *tableRow) error {
 	tw := newKubectlTableWriter(w)
 
 	if !flags.NoHeaders {
@@ -173,15 +231,26 @@ func (t table) Print(w io.Writer, flags *Flags) error {
 		if flags.ShowNamespace {
 			headers = append(headers, hdrNamespace)
 		}
-		headers = append(headers, hdrName, hdrMode, hdrTarget)
+		headers = append(headers, hdrName, hdrMode, hdrTarget, hdrAge)
 		if flags.wide {
-			headers = append(headers, hdrCPURequest, hdrCPUTarget)
+			headers = append(headers, hdrRecommender, hdrQOS)
 		}
-		headers = append(headers, hdrCPUDifference)
-		if flags.wide {
-			headers = append(headers, hdrMemRequest, hdrMemTarget)
+		for _, name := range flags.resourceNames() {
+			req, rec, diff := resourceHeaders(name)
+			if flags.wide {
+				headers = append(headers, req, rec)
+			}
+			headers = append(headers, diff)
+			if flags.ShowDelta {
+				headers = append(headers, deltaHeaders(name)...)
+			}
+			if flags.suggested() {
+				headers = append(headers, suggestedHeader(name))
+			}
+		}
+		if flags.ShowRisk {
+			headers = append(headers, hdrRisk)
 		}
-		headers = append(headers, hdrMemDifference)
 		tw.SetHeader(headers)
 	}
 	for _, row := range t {
@@ -190,6 +259,11 @@ func (t table) Print(w io.Writer, flags *Flags) error {
 			tw.Append(childRow.toTableData(flags, true))
 		}
 	}
+	if total != nil {
+		// The total row has no kind to display,
+		// like the rows of the containers.
+		tw.Append(total.toTableData(flags, true))
+	}
 	tw.Render()
 
 	if flags.ShowStats {
@@ -197,71 +271,49 @@ func (t table) Print(w io.Writer, flags *Flags) error {
 		if err != nil {
 			return err
 		}
-		return t.printStats(w)
+		return printStats(w, newStatsReport(t.withoutBaselined(), flags), flags)
 	}
 	return nil
 }
 
-type tableStatFn func(column func(i int) *resource.Quantity) *resource.Quantity
-
-func (t table) printStats(w io.Writer) error {
-	tw := newKubectlTableWriter(w)
+// resourceHeaders returns the headers of the request,
+// recommendation and difference columns of a resource.
+func resourceHeaders(name corev1.ResourceName) (string, string, string) {
+	switch name {
+	case corev1.ResourceCPU:
+		return hdrCPURequest, hdrCPUTarget, hdrCPUDifference
+	case corev1.ResourceMemory:
+		return hdrMemRequest, hdrMemTarget, hdrMemDifference
+	default:
+		return fmt.Sprintf("%s Request", name), fmt.Sprintf("%s Target", name), fmt.Sprintf("%% %s Diff", name)
+	}
+}
 
-	statFuncs := []tableStatFn{
-		t.sumQuantities,
-		t.meanQuantities,
-		t.medianQuantities,
-	}
-	rows := []struct {
-		name           string
-		quantityGetter func(i int) *resource.Quantity
-		asBytes        bool
-	}{
-		{"CPU Requests (# cores)", func(i int) *resource.Quantity { return t[i].Requests.CPU }, false},
-		{"CPU Recommendations (# cores)", func(i int) *resource.Quantity { return t[i].Recommendations.CPU }, false},
-		{"MEM Requests (IEC/SI)", func(i int) *resource.Quantity { return t[i].Requests.Memory }, true},
-		{"MEM Recommendations (IEC/SI)", func(i int) *resource.Quantity { return t[i].Recommendations.Memory }, true},
-	}
-	for _, row := range rows {
-		values := make([]string, 0, len(statFuncs))
-
-		for _, fn := range statFuncs {
-			scaledQuantity := func(i int) *resource.Quantity {
-				q := row.quantityGetter(i)
-
-				// Scale the quantity according to the number
-				// of replicas declared in the controller's spec.
-				var replicas int64
-				n, err := t[i].Target.ReplicasCount()
-				if err != nil {
-					replicas = 1
-				} else {
-					replicas = n
-				}
-				return multiplyQuantity(q, replicas)
-			}
-			q := fn(scaledQuantity)
-
-			s := tableUnsetCell
-			if q != nil {
-				if row.asBytes {
-					d := inf.Dec{}
-					d.Round(q.AsDec(), 0, inf.RoundUp)
-					b := d.UnscaledBig()
-					s = humanize.BigIBytes(b, 2) + "/" + humanize.BigBytes(b, 2)
-					s = strings.ReplaceAll(s, " ", "")
-				} else {
-					s = q.AsDec().Round(q.AsDec(), 2, inf.RoundCeil).String()
-				}
-			}
-			values = append(values, s)
-		}
-		tw.Append(append([]string{row.name}, values...))
+// deltaHeaders returns the headers of the absolute
+// difference columns of a resource, per pod and for
+// all the replicas.
+func deltaHeaders(name corev1.ResourceName) []string {
+	label := string(name)
+	switch name {
+	case corev1.ResourceCPU:
+		label = "CPU"
+	case corev1.ResourceMemory:
+		label = "Memory"
 	}
-	tw.SetHeader([]string{"Description", "Total", "Mean", "Median"})
-	tw.Render()
+	return []string{label + " Delta", label + " Waste"}
+}
 
-	return nil
+// suggestedHeader returns the header of the
+// suggested recommendation column of a resource.
+func suggestedHeader(name corev1.ResourceName) string {
+	switch name {
+	case corev1.ResourceCPU:
+		return "CPU Suggested"
+	case corev1.ResourceMemory:
+		return "Memory Suggested"
+	default:
+		return fmt.Sprintf("%s Suggested", name)
+	}
 }
 
 func multiplyQuantity(q *resource.Quantity, n int64) *resource.Quantity {
@@ -281,63 +333,6 @@ func multiplyQuantity(q *resource.Quantity, n int64) *resource.Quantity {
 	return &ret
 }
 
-func (t table) sumQuantities(column func(i int) *resource.Quantity) *resource.Quantity {
-	var sum resource.Quantity
-	for i := range t {
-		v := column(i)
-		if v != nil {
-			sum.Add(*v)
-		}
-	}
-	return &sum
-}
-
-func (t table) meanQuantities(column func(i int) *resource.Quantity) *resource.Quantity {
-	sum := t.sumQuantities(column)
-	dec := sum.AsDec()
-	tmp := inf.Dec{}
-	tmp.QuoRound(dec, inf.NewDec(int64(len(t)), 0), dec.Scale(), inf.RoundDown)
-
-	return resource.NewDecimalQuantity(tmp, resource.DecimalSI)
-}
-
-func (t table) medianQuantities(column func(i int) *resource.Quantity) *resource.Quantity {
-	var values []*resource.Quantity
-
-	// Collect all values and sort them.
-	for i := range t {
-		v := column(i)
-		if v != nil {
-			values = append(values, v)
-		}
-	}
-	sort.Slice(values, func(i, j int) bool {
-		b := compareQuantities(values[i], values[j])
-		switch b {
-		case -1:
-			return true
-		default:
-			return false
-		}
-	})
-	// No math is needed if there are no numbers.
-	// For even numbers we add the two middle values
-	// and divide by two.
-	// For odd numbers we just use the middle value.
-	l := len(values)
-	if l == 0 {
-		return nil
-	} else if l%2 == 0 {
-		q := values[l/2-1]
-		q.Add(*(values[l/2]))
-		tmp := inf.Dec{}
-		tmp.QuoRound(q.AsDec(), inf.NewDec(2, 0), 2, inf.RoundUp)
-
-		return resource.NewDecimalQuantity(tmp, resource.DecimalSI)
-	}
-	return values[l/2]
-}
-
 // newKubectlTableWriter returns a new table writer that writes
 // to w and print according to the Kubectl output format.
 func newKubectlTableWriter(w io.Writer) *tablewriter.Table {
@@ -358,6 +353,13 @@ func newKubectlTableWriter(w io.Writer) *tablewriter.Table {
 	return tw
 }
 
+// Values returned by a lessFunc to place r1 before or after r2
+// regardless of the sort order, such as for missing values.
+const (
+	placeFirst = -2
+	placeLast  = 2
+)
+
 // multiTableSorter implements the sort.Sort interface
 // for a table. It can sort using different sets of
 // multiple fields in the comparison.
@@ -377,6 +379,10 @@ func (mts multiTableSorter) Less(i, j int) bool {
 			return withOrder(true, mts.order)
 		case 1: // r2 placed before
 			return withOrder(false, mts.order)
+		case placeFirst:
+			return true
+		case placeLast:
+			return false
 		default:
 			continue
 		}
@@ -390,17 +396,38 @@ var columnLessFunc = map[string]lessFunc{
 	"name":      func(r1, r2 *tableRow) int { return strings.Compare(r1.Name, r2.Name) },
 	"namespace": func(r1, r2 *tableRow) int { return strings.Compare(r1.Namespace, r2.Namespace) },
 	"target":    func(r1, r2 *tableRow) int { return strings.Compare(r1.TargetName, r2.TargetName) },
-	"cpu-diff":  func(r1, r2 *tableRow) int { return compareFloat64(r1.CPUDifference, r2.CPUDifference) },
-	"mem-diff":  func(r1, r2 *tableRow) int { return compareFloat64(r1.MemoryDifference, r2.MemoryDifference) },
-	"cpu-req":   func(r1, r2 *tableRow) int { return compareQuantities(r1.Requests.CPU, r2.Requests.CPU) },
-	"mem-req":   func(r1, r2 *tableRow) int { return compareQuantities(r1.Requests.Memory, r2.Requests.Memory) },
-	"cpu-rec":   func(r1, r2 *tableRow) int { return compareQuantities(r1.Recommendations.CPU, r2.Recommendations.CPU) },
+	"age":       func(r1, r2 *tableRow) int { return compareAgesMissingLast(r1.RecommendedAt, r2.RecommendedAt) },
+	"cpu-diff": func(r1, r2 *tableRow) int {
+		return compareFloat64(r1.Differences[corev1.ResourceCPU], r2.Differences[corev1.ResourceCPU])
+	},
+	"mem-diff": func(r1, r2 *tableRow) int {
+		return compareFloat64(r1.Differences[corev1.ResourceMemory], r2.Differences[corev1.ResourceMemory])
+	},
+	"cpu-req": func(r1, r2 *tableRow) int { return compareQuantities(r1.Requests.CPU, r2.Requests.CPU) },
+	"mem-req": func(r1, r2 *tableRow) int { return compareQuantities(r1.Requests.Memory, r2.Requests.Memory) },
+	"cpu-rec": func(r1, r2 *tableRow) int { return compareQuantities(r1.Recommendations.CPU, r2.Recommendations.CPU) },
 	"mem-rec": func(r1, r2 *tableRow) int {
 		return compareQuantities(r1.Recommendations.Memory, r2.Recommendations.Memory)
 	},
+	"cpu-delta": func(r1, r2 *tableRow) int {
+		return compareQuantitiesMissingLast(r1.delta(corev1.ResourceCPU), r2.delta(corev1.ResourceCPU))
+	},
+	"mem-delta": func(r1, r2 *tableRow) int {
+		return compareQuantitiesMissingLast(r1.delta(corev1.ResourceMemory), r2.delta(corev1.ResourceMemory))
+	},
+	"cpu-waste": func(r1, r2 *tableRow) int {
+		return compareQuantitiesMissingLast(r1.waste(corev1.ResourceCPU), r2.waste(corev1.ResourceCPU))
+	},
+	"mem-waste": func(r1, r2 *tableRow) int {
+		return compareQuantitiesMissingLast(r1.waste(corev1.ResourceMemory), r2.waste(corev1.ResourceMemory))
+	},
 }
 
-func formatPercentage(f *float64, flags *Flags) string {
+// formatPercentage formats the difference of the named resource,
+// colored according to the thresholds of the rule for the direction
+// of the difference. If the rule is nil, the thresholds of the flags
+// are used.
+func formatPercentage(f *float64, rule *vpa.ThresholdRule, name corev1.ResourceName, flags *Flags) string {
 	if f == nil {
 		return tableUnsetCell
 	}
@@ -412,7 +439,9 @@ func formatPercentage(f *float64, flags *Flags) string {
 	p := termenv.ColorProfile()
 	s := termenv.String(n)
 
-	warn, crit := flags.WarningThreshold, flags.CriticalThreshold
+	th := thresholdsFor(rule, name, *f, flags)
+	warn, crit := th.Warning, th.Critical
+
 	switch {
 	case *f > -warn && *f < warn:
 		s = s.Foreground(p.Color("#A8CC8C"))
@@ -424,6 +453,64 @@ func formatPercentage(f *float64, flags *Flags) string {
 	return s.Bold().String()
 }
 
+// resourceColumnLessFunc returns the function that compares the
+// rows by the request, recommendation or difference of a resource
+// other than CPU and memory, such as "ephemeral-storage-diff".
+func resourceColumnLessFunc(col string) lessFunc {
+	i := strings.LastIndex(col, "-")
+	if i <= 0 {
+		return nil
+	}
+	name := corev1.ResourceName(col[:i])
+
+	switch col[i+1:] {
+	case "req":
+		return func(r1, r2 *tableRow) int { return compareQuantities(r1.Requests.Get(name), r2.Requests.Get(name)) }
+	case "rec":
+		return func(r1, r2 *tableRow) int {
+			return compareQuantities(r1.Recommendations.Get(name), r2.Recommendations.Get(name))
+		}
+	case "diff":
+		return func(r1, r2 *tableRow) int { return compareFloat64(r1.Differences[name], r2.Differences[name]) }
+	case "delta":
+		return func(r1, r2 *tableRow) int { return compareQuantitiesMissingLast(r1.delta(name), r2.delta(name)) }
+	case "waste":
+		return func(r1, r2 *tableRow) int { return compareQuantitiesMissingLast(r1.waste(name), r2.waste(name)) }
+	}
+	return nil
+}
+
+// formatResourceRecommendation formats the recommendation
+// of a resource. The recommendations of resources measured
+// in bytes use the same format as the request.
+func formatResourceRecommendation(name corev1.ResourceName, rec, req *resource.Quantity) string {
+	if vpa.IsBytesResource(name) {
+		return formatMemoryRecommendation(rec, req)
+	}
+	return formatQuantity(rec)
+}
+
+// formatDelta formats a signed difference of quantities. The
+// differences of resources measured in bytes are humanized.
+func formatDelta(name corev1.ResourceName, q *resource.Quantity) string {
+	if q == nil {
+		return tableUnsetCell
+	}
+	abs := q.DeepCopy()
+	sign := "+"
+	if q.Sign() < 0 {
+		sign = "-"
+		abs.Neg()
+	}
+	if !vpa.IsBytesResource(name) {
+		return sign + abs.String()
+	}
+	d := inf.Dec{}
+	d.Round(abs.AsDec(), 0, inf.RoundUp)
+
+	return sign + humanize.BigIBytes(d.UnscaledBig(), 2)
+}
+
 func formatQuantity(q *resource.Quantity) string {
 	if q == nil || q.IsZero() {
 		return tableUnsetCell
@@ -431,6 +518,29 @@ func formatQuantity(q *resource.Quantity) string {
 	return q.String()
 }
 
+// formatMemoryRecommendation formats a memory recommendation
+// using the same format as the request it is compared with.
+func formatMemoryRecommendation(rec, req *resource.Quantity) string {
+	if rec == nil {
+		return tableUnsetCell
+	}
+	if req == nil {
+		return rec.String()
+	}
+	d := inf.Dec{}
+	d.Round(rec.AsDec(), 0, inf.RoundUp)
+	b := d.UnscaledBig()
+
+	switch req.Format {
+	case resource.DecimalSI:
+		return humanize.BigBytes(b, 2)
+	case resource.BinarySI:
+		return humanize.BigIBytes(b, 2)
+	default:
+		return rec.String()
+	}
+}
+
 func compareFloat64(f1, f2 *float64) int {
 	switch {
 	case f1 == nil && f2 == nil:
@@ -459,6 +569,20 @@ func compareQuantities(q1, q2 *resource.Quantity) int {
 	return q1.Cmp(*q2)
 }
 
+// compareQuantitiesMissingLast is like compareQuantities, but
+// the nil quantities are placed last in both sort orders.
+func compareQuantitiesMissingLast(q1, q2 *resource.Quantity) int {
+	switch {
+	case q1 == nil && q2 == nil:
+		return 0
+	case q1 == nil:
+		return placeLast
+	case q2 == nil:
+		return placeFirst
+	}
+	return q1.Cmp(*q2)
+}
+
 func withOrder(b bool, order sortOrder) bool {
 	switch order {
 	case orderAsc:
@@ -6,64 +6,62 @@ import (
 	"testing"
 	"text/tabwriter"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/utils/pointer"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
 )
 
 func TestSortTable(t *testing.T) {
+	diffs := func(cpu, mem *float64) map[corev1.ResourceName]*float64 {
+		return map[corev1.ResourceName]*float64{corev1.ResourceCPU: cpu, corev1.ResourceMemory: mem}
+	}
 	table := table{
 		{
-			Name:             "zeus",
-			Namespace:        "athens",
-			CPUDifference:    pointer.Float64(3.14),
-			MemoryDifference: pointer.Float64(12.34),
+			Name:        "zeus",
+			Namespace:   "athens",
+			Differences: diffs(pointer.Float64(3.14), pointer.Float64(12.34)),
 		},
 		{
-			Name:             "hera",
-			Namespace:        "sparta",
-			CPUDifference:    pointer.Float64(1.43),
-			MemoryDifference: pointer.Float64(-6.72),
+			Name:        "hera",
+			Namespace:   "sparta",
+			Differences: diffs(pointer.Float64(1.43), pointer.Float64(-6.72)),
 		},
 		{
-			Name:             "poseidon",
-			Namespace:        "corinth",
-			CPUDifference:    pointer.Float64(-2.50),
-			MemoryDifference: pointer.Float64(8.9),
+			Name:        "poseidon",
+			Namespace:   "corinth",
+			Differences: diffs(pointer.Float64(-2.50), pointer.Float64(8.9)),
 		},
 		{
-			Name:             "demeter",
-			Namespace:        "pergamon",
-			CPUDifference:    nil,
-			MemoryDifference: pointer.Float64(0.45),
+			Name:        "demeter",
+			Namespace:   "pergamon",
+			Differences: diffs(nil, pointer.Float64(0.45)),
 		},
 		{
-			Name:             "athena",
-			Namespace:        "athens",
-			CPUDifference:    nil,
-			MemoryDifference: nil,
+			Name:        "athena",
+			Namespace:   "athens",
+			Differences: diffs(nil, nil),
 		},
 		{
-			Name:             "apollo",
-			Namespace:        "olympia",
-			CPUDifference:    pointer.Float64(10.21),
-			MemoryDifference: pointer.Float64(13.37),
+			Name:        "apollo",
+			Namespace:   "olympia",
+			Differences: diffs(pointer.Float64(10.21), pointer.Float64(13.37)),
 		},
 		{
-			Name:             "artemis",
-			Namespace:        "thebes",
-			CPUDifference:    pointer.Float64(-0.01),
-			MemoryDifference: pointer.Float64(7.43),
+			Name:        "artemis",
+			Namespace:   "thebes",
+			Differences: diffs(pointer.Float64(-0.01), pointer.Float64(7.43)),
 		},
 		{
-			Name:             "hermes",
-			Namespace:        "sparta",
-			CPUDifference:    pointer.Float64(-100.43),
-			MemoryDifference: nil,
+			Name:        "hermes",
+			Namespace:   "sparta",
+			Differences: diffs(pointer.Float64(-100.43), nil),
 		},
 		{
-			Name:             "dionysus",
-			Namespace:        "corinth",
-			CPUDifference:    pointer.Float64(-250.00),
-			MemoryDifference: pointer.Float64(666.66),
+			Name:        "dionysus",
+			Namespace:   "corinth",
+			Differences: diffs(pointer.Float64(-250.00), pointer.Float64(666.66)),
 		},
 	}
 	table.SortBy(orderAsc, "namespace", "name")
@@ -118,7 +116,7 @@ func TestSortTable(t *testing.T) {
 	w := tabwriter.NewWriter(&buf, 1, 0, 2, ' ', 0)
 
 	for _, row := range table {
-		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.Namespace, row.Name, fts(row.CPUDifference), fts(row.MemoryDifference))
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.Namespace, row.Name, fts(row.Differences[corev1.ResourceCPU]), fts(row.Differences[corev1.ResourceMemory]))
 		if err != nil {
 			t.Error(err)
 		}
@@ -135,3 +133,91 @@ func fts(f *float64) string {
 	}
 	return fmt.Sprintf("%+.2f", *f)
 }
+
+func TestSortTableByResource(t *testing.T) {
+	newRow := func(name, storage string) *tableRow {
+		row := &tableRow{Name: name}
+		row.Requests.Set(corev1.ResourceEphemeralStorage, resource.NewQuantity(0, resource.BinarySI))
+		if storage != "" {
+			q := resource.MustParse(storage)
+			row.Requests.Set(corev1.ResourceEphemeralStorage, &q)
+		}
+		return row
+	}
+	table := table{newRow("a", "2Gi"), newRow("b", "512Mi"), newRow("c", "1Gi")}
+	table.SortBy(orderDesc, "ephemeral-storage-req")
+
+	for i, want := range []string{"a", "c", "b"} {
+		if table[i].Name != want {
+			t.Errorf("row %d: got %s, want %s", i, table[i].Name, want)
+		}
+	}
+	if fn := resourceColumnLessFunc("ephemeral-storage-foo"); fn != nil {
+		t.Error("expected nil less func for unknown column suffix")
+	}
+}
+
+func TestSortTableByWaste(t *testing.T) {
+	newRow := func(name, req, rec string, replicas int32) *tableRow {
+		rq, rc := resource.MustParse(req), resource.MustParse(rec)
+		return &tableRow{
+			Name:            name,
+			TargetReplicas:  replicas,
+			Requests:        vpa.ResourceQuantities{CPU: &rq},
+			Recommendations: vpa.ResourceQuantities{CPU: &rc},
+		}
+	}
+	table := table{
+		newRow("small", "40m", "10m", 3),                   // +30m, +90m
+		newRow("large", "32", "26", 2),                     // +6, +12
+		newRow("many", "500m", "250m", 100),                // +250m, +25
+		newRow("under", "100m", "200m", 10),                // -100m, -1
+		{Name: "none", Requests: vpa.ResourceQuantities{}}, // no recommendation
+	}
+	table.SortBy(orderAsc, "cpu-waste")
+
+	for i, want := range []string{"under", "small", "large", "many", "none"} {
+		if table[i].Name != want {
+			t.Errorf("row %d: got %s, want %s", i, table[i].Name, want)
+		}
+	}
+	table.SortBy(orderAsc, "cpu-delta")
+
+	for i, want := range []string{"under", "small", "many", "large", "none"} {
+		if table[i].Name != want {
+			t.Errorf("row %d: got %s, want %s", i, table[i].Name, want)
+		}
+	}
+	for _, tt := range []struct {
+		name corev1.ResourceName
+		q    string
+		want string
+	}{
+		{corev1.ResourceCPU, "250m", "+250m"},
+		{corev1.ResourceCPU, "-1", "-1"},
+		{corev1.ResourceMemory, "-64Mi", "-64 Mi"},
+	} {
+		q := resource.MustParse(tt.q)
+		if got := formatDelta(tt.name, &q); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestSortTableByWasteDesc(t *testing.T) {
+	rq, rc := resource.MustParse("2"), resource.MustParse("1")
+	table := table{
+		{Name: "none"},
+		{
+			Name:            "some",
+			TargetReplicas:  1,
+			Requests:        vpa.ResourceQuantities{CPU: &rq},
+			Recommendations: vpa.ResourceQuantities{CPU: &rc},
+		},
+	}
+	table.SortBy(orderDesc, "cpu-waste")
+
+	if table[0].Name != "some" {
+		t.Errorf("expected rows without waste to be sorted last")
+	}
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+// thresholdRules represents the file of the threshold rules
+// applied to the differences of the VPA resources.
+type thresholdRules struct {
+	Default *vpa.ThresholdRule  `json:"default,omitempty"`
+	Rules   []vpa.ThresholdRule `json:"rules,omitempty"`
+}
+
+// newThresholder returns the thresholder configured by the flags.
+// The thresholds of the default rule that the file doesn't set are
+// the warning and critical thresholds of the flags.
+func newThresholder(flags *Flags) (*vpa.Thresholder, error) {
+	t := vpa.NewThresholder(flags.WarningThreshold, flags.CriticalThreshold)
+
+	if flags.ThresholdRules != "" {
+		b, err := os.ReadFile(flags.ThresholdRules)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read threshold rules: %w", err)
+		}
+		tr := &thresholdRules{}
+		if err := yaml.UnmarshalStrict(b, tr); err != nil {
+			return nil, fmt.Errorf("couldn't decode threshold rules %s: %w", flags.ThresholdRules, err)
+		}
+		if d := tr.Default; d != nil {
+			if d.Over != nil {
+				t.Default.Over = d.Over
+			}
+			if d.Under != nil {
+				t.Default.Under = d.Under
+			}
+			t.Default.Resources = d.Resources
+		}
+		t.Rules = tr.Rules
+	}
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid threshold rules: %w", err)
+	}
+	return t, nil
+}
+
+// thresholdsFor returns the thresholds of the named resource for
+// the direction of the difference. If the rule is nil, the default
+// rule is used, such as for the aggregated differences.
+func thresholdsFor(rule *vpa.ThresholdRule, name corev1.ResourceName, diff float64, flags *Flags) vpa.Thresholds {
+	if rule == nil {
+		if flags.thresholder == nil {
+			return vpa.Thresholds{Warning: flags.WarningThreshold, Critical: flags.CriticalThreshold}
+		}
+		rule = &flags.thresholder.Default
+	}
+	return rule.ForDifference(name, diff)
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func TestNewThresholder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thresholds.yaml")
+	err := os.WriteFile(path, []byte(`
+default:
+  resources:
+    memory:
+      under:
+        warning: 5
+        critical: 10
+rules:
+  - namespaces: ["batch-*"]
+    kinds: [CronJob]
+    over:
+      warning: 50
+      critical: 200
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags := DefaultFlags()
+	flags.ThresholdRules = path
+
+	th, err := newThresholder(flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		ns, kind string
+		name     corev1.ResourceName
+		diff     float64
+		want     vpa.Thresholds
+	}{
+		// The flags set the thresholds of the default
+		// rule that the file leaves unset.
+		{"payments", "Deployment", corev1.ResourceCPU, 30, vpa.Thresholds{Warning: flags.WarningThreshold, Critical: flags.CriticalThreshold}},
+		{"payments", "Deployment", corev1.ResourceMemory, -30, vpa.Thresholds{Warning: 5, Critical: 10}},
+		{"batch-etl", "CronJob", corev1.ResourceCPU, 30, vpa.Thresholds{Warning: 50, Critical: 200}},
+		{"batch-etl", "CronJob", corev1.ResourceMemory, -30, vpa.Thresholds{Warning: 5, Critical: 10}},
+		{"batch-etl", "Deployment", corev1.ResourceCPU, 30, vpa.Thresholds{Warning: flags.WarningThreshold, Critical: flags.CriticalThreshold}},
+	} {
+		rule := th.RuleFor(tt.ns, nil, tt.kind)
+		if got := thresholdsFor(rule, tt.name, tt.diff, flags); got != tt.want {
+			t.Errorf("%s/%s %s %+v: got %+v, want %+v", tt.ns, tt.kind, tt.name, tt.diff, got, tt.want)
+		}
+	}
+	if err := os.WriteFile(path, []byte("rules:\n  - over:\n      warning: 50\n      critical: 20\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newThresholder(flags); err == nil {
+		t.Error("expected error for a warning threshold above the critical threshold")
+	}
+}
+
+func TestThresholdsForWithoutRule(t *testing.T) {
+	flags := DefaultFlags()
+	flags.WarningThreshold, flags.CriticalThreshold = 15, 40
+
+	want := vpa.Thresholds{Warning: 15, Critical: 40}
+	if got := thresholdsFor(nil, corev1.ResourceCPU, 10, flags); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
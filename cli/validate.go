@@ -0,0 +1,461 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+const (
+	violationBelowMin      = "below-min"
+	violationAboveMax      = "above-max"
+	violationLimitAboveMax = "limit-above-max"
+	violationAboveMaxRatio = "above-max-ratio"
+)
+
+// validationReport represents the violations of the LimitRange
+// and ResourceQuota objects of the namespaces that applying the
+// recommendations would cause.
+type validationReport struct {
+	LimitRanges []limitRangeViolation `json:"limitRanges"`
+	Quotas      []quotaUsage          `json:"quotas"`
+}
+
+// limitRangeViolation represents a recommendation, or the limit
+// scaled along with it, outside the bounds of a LimitRange. The
+// container is empty for the limits of type Pod.
+type limitRangeViolation struct {
+	Namespace            string              `json:"namespace"`
+	VPA                  string              `json:"vpa"`
+	Container            string              `json:"container,omitempty"`
+	LimitRange           string              `json:"limitRange"`
+	Type                 corev1.LimitType    `json:"type"`
+	Resource             corev1.ResourceName `json:"resource"`
+	Recommendation       *resource.Quantity  `json:"recommendation"`
+	Limit                *resource.Quantity  `json:"limit,omitempty"`
+	Min                  *resource.Quantity  `json:"min,omitempty"`
+	Max                  *resource.Quantity  `json:"max,omitempty"`
+	MaxLimitRequestRatio *resource.Quantity  `json:"maxLimitRequestRatio,omitempty"`
+	Violation            string              `json:"violation"`
+}
+
+// quotaUsage represents the usage of a resource of a quota once
+// the recommendations are applied. The current and recommended
+// quantities are the requests of the targets of the VPAs of the
+// namespace, multiplied by their replicas.
+type quotaUsage struct {
+	Namespace   string              `json:"namespace"`
+	Quota       string              `json:"quota"`
+	Resource    corev1.ResourceName `json:"resource"`
+	Hard        *resource.Quantity  `json:"hard"`
+	Used        *resource.Quantity  `json:"used"`
+	Current     *resource.Quantity  `json:"current"`
+	Recommended *resource.Quantity  `json:"recommended"`
+	Projected   *resource.Quantity  `json:"projected"`
+	Headroom    *resource.Quantity  `json:"headroom"`
+	Exceeded    bool                `json:"exceeded"`
+}
+
+// checkLimitRanges returns the recommendations of the VPA that
+// are outside the bounds of the limits of type Container, and
+// the recommendations of the pod, as computed for the target,
+// outside the bounds of the limits of type Pod. The limits of
+// the containers and of the pod, scaled proportionally to the
+// recommendations, are checked against the max and the max
+// limit/request ratio.
+func checkLimitRanges(
+	v *vpav1.VerticalPodAutoscaler,
+	pod vpa.ResourceQuantities,
+	containerLimits map[string]corev1.ResourceList,
+	podLimits corev1.ResourceList,
+	rt vpa.RecommendationType,
+	limitRanges []*corev1.LimitRange,
+) []limitRangeViolation {
+	var ret []limitRangeViolation
+
+	for _, lr := range limitRanges {
+		for _, item := range lr.Spec.Limits {
+			base := limitRangeViolation{
+				Namespace:  v.Namespace,
+				VPA:        v.Name,
+				LimitRange: lr.Name,
+				Type:       item.Type,
+			}
+			switch item.Type {
+			case corev1.LimitTypeContainer:
+				if v.Status.Recommendation == nil {
+					continue
+				}
+				for _, cr := range v.Status.Recommendation.ContainerRecommendations {
+					base.Container = cr.ContainerName
+					ret = append(ret, checkLimitRangeItem(base, item, vpa.ContainerRecommendations(cr, rt), containerLimits[cr.ContainerName])...)
+				}
+			case corev1.LimitTypePod:
+				ret = append(ret, checkLimitRangeItem(base, item, pod, podLimits)...)
+			}
+		}
+	}
+	return ret
+}
+
+func checkLimitRangeItem(
+	base limitRangeViolation,
+	item corev1.LimitRangeItem,
+	rq vpa.ResourceQuantities,
+	limits corev1.ResourceList,
+) []limitRangeViolation {
+	var ret []limitRangeViolation
+
+	for _, name := range rq.Names() {
+		rec := rq.Get(name)
+		if rec == nil || rec.IsZero() {
+			continue
+		}
+		lv := base
+		lv.Resource = name
+		lv.Recommendation = rec
+
+		if q, ok := limits[name]; ok && !q.IsZero() {
+			lv.Limit = &q
+		}
+		if q, ok := item.Min[name]; ok {
+			lv.Min = &q
+			if rec.Cmp(q) < 0 {
+				lv.Violation = violationBelowMin
+			}
+		}
+		if q, ok := item.Max[name]; ok {
+			lv.Max = &q
+			if rec.Cmp(q) > 0 {
+				lv.Violation = violationAboveMax
+			}
+		}
+		if lv.Violation != "" {
+			ret = append(ret, lv)
+		}
+		if lv.Limit == nil {
+			continue
+		}
+		// The limit is at least as high as the recommendation,
+		// and is only reported above the max if it isn't.
+		if lv.Max != nil && lv.Violation != violationAboveMax && lv.Limit.Cmp(*lv.Max) > 0 {
+			llv := lv
+			llv.Violation = violationLimitAboveMax
+			ret = append(ret, llv)
+		}
+		if q, ok := item.MaxLimitRequestRatio[name]; ok && exceedsRatio(*lv.Limit, *rec, q) {
+			llv := lv
+			llv.MaxLimitRequestRatio = &q
+			llv.Violation = violationAboveMaxRatio
+			ret = append(ret, llv)
+		}
+	}
+	return ret
+}
+
+// exceedsRatio returns whether the ratio of
+// the limit to the request exceeds the max.
+func exceedsRatio(limit, request, max resource.Quantity) bool {
+	l := new(big.Int).Mul(big.NewInt(limit.MilliValue()), big.NewInt(1000))
+	r := new(big.Int).Mul(big.NewInt(request.MilliValue()), big.NewInt(max.MilliValue()))
+
+	return l.Cmp(r) > 0
+}
+
+// quotaRequestResource returns the name of the resource
+// whose requests are limited by the named quota resource.
+func quotaRequestResource(name corev1.ResourceName) (corev1.ResourceName, bool) {
+	switch name {
+	case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+		return name, true
+	}
+	if s := string(name); strings.HasPrefix(s, corev1.DefaultResourceRequestsPrefix) {
+		return corev1.ResourceName(strings.TrimPrefix(s, corev1.DefaultResourceRequestsPrefix)), true
+	}
+	return "", false
+}
+
+// checkQuotas returns the usage of the requests resources of the
+// quotas of a namespace once the recommendations of the rows are
+// applied. The rows without a recommendation for a resource keep
+// their current requests. The quotas with scopes are ignored, as
+// they may not apply to all the pods of the targets.
+func checkQuotas(namespace string, t table, quotas []*corev1.ResourceQuota) []quotaUsage {
+	var ret []quotaUsage
+
+	sort.SliceStable(quotas, func(i, j int) bool {
+		return quotas[i].Name < quotas[j].Name
+	})
+	for _, quota := range quotas {
+		if len(quota.Spec.Scopes) != 0 || quota.Spec.ScopeSelector != nil {
+			klog.V(4).Infof("ignoring scoped quota %s/%s", namespace, quota.Name)
+			continue
+		}
+		names := make([]corev1.ResourceName, 0, len(quota.Spec.Hard))
+		for name := range quota.Spec.Hard {
+			names = append(names, name)
+		}
+		vpa.SortResourceNames(names)
+
+		for _, name := range names {
+			res, ok := quotaRequestResource(name)
+			if !ok {
+				continue
+			}
+			var current, recommended vpa.ResourceQuantities
+
+			for _, row := range t {
+				replicas := int64(row.TargetReplicas)
+				req, rec := row.Requests.Get(res), row.Recommendations.Get(res)
+				if rec == nil || rec.IsZero() {
+					rec = req
+				}
+				addQuantity(&current, res, multiplyQuantity(req, replicas))
+				addQuantity(&recommended, res, multiplyQuantity(rec, replicas))
+			}
+			hard := quota.Spec.Hard[name]
+			used := quota.Status.Used[name]
+
+			qu := quotaUsage{
+				Namespace:   namespace,
+				Quota:       quota.Name,
+				Resource:    name,
+				Hard:        &hard,
+				Used:        &used,
+				Current:     zeroIfNil(current.Get(res)),
+				Recommended: zeroIfNil(recommended.Get(res)),
+			}
+			projected := used.DeepCopy()
+			projected.Sub(*qu.Current)
+			projected.Add(*qu.Recommended)
+			qu.Projected = &projected
+
+			headroom := hard.DeepCopy()
+			headroom.Sub(projected)
+			qu.Headroom = &headroom
+			qu.Exceeded = headroom.Sign() < 0
+
+			ret = append(ret, qu)
+		}
+	}
+	return ret
+}
+
+func zeroIfNil(q *resource.Quantity) *resource.Quantity {
+	if q == nil {
+		return &resource.Quantity{}
+	}
+	return q
+}
+
+// validateOptions represents the options of the validate command.
+type validateOptions struct {
+	*CommandOptions
+
+	Output string
+}
+
+// newValidateCmd returns a new command that validates the
+// recommendations against the LimitRange and ResourceQuota
+// objects of their namespace.
+func newValidateCmd(co *CommandOptions) *cobra.Command {
+	vo := &validateOptions{CommandOptions: co}
+
+	cmd := &cobra.Command{
+		Use:   "validate [options]",
+		Short: "Validate the recommendations against the LimitRanges and ResourceQuotas",
+		Long: `Validate the recommendations against the LimitRanges and ResourceQuotas.
+
+Applying a recommendation fails admission when it violates the LimitRange
+of a namespace, or exceeds its ResourceQuota. The recommendations of the
+containers are compared with the min and max of the limits of type
+Container, and the recommendations of the pods with the limits of type
+Pod.
+
+For each quota of the namespaces of the VPA resources, the requests of the
+targets, multiplied by their replicas, are replaced by the recommendations
+in the used quantities of the quota, to show the remaining headroom once
+the recommendations are applied. The quotas with scopes are ignored.`,
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		Run:                   vo.Run,
+	}
+	cmd.Flags().StringVarP(&vo.Output, flagOutput, flagOutputShorthand, vo.Output,
+		"Output format. One of 'json'")
+
+	return cmd
+}
+
+// Run is the method called by cobra to run the command.
+func (vo *validateOptions) Run(c *cobra.Command, args []string) {
+	cmdutil.CheckErr(vo.Complete(c, args))
+	cmdutil.CheckErr(vo.Validate(c, args))
+	cmdutil.CheckErr(vo.Execute())
+}
+
+// Validate ensure that required options to run the
+// command are set and valid.
+func (vo *validateOptions) Validate(c *cobra.Command, args []string) error {
+	switch vo.Output {
+	case "", jsonOutput:
+	default:
+		return fmt.Errorf("unsupported output format: %s", vo.Output)
+	}
+	return vo.CommandOptions.Validate(c, args)
+}
+
+// Execute runs the command.
+func (vo *validateOptions) Execute() error {
+	ctx := context.Background()
+
+	vpas, err := vo.listVPAs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(vpas) == 0 {
+		vo.printNoResources()
+		return nil
+	}
+	r := validationReport{
+		LimitRanges: make([]limitRangeViolation, 0),
+		Quotas:      make([]quotaUsage, 0),
+	}
+	// Group the rows by namespace, to fetch the
+	// objects of each namespace only once.
+	var namespaces []string
+	rows := make(map[string]table)
+	vpaByRow := make(map[*tableRow]*vpav1.VerticalPodAutoscaler)
+
+	for _, v := range vpas {
+		t := vo.bindRecommendationsAndRequests([]*vpav1.VerticalPodAutoscaler{v})
+		if len(t) == 0 {
+			continue
+		}
+		if _, ok := rows[v.Namespace]; !ok {
+			namespaces = append(namespaces, v.Namespace)
+		}
+		rows[v.Namespace] = append(rows[v.Namespace], t[0])
+		vpaByRow[t[0]] = v
+	}
+	sort.Strings(namespaces)
+
+	for _, ns := range namespaces {
+		lrs, err := vo.Client.ListLimitRanges(ctx, ns)
+		if err != nil {
+			return fmt.Errorf("couldn't list limit ranges of namespace %s: %w", ns, err)
+		}
+		quotas, err := vo.Client.ListResourceQuotas(ctx, ns)
+		if err != nil {
+			return fmt.Errorf("couldn't list resource quotas of namespace %s: %w", ns, err)
+		}
+		for _, row := range rows[ns] {
+			var (
+				v         = vpaByRow[row]
+				cl        map[string]corev1.ResourceList
+				podLimits corev1.ResourceList
+			)
+			if row.Target != nil {
+				cl, podLimits = row.Target.RecommendedLimits(v, vo.Flags.RecommendationType)
+			}
+			r.LimitRanges = append(r.LimitRanges, checkLimitRanges(v, row.Recommendations, cl, podLimits, vo.Flags.RecommendationType, lrs)...)
+		}
+		r.Quotas = append(r.Quotas, checkQuotas(ns, rows[ns], quotas)...)
+	}
+	if vo.Output == jsonOutput {
+		enc := json.NewEncoder(vo.Out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return printValidationReport(vo.Out, r, vo.Flags)
+}
+
+func printValidationReport(w io.Writer, r validationReport, flags *Flags) error {
+	if len(r.LimitRanges) == 0 {
+		fmt.Fprintln(w, "No LimitRange violations found.")
+	} else {
+		tw := newKubectlTableWriter(w)
+		if !flags.NoHeaders {
+			tw.SetHeader([]string{hdrNamespace, hdrName, "Container", "LimitRange", "Resource", "Recommendation", "Limit", "Min", "Max", "Max Ratio", "Violation"})
+		}
+		for _, lv := range r.LimitRanges {
+			container := lv.Container
+			if container == "" {
+				container = tableUnsetCell
+			}
+			tw.Append([]string{
+				lv.Namespace,
+				lv.VPA,
+				container,
+				fmt.Sprintf("%s (%s)", lv.LimitRange, lv.Type),
+				string(lv.Resource),
+				formatQuantity(lv.Recommendation),
+				formatQuantity(lv.Limit),
+				formatQuantity(lv.Min),
+				formatQuantity(lv.Max),
+				formatQuantity(lv.MaxLimitRequestRatio),
+				formatValidationStatus(lv.Violation, true, flags),
+			})
+		}
+		tw.Render()
+	}
+	fmt.Fprintln(w)
+
+	if len(r.Quotas) == 0 {
+		fmt.Fprintln(w, "No ResourceQuotas found.")
+		return nil
+	}
+	tw := newKubectlTableWriter(w)
+	if !flags.NoHeaders {
+		tw.SetHeader([]string{hdrNamespace, "Quota", "Resource", "Hard", "Used", "Current", "Recommended", "Projected", "Headroom", "Status"})
+	}
+	for _, qu := range r.Quotas {
+		status := "ok"
+		if qu.Exceeded {
+			status = "exceeded"
+		}
+		tw.Append([]string{
+			qu.Namespace,
+			qu.Quota,
+			string(qu.Resource),
+			qu.Hard.String(),
+			qu.Used.String(),
+			qu.Current.String(),
+			qu.Recommended.String(),
+			qu.Projected.String(),
+			qu.Headroom.String(),
+			formatValidationStatus(status, qu.Exceeded, flags),
+		})
+	}
+	tw.Render()
+
+	return nil
+}
+
+func formatValidationStatus(status string, failed bool, flags *Flags) string {
+	if termenv.EnvNoColor() || flags.NoColors {
+		return status
+	}
+	p := termenv.ColorProfile()
+	s := termenv.String(status)
+
+	if failed {
+		s = s.Foreground(p.Color("#E88388"))
+	} else {
+		s = s.Foreground(p.Color("#A8CC8C"))
+	}
+	return s.String()
+}
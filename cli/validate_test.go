@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+func TestCheckLimitRanges(t *testing.T) {
+	v := &vpav1.VerticalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Status: vpav1.VerticalPodAutoscalerStatus{
+			Recommendation: &vpav1.RecommendedPodResources{
+				ContainerRecommendations: []vpav1.RecommendedContainerResources{
+					{
+						ContainerName: "app",
+						Target: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("50m"),
+							corev1.ResourceMemory: resource.MustParse("4Gi"),
+						},
+					},
+					{
+						ContainerName: "proxy",
+						Target: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("200m"),
+							corev1.ResourceMemory: resource.MustParse("128Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+	lr := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits"},
+		Spec: corev1.LimitRangeSpec{
+			Limits: []corev1.LimitRangeItem{
+				{
+					Type:                 corev1.LimitTypeContainer,
+					Min:                  corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+					Max:                  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+					MaxLimitRequestRatio: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+				},
+				{
+					Type: corev1.LimitTypePod,
+					Max:  corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+				},
+			},
+		},
+	}
+	pod := vpa.ResourceQuantities{CPU: resource.NewMilliQuantity(250, resource.DecimalSI)}
+
+	// The limits, as scaled along with the recommendations.
+	limits := map[string]corev1.ResourceList{
+		"app": {
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("8Gi"),
+		},
+		"proxy": {
+			corev1.ResourceCPU:    resource.MustParse("1"),
+			corev1.ResourceMemory: resource.MustParse("3Gi"),
+		},
+	}
+	podLimits := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1100m")}
+
+	got := checkLimitRanges(v, pod, limits, podLimits, vpa.RecommendationTarget, []*corev1.LimitRange{lr})
+
+	want := []struct {
+		container string
+		resource  corev1.ResourceName
+		violation string
+	}{
+		{"app", corev1.ResourceCPU, violationBelowMin},
+		{"app", corev1.ResourceMemory, violationAboveMax},
+		{"proxy", corev1.ResourceCPU, violationAboveMaxRatio},
+		{"proxy", corev1.ResourceMemory, violationLimitAboveMax},
+		{"", corev1.ResourceCPU, violationAboveMax},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d violations, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Container != w.container || got[i].Resource != w.resource || got[i].Violation != w.violation {
+			t.Errorf("violation #%d: got %s/%s/%s, want %s/%s/%s", i,
+				got[i].Container, got[i].Resource, got[i].Violation,
+				w.container, w.resource, w.violation,
+			)
+		}
+	}
+}
+
+func TestCheckQuotas(t *testing.T) {
+	newRow := func(replicas int32, req, rec string) *tableRow {
+		row := &tableRow{TargetReplicas: replicas}
+		q := resource.MustParse(req)
+		row.Requests.CPU = &q
+		if rec != "" {
+			r := resource.MustParse(rec)
+			row.Recommendations.CPU = &r
+		}
+		return row
+	}
+	tbl := table{
+		newRow(3, "500m", "1"), // 1500m -> 3
+		newRow(2, "250m", ""),  // 500m -> 500m
+		newRow(1, "1", "500m"), // 1 -> 500m
+	}
+	quotas := []*corev1.ResourceQuota{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "scoped"},
+			Spec: corev1.ResourceQuotaSpec{
+				Hard:   corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1")},
+				Scopes: []corev1.ResourceQuotaScope{corev1.ResourceQuotaScopeBestEffort},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "compute"},
+			Spec: corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{
+					corev1.ResourceRequestsCPU: resource.MustParse("4"),
+					corev1.ResourceLimitsCPU:   resource.MustParse("8"),
+					corev1.ResourcePods:        resource.MustParse("20"),
+				},
+			},
+			Status: corev1.ResourceQuotaStatus{
+				Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("3500m")},
+			},
+		},
+	}
+	got := checkQuotas("default", tbl, quotas)
+	if len(got) != 1 {
+		t.Fatalf("got %d quota usages, want 1: %+v", len(got), got)
+	}
+	qu := got[0]
+	if qu.Quota != "compute" || qu.Resource != corev1.ResourceRequestsCPU {
+		t.Errorf("unexpected quota usage: %s/%s", qu.Quota, qu.Resource)
+	}
+	for _, tt := range []struct {
+		name string
+		got  *resource.Quantity
+		want string
+	}{
+		{"current", qu.Current, "3"},
+		{"recommended", qu.Recommended, "4"},
+		{"projected", qu.Projected, "4500m"},
+		{"headroom", qu.Headroom, "-500m"},
+	} {
+		if tt.got.Cmp(resource.MustParse(tt.want)) != 0 {
+			t.Errorf("%s: got %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+	if !qu.Exceeded {
+		t.Error("expected quota to be exceeded")
+	}
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+// printRequestsVariance writes to w the distribution of the
+// requests of the named resources of the containers of the
+// targets whose pods disagree.
+func printRequestsVariance(w io.Writer, tables []table, names []corev1.ResourceName) error {
+	tw := newKubectlTableWriter(w)
+
+	header := []string{hdrNamespace, hdrTarget, "Container", "Pods", "Distinct"}
+	for _, name := range names {
+		header = append(header, varianceHeader(name))
+	}
+	tw.SetHeader(append(header, "Source"))
+
+	var n int
+	for _, t := range tables {
+		for _, row := range t {
+			if !row.MixedRequests {
+				continue
+			}
+			source, pod := row.Target.RequestsSource()
+			if pod != "" {
+				source = vpa.RequestsSource(fmt.Sprintf("%s (%s)", source, pod))
+			}
+			for _, rv := range row.Target.RequestsVariance() {
+				data := []string{
+					row.Namespace,
+					row.TargetName,
+					rv.Container,
+					fmt.Sprintf("%d/%d", rv.Pods, len(row.Target.Pods())),
+					fmt.Sprintf("%d", rv.Distinct),
+				}
+				for _, name := range names {
+					data = append(data, formatQuantity(quantityOf(rv.Min, name))+"/"+formatQuantity(quantityOf(rv.Max, name)))
+				}
+				tw.Append(append(data, source.String()))
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		fmt.Fprintln(w, "No request variance found across pods.")
+		return nil
+	}
+	tw.Render()
+
+	return nil
+}
+
+// varianceHeader returns the header of the
+// min/max requests column of a resource.
+func varianceHeader(name corev1.ResourceName) string {
+	switch name {
+	case corev1.ResourceCPU:
+		return "CPU Min/Max"
+	case corev1.ResourceMemory:
+		return "Memory Min/Max"
+	default:
+		return fmt.Sprintf("%s Min/Max", name)
+	}
+}
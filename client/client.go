@@ -22,7 +22,11 @@ import (
 	"k8s.io/client-go/tools/pager"
 )
 
-const vpaKind = "VerticalPodAutoscaler"
+const (
+	vpaKind               = "VerticalPodAutoscaler"
+	vpaResource           = "verticalpodautoscalers"
+	vpaCheckpointResource = "verticalpodautoscalercheckpoints"
+)
 
 // ListOptions represents the options for listing resources.
 type ListOptions struct {
@@ -41,9 +45,17 @@ type Interface interface {
 	GetRESTMapper() (meta.RESTMapper, error)
 	IsClusterReachable() error
 	HasGroupVersion(version schema.GroupVersion) (bool, error)
+	VPAGroupVersion() (schema.GroupVersion, error)
 	ListVPAResources(context.Context, ListOptions) ([]*vpav1.VerticalPodAutoscaler, error)
 	GetVPATarget(context.Context, *autoscalingv1.CrossVersionObjectReference, string) (*unstructuredv1.Unstructured, error)
 	ListDependentPods(ctx context.Context, targetMeta metav1.ObjectMeta, labelSelector string) ([]*corev1.Pod, error)
+	ListResources(ctx context.Context, gk schema.GroupKind, namespace, labelSelector string) ([]*unstructuredv1.Unstructured, error)
+	CreateVPAResource(context.Context, *vpav1.VerticalPodAutoscaler) error
+	ListNodes(ctx context.Context, labelSelector string) ([]*corev1.Node, error)
+	ListLimitRanges(ctx context.Context, namespace string) ([]*corev1.LimitRange, error)
+	ListResourceQuotas(ctx context.Context, namespace string) ([]*corev1.ResourceQuota, error)
+	ListEvents(ctx context.Context, namespace, fieldSelector string) ([]*corev1.Event, error)
+	ListVPACheckpoints(ctx context.Context, namespace string) ([]*vpav1.VerticalPodAutoscalerCheckpoint, error)
 }
 
 var _ Interface = (*client)(nil)
@@ -56,6 +68,7 @@ type client struct {
 	discoveryClient discovery.DiscoveryInterface
 	coreClient      *corev1client.CoreV1Client
 	mapper          meta.RESTMapper
+	vpaVersion      *schema.GroupVersion
 
 	// lock during lazy init of the client
 	sync.Mutex
@@ -106,8 +119,18 @@ func (c *client) HasGroupVersion(gv schema.GroupVersion) (bool, error) {
 }
 
 // ListVPAResources returns the list of VerticalPodAutoscaler
-// resources that match the listing options parameters.
+// resources that match the listing options parameters. The
+// resources are listed with the version negotiated with the
+// cluster, and converted to the v1 types.
 func (c *client) ListVPAResources(_ context.Context, opts ListOptions) ([]*vpav1.VerticalPodAutoscaler, error) {
+	gv, err := c.VPAGroupVersion()
+	if err != nil {
+		return nil, err
+	}
+	// Fully-qualified resource type, such as
+	// verticalpodautoscalers.v1beta2.autoscaling.k8s.io
+	rt := fmt.Sprintf("%s.%s.%s", vpaResource, gv.Version, gv.Group)
+
 	b := resource.NewBuilder(c.flags)
 	r := b.Unstructured().
 		NamespaceParam(opts.Namespace).
@@ -115,7 +138,7 @@ func (c *client) ListVPAResources(_ context.Context, opts ListOptions) ([]*vpav1
 		LabelSelectorParam(opts.LabelSelector).
 		FieldSelectorParam(opts.FieldSelector).
 		RequestChunksOf(opts.Limit).
-		ResourceTypeOrNameArgs(true, append([]string{vpaKind}, opts.ResourceNames...)...).
+		ResourceTypeOrNameArgs(true, append([]string{rt}, opts.ResourceNames...)...).
 		SingleResourceType().
 		RequireObject(true).
 		ContinueOnError().
@@ -130,7 +153,6 @@ func (c *client) ListVPAResources(_ context.Context, opts ListOptions) ([]*vpav1
 	if err != nil || infos == nil {
 		return nil, err
 	}
-	conv := runtime.DefaultUnstructuredConverter
 	vpas := make([]*vpav1.VerticalPodAutoscaler, len(infos))
 
 	for i, v := range infos {
@@ -140,14 +162,40 @@ func (c *client) ListVPAResources(_ context.Context, opts ListOptions) ([]*vpav1
 		if !ok {
 			return nil, fmt.Errorf("unexpected result type: %T", v)
 		}
-		vpas[i] = &vpav1.VerticalPodAutoscaler{}
-		if err := conv.FromUnstructured(u.Object, vpas[i]); err != nil {
+		if vpas[i], err = convertVPA(u.Object); err != nil {
 			return nil, err
 		}
 	}
 	return vpas, nil
 }
 
+// CreateVPAResource creates the given VerticalPodAutoscaler resource,
+// using the version negotiated with the cluster.
+func (c *client) CreateVPAResource(ctx context.Context, vpa *vpav1.VerticalPodAutoscaler) error {
+	gv, err := c.VPAGroupVersion()
+	if err != nil {
+		return err
+	}
+	obj, err := convertVPAToVersion(vpa, gv)
+	if err != nil {
+		return err
+	}
+	ri := c.dynamicClient.Resource(gv.WithResource(vpaResource)).Namespace(vpa.Namespace)
+
+	_, err = ri.Create(ctx, &unstructuredv1.Unstructured{Object: obj}, metav1.CreateOptions{})
+	if err != nil {
+		switch {
+		case apierrors.IsForbidden(err):
+			return fmt.Errorf("no access to create resource %s in namespace %s", vpaResource, vpa.Namespace)
+		case apierrors.IsAlreadyExists(err):
+			return fmt.Errorf("resource already exists: %s/%s", vpa.Namespace, vpa.Name)
+		default:
+			return fmt.Errorf("couldn't create resource %s in namespace %s: %w", vpaResource, vpa.Namespace, err)
+		}
+	}
+	return nil
+}
+
 // GetVPATarget fetches the controller targeted by the given VPA reference
 // and return a generic unstructured object.
 func (c *client) GetVPATarget(ctx context.Context, ref *autoscalingv1.CrossVersionObjectReference, namespace string) (*unstructuredv1.Unstructured, error) {
@@ -181,7 +229,7 @@ func (c *client) GetVPATarget(ctx context.Context, ref *autoscalingv1.CrossVersi
 		case apierrors.IsForbidden(err):
 			return nil, fmt.Errorf("no access to get resource %s in namespace %s", m.Resource.String(), namespace)
 		case apierrors.IsNotFound(err):
-			return nil, fmt.Errorf("resource not found: %s/%s", namespace, ref.Name)
+			return nil, fmt.Errorf("resource not found: %s/%s: %w", namespace, ref.Name, err)
 		default:
 			return nil, fmt.Errorf("couldn't get resource %s in namespace %s: %w", m.Resource.String(), namespace, err)
 		}
@@ -197,17 +245,18 @@ func (c *client) ListDependentPods(ctx context.Context, targetMeta metav1.Object
 	p := pager.New(func(ctx context.Context, o metav1.ListOptions) (runtime.Object, error) {
 		return i.List(ctx, o)
 	})
-	obj, _, err := p.List(ctx, metav1.ListOptions{
-		LabelSelector: labelSelector,
-		Limit:         250,
-	})
-	if err != nil {
-		return nil, err
-	}
-	list := obj.(*corev1.PodList)
 	pods := make([]*corev1.Pod, 0)
 
-	for i, pod := range list.Items {
+	// The pager returns a list of a different type if the
+	// result spans multiple pages, so iterate over the items.
+	err := p.EachListItem(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+		Limit:         250,
+	}, func(obj runtime.Object) error {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok {
+			return fmt.Errorf("unexpected result type: %T", obj)
+		}
 		for _, ref := range pod.GetOwnerReferences() {
 			// TODO(will): This is rather weak.
 			// Instead, we should go through the chain of owner references
@@ -215,13 +264,174 @@ func (c *client) ListDependentPods(ctx context.Context, targetMeta metav1.Object
 			// of the VerticalPodAutoscaler resource.
 			// i.e. Pod -> ReplicaSet -> Deployment
 			if referenceMatchController(ref, targetMeta) {
-				pods = append(pods, &list.Items[i])
+				pods = append(pods, pod)
+				break
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return pods, nil
 }
 
+// ListNodes returns the list of nodes that match the label selector.
+func (c *client) ListNodes(ctx context.Context, labelSelector string) ([]*corev1.Node, error) {
+	i := c.coreClient.Nodes()
+
+	p := pager.New(func(ctx context.Context, o metav1.ListOptions) (runtime.Object, error) {
+		return i.List(ctx, o)
+	})
+	nodes := make([]*corev1.Node, 0)
+
+	err := p.EachListItem(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+		Limit:         250,
+	}, func(obj runtime.Object) error {
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			return fmt.Errorf("unexpected result type: %T", obj)
+		}
+		nodes = append(nodes, node)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// ListLimitRanges returns the list of LimitRange objects of a namespace.
+func (c *client) ListLimitRanges(ctx context.Context, namespace string) ([]*corev1.LimitRange, error) {
+	list, err := c.coreClient.LimitRanges(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*corev1.LimitRange, 0, len(list.Items))
+	for i := range list.Items {
+		ret = append(ret, &list.Items[i])
+	}
+	return ret, nil
+}
+
+// ListResourceQuotas returns the list of ResourceQuota objects of a namespace.
+func (c *client) ListResourceQuotas(ctx context.Context, namespace string) ([]*corev1.ResourceQuota, error) {
+	list, err := c.coreClient.ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*corev1.ResourceQuota, 0, len(list.Items))
+	for i := range list.Items {
+		ret = append(ret, &list.Items[i])
+	}
+	return ret, nil
+}
+
+// ListEvents returns the list of events of a namespace
+// that match the field selector.
+func (c *client) ListEvents(ctx context.Context, namespace, fieldSelector string) ([]*corev1.Event, error) {
+	i := c.coreClient.Events(namespace)
+
+	p := pager.New(func(ctx context.Context, o metav1.ListOptions) (runtime.Object, error) {
+		return i.List(ctx, o)
+	})
+	events := make([]*corev1.Event, 0)
+
+	err := p.EachListItem(ctx, metav1.ListOptions{
+		FieldSelector: fieldSelector,
+		Limit:         250,
+	}, func(obj runtime.Object) error {
+		event, ok := obj.(*corev1.Event)
+		if !ok {
+			return fmt.Errorf("unexpected result type: %T", obj)
+		}
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ListVPACheckpoints returns the list of VerticalPodAutoscalerCheckpoint
+// resources of a namespace, or of all namespaces if the namespace is
+// empty. The resources are listed with the version negotiated with the
+// cluster, whose checkpoint types are the same in all the versions.
+func (c *client) ListVPACheckpoints(ctx context.Context, namespace string) ([]*vpav1.VerticalPodAutoscalerCheckpoint, error) {
+	gv, err := c.VPAGroupVersion()
+	if err != nil {
+		return nil, err
+	}
+	ri := c.dynamicClient.Resource(gv.WithResource(vpaCheckpointResource)).Namespace(namespace)
+
+	p := pager.New(func(ctx context.Context, o metav1.ListOptions) (runtime.Object, error) {
+		return ri.List(ctx, o)
+	})
+	var checkpoints []*vpav1.VerticalPodAutoscalerCheckpoint
+
+	err = p.EachListItem(ctx, metav1.ListOptions{
+		Limit: 250,
+	}, func(obj runtime.Object) error {
+		u, ok := obj.(*unstructuredv1.Unstructured)
+		if !ok {
+			return fmt.Errorf("unexpected result type: %T", obj)
+		}
+		cp, err := decodeCheckpoint(u)
+		if err != nil {
+			return err
+		}
+		checkpoints = append(checkpoints, cp)
+		return nil
+	})
+	if err != nil {
+		if apierrors.IsForbidden(err) {
+			return nil, fmt.Errorf("no access to list resource %s in namespace %s", vpaCheckpointResource, namespace)
+		}
+		return nil, fmt.Errorf("couldn't list resource %s: %w", vpaCheckpointResource, err)
+	}
+	return checkpoints, nil
+}
+
+// ListResources returns the list of resources of the given kind
+// in a namespace, or in all namespaces if the namespace is empty.
+func (c *client) ListResources(ctx context.Context, gk schema.GroupKind, namespace, labelSelector string) ([]*unstructuredv1.Unstructured, error) {
+	m, err := c.mapper.RESTMapping(gk)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't find mapping for %s: %w", gk, err)
+	}
+	var ri dynamic.ResourceInterface
+
+	nri := c.dynamicClient.Resource(m.Resource)
+
+	if namespace != "" && m.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = nri.Namespace(namespace)
+	} else {
+		ri = nri
+	}
+	p := pager.New(func(ctx context.Context, o metav1.ListOptions) (runtime.Object, error) {
+		return ri.List(ctx, o)
+	})
+	var list []*unstructuredv1.Unstructured
+
+	err = p.EachListItem(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+		Limit:         250,
+	}, func(obj runtime.Object) error {
+		u, ok := obj.(*unstructuredv1.Unstructured)
+		if !ok {
+			return fmt.Errorf("unexpected result type: %T", obj)
+		}
+		list = append(list, u)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list resource %s: %w", m.Resource.String(), err)
+	}
+	return list, nil
+}
+
 // referenceMatchController returns whether the given
 // OwnerReference matches a target controller.
 func referenceMatchController(ref metav1.OwnerReference, targetMeta metav1.ObjectMeta) bool {
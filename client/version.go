@@ -0,0 +1,138 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	unstructuredv1 "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	vpav1beta2 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1beta2"
+)
+
+// supportedVPAVersions lists the versions of the VPA
+// API that the client can decode, by order of preference.
+var supportedVPAVersions = []schema.GroupVersion{
+	vpav1.SchemeGroupVersion,
+	vpav1beta2.SchemeGroupVersion,
+}
+
+// VPAGroupVersion returns the best version of the VPA API
+// served by the remote Kubernetes cluster, among the versions
+// supported by the client. The result is cached after the
+// first successful negotiation.
+func (c *client) VPAGroupVersion() (schema.GroupVersion, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.vpaVersion != nil {
+		return *c.vpaVersion, nil
+	}
+	apiGroups, err := c.discoveryClient.ServerGroups()
+	if err != nil {
+		return schema.GroupVersion{}, fmt.Errorf("couldn't get available api groups/versions from server: %w", err)
+	}
+	gv, ok := negotiateVPAVersion(apiGroups)
+	if !ok {
+		return schema.GroupVersion{}, fmt.Errorf("group %s not available in any supported version", vpav1.SchemeGroupVersion.Group)
+	}
+	c.vpaVersion = &gv
+
+	return gv, nil
+}
+
+// negotiateVPAVersion returns the first supported version
+// of the VPA API that is served by one of the groups.
+func negotiateVPAVersion(groups *metav1.APIGroupList) (schema.GroupVersion, bool) {
+	for _, g := range groups.Groups {
+		if g.Name != vpav1.SchemeGroupVersion.Group {
+			continue
+		}
+		for _, gv := range supportedVPAVersions {
+			if hasMatchingGroupVersions(g.Versions, gv.Version) {
+				return gv, true
+			}
+		}
+	}
+	return schema.GroupVersion{}, false
+}
+
+// convertVPA decodes an unstructured VPA object of any supported
+// version into the v1 types. The v1beta2 API is a subset of the v1
+// API, and its objects are decoded into their own types first to
+// drop the fields that are unknown to that version.
+func convertVPA(obj map[string]interface{}) (*vpav1.VerticalPodAutoscaler, error) {
+	conv := runtime.DefaultUnstructuredConverter
+
+	apiVersion, _ := obj["apiVersion"].(string)
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q into GroupVersion: %w", apiVersion, err)
+	}
+	switch gv {
+	case vpav1.SchemeGroupVersion:
+	case vpav1beta2.SchemeGroupVersion:
+		v := &vpav1beta2.VerticalPodAutoscaler{}
+		if err := conv.FromUnstructured(obj, v); err != nil {
+			return nil, err
+		}
+		if obj, err = conv.ToUnstructured(v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported VPA version: %s", apiVersion)
+	}
+	v := &vpav1.VerticalPodAutoscaler{}
+	if err := conv.FromUnstructured(obj, v); err != nil {
+		return nil, err
+	}
+	v.APIVersion = vpav1.SchemeGroupVersion.String()
+
+	return v, nil
+}
+
+// convertVPAToVersion returns the unstructured representation of
+// a v1 VPA object in the given version. The fields of the v1 API
+// that are unknown to the version are dropped.
+func convertVPAToVersion(v *vpav1.VerticalPodAutoscaler, gv schema.GroupVersion) (map[string]interface{}, error) {
+	conv := runtime.DefaultUnstructuredConverter
+
+	obj, err := conv.ToUnstructured(v)
+	if err != nil {
+		return nil, err
+	}
+	switch gv {
+	case vpav1.SchemeGroupVersion:
+	case vpav1beta2.SchemeGroupVersion:
+		vb := &vpav1beta2.VerticalPodAutoscaler{}
+		if err := conv.FromUnstructured(obj, vb); err != nil {
+			return nil, err
+		}
+		if obj, err = conv.ToUnstructured(vb); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported VPA version: %s", gv)
+	}
+	obj["apiVersion"] = gv.String()
+	obj["kind"] = vpaKind
+
+	return obj, nil
+}
+
+// decodeCheckpoint decodes an unstructured VPA checkpoint object.
+// The buckets of the histograms are maps with integer keys, which
+// the unstructured converter cannot decode, so the object goes
+// through its JSON representation.
+func decodeCheckpoint(u *unstructuredv1.Unstructured) (*vpav1.VerticalPodAutoscalerCheckpoint, error) {
+	b, err := u.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	cp := &vpav1.VerticalPodAutoscalerCheckpoint{}
+	if err := json.Unmarshal(b, cp); err != nil {
+		return nil, fmt.Errorf("couldn't decode checkpoint %s/%s: %w", u.GetNamespace(), u.GetName(), err)
+	}
+	return cp, nil
+}
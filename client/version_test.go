@@ -0,0 +1,153 @@
+package client
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	unstructuredv1 "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	vpav1beta2 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1beta2"
+)
+
+func TestNegotiateVPAVersion(t *testing.T) {
+	group := func(name string, versions ...string) metav1.APIGroup {
+		g := metav1.APIGroup{Name: name}
+		for _, v := range versions {
+			g.Versions = append(g.Versions, metav1.GroupVersionForDiscovery{Version: v})
+		}
+		return g
+	}
+	for _, tt := range []struct {
+		name   string
+		groups []metav1.APIGroup
+		want   string
+		ok     bool
+	}{
+		{"v1 preferred", []metav1.APIGroup{group("autoscaling.k8s.io", "v1beta2", "v1")}, "autoscaling.k8s.io/v1", true},
+		{"v1beta2 only", []metav1.APIGroup{group("apps", "v1"), group("autoscaling.k8s.io", "v1beta1", "v1beta2")}, "autoscaling.k8s.io/v1beta2", true},
+		{"unsupported", []metav1.APIGroup{group("autoscaling.k8s.io", "v1beta1")}, "", false},
+		{"missing", []metav1.APIGroup{group("apps", "v1")}, "", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			gv, ok := negotiateVPAVersion(&metav1.APIGroupList{Groups: tt.groups})
+			if ok != tt.ok {
+				t.Fatalf("got ok %t, want %t", ok, tt.ok)
+			}
+			if ok && gv.String() != tt.want {
+				t.Errorf("got version %s, want %s", gv, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertVPA(t *testing.T) {
+	obj := map[string]interface{}{
+		"apiVersion": "autoscaling.k8s.io/v1beta2",
+		"kind":       "VerticalPodAutoscaler",
+		"metadata": map[string]interface{}{
+			"name":      "app",
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"targetRef": map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"name":       "app",
+			},
+			"updatePolicy": map[string]interface{}{
+				"updateMode": "Off",
+			},
+		},
+		"status": map[string]interface{}{
+			"recommendation": map[string]interface{}{
+				"containerRecommendations": []interface{}{
+					map[string]interface{}{
+						"containerName": "app",
+						"target": map[string]interface{}{
+							"cpu": "250m",
+						},
+					},
+				},
+			},
+		},
+	}
+	v, err := convertVPA(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.APIVersion != vpav1.SchemeGroupVersion.String() {
+		t.Errorf("got apiVersion %s, want %s", v.APIVersion, vpav1.SchemeGroupVersion)
+	}
+	if v.Spec.TargetRef == nil || v.Spec.TargetRef.Name != "app" {
+		t.Errorf("unexpected target reference: %v", v.Spec.TargetRef)
+	}
+	if v.Spec.UpdatePolicy == nil || *v.Spec.UpdatePolicy.UpdateMode != vpav1.UpdateModeOff {
+		t.Errorf("unexpected update policy: %v", v.Spec.UpdatePolicy)
+	}
+	crs := v.Status.Recommendation.ContainerRecommendations
+	if len(crs) != 1 || crs[0].Target.Cpu().String() != "250m" {
+		t.Errorf("unexpected recommendations: %v", crs)
+	}
+	obj["apiVersion"] = "autoscaling.k8s.io/v1beta1"
+	if _, err := convertVPA(obj); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+}
+
+func TestConvertVPAToVersion(t *testing.T) {
+	minReplicas := int32(2)
+	v := &vpav1.VerticalPodAutoscaler{
+		Spec: vpav1.VerticalPodAutoscalerSpec{
+			UpdatePolicy: &vpav1.PodUpdatePolicy{MinReplicas: &minReplicas},
+		},
+	}
+	v.Name = "app"
+
+	obj, err := convertVPAToVersion(v, vpav1beta2.SchemeGroupVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj["apiVersion"] != "autoscaling.k8s.io/v1beta2" {
+		t.Errorf("got apiVersion %v", obj["apiVersion"])
+	}
+	spec := obj["spec"].(map[string]interface{})
+	if up, ok := spec["updatePolicy"].(map[string]interface{}); ok {
+		if _, ok := up["minReplicas"]; ok {
+			t.Error("expected minReplicas to be dropped")
+		}
+	}
+}
+
+func TestDecodeCheckpoint(t *testing.T) {
+	u := &unstructuredv1.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "autoscaling.k8s.io/v1beta2",
+		"kind":       "VerticalPodAutoscalerCheckpoint",
+		"metadata": map[string]interface{}{
+			"name":      "app-app",
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"vpaObjectName": "app",
+			"containerName": "app",
+		},
+		"status": map[string]interface{}{
+			"cpuHistogram": map[string]interface{}{
+				"bucketWeights": map[string]interface{}{
+					"5": int64(10000),
+				},
+				"totalWeight": 1.5,
+			},
+			"totalSamplesCount": int64(42),
+		},
+	}}
+	cp, err := decodeCheckpoint(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Spec.VPAObjectName != "app" || cp.Status.TotalSamplesCount != 42 {
+		t.Errorf("unexpected checkpoint: %+v", cp)
+	}
+	if w := cp.Status.CPUHistogram.BucketWeights[5]; w != 10000 {
+		t.Errorf("got weight %d, want 10000", w)
+	}
+}
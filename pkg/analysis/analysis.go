@@ -0,0 +1,361 @@
+// Package analysis compares the recommendations of VerticalPodAutoscaler
+// resources with the actual resource requests of the pods of their targets.
+// It is the engine of the kubectl-vpa-recommendation command, and can be
+// embedded in other programs, such as operators or bots.
+package analysis
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/client"
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+const (
+	defaultTimeout   = 15 * time.Second
+	defaultPageLimit = 250
+)
+
+// Options represents the options of an analysis.
+type Options struct {
+	// Namespace is the namespace of the VPA resources.
+	// It is ignored if AllNamespaces is true.
+	Namespace     string
+	AllNamespaces bool
+	// Names restricts the analysis to the named VPA resources.
+	Names         []string
+	LabelSelector string
+	FieldSelector string
+	// Recommender restricts the analysis to the VPA resources
+	// that select the named recommender. The DefaultRecommender
+	// name matches the resources that select none.
+	Recommender string
+	// RecommendationType is the type of recommendation compared
+	// to the requests. Default to vpa.RecommendationTarget.
+	RecommendationType vpa.RecommendationType
+	// RequestsSource is the source of the requests when the pods
+	// of a target disagree. Default to vpa.SourceMajority.
+	RequestsSource vpa.RequestsSource
+	// Rounder, if not nil, rounds the recommendations
+	// into the suggested values of the results.
+	Rounder *vpa.Rounder
+	// Thresholder, if not nil, sets the threshold
+	// rule that matches each result.
+	Thresholder *vpa.Thresholder
+	// Timeout is the timeout of the list requests of
+	// the VPA resources. Default to 15 seconds.
+	Timeout time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.RecommendationType == "" {
+		o.RecommendationType = vpa.RecommendationTarget
+	}
+	if o.RequestsSource == "" {
+		o.RequestsSource = vpa.SourceMajority
+	}
+	if o.Timeout == 0 {
+		o.Timeout = defaultTimeout
+	}
+	return o
+}
+
+// Report represents the result of an analysis.
+type Report struct {
+	Timestamp          metav1.Time
+	RecommendationType vpa.RecommendationType
+	Items              []Item
+	// Skipped is the number of VPA resources whose
+	// target couldn't be resolved, and have no item.
+	Skipped int
+}
+
+// Item represents the comparison of the recommendations
+// of a single VPA resource with the requests of its target.
+type Item struct {
+	Namespace string
+	Name      string
+	// GroupVersionKind, Labels, Annotations and Conditions
+	// are those of the VPA resource.
+	GroupVersionKind schema.GroupVersionKind
+	Labels           map[string]string
+	Annotations      map[string]string
+	Conditions       []vpav1.VerticalPodAutoscalerCondition
+	// Mode is the update mode of the VPA, if set.
+	Mode                string
+	Recommenders        []string
+	Target              Target
+	Replicas            *int64
+	RequestsSource      vpa.RequestsSource
+	SourcePod           string
+	MixedRequests       bool
+	Variance            []vpa.RequestsVariance
+	QOSClass            corev1.PodQOSClass
+	RecommendedQOSClass corev1.PodQOSClass
+	RecommendedAt       *metav1.Time
+	RecommendedAtSource vpa.RecommendationTimeSource
+	OutdatedTemplate    bool
+	Requests            vpa.ResourceQuantities
+	Recommendations     vpa.ResourceQuantities
+	Suggested           vpa.ResourceQuantities
+	// Differences holds the difference, in percent, between the
+	// request and the recommendation of each requested resource.
+	Differences map[corev1.ResourceName]*float64
+	Containers  []Container
+
+	// Thresholds and Rounding are the rules that match
+	// the VPA, if a thresholder and a rounder are set.
+	Thresholds *vpa.ThresholdRule
+	Rounding   *vpa.RoundingRule
+
+	controller *vpa.TargetController
+}
+
+// TargetController returns the resolved target of the VPA,
+// to inspect its pods further.
+func (i *Item) TargetController() *vpa.TargetController {
+	return i.controller
+}
+
+// Target represents the target controller of a VPA resource.
+type Target struct {
+	APIVersion string
+	Kind       string
+	Name       string
+}
+
+// Container represents the comparison of the recommendation
+// of a single container with its requests. The init and sidecar
+// containers without a recommendation are also represented.
+type Container struct {
+	Name string
+	Type vpa.ContainerType
+	// Recommendation holds the bounds of the recommendation
+	// of the container, and is nil if it has none.
+	Recommendation      *vpav1.RecommendedContainerResources
+	RecommendedAt       *metav1.Time
+	RecommendedAtSource vpa.RecommendationTimeSource
+	OutdatedTemplate    bool
+	Requests            vpa.ResourceQuantities
+	Recommendations     vpa.ResourceQuantities
+	Suggested           vpa.ResourceQuantities
+	Differences         map[corev1.ResourceName]*float64
+}
+
+// Analyze lists the VPA resources that match the options,
+// and compares their recommendations with the requests
+// of their targets.
+func Analyze(ctx context.Context, c client.Interface, opts Options) (Report, error) {
+	vpas, err := List(ctx, c, opts)
+	if err != nil {
+		return Report{}, err
+	}
+	return AnalyzeVPAs(ctx, c, vpas, opts), nil
+}
+
+// List returns the VPA resources that match the options. It fails
+// if the cluster doesn't serve a supported version of the VPA API.
+func List(ctx context.Context, c client.Interface, opts Options) ([]*vpav1.VerticalPodAutoscaler, error) {
+	opts = opts.withDefaults()
+
+	if err := CheckAvailable(c); err != nil {
+		return nil, err
+	}
+	timeout := int64(opts.Timeout.Seconds())
+
+	vpas, err := c.ListVPAResources(ctx, client.ListOptions{
+		Namespace:      opts.Namespace,
+		AllNamespaces:  opts.AllNamespaces,
+		ResourceNames:  opts.Names,
+		LabelSelector:  opts.LabelSelector,
+		FieldSelector:  opts.FieldSelector,
+		TimeoutSeconds: &timeout,
+		Limit:          defaultPageLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	klog.V(4).Infof("fetched %d VPA(s)", len(vpas))
+
+	if name := opts.Recommender; name != "" {
+		vpas = FilterByRecommender(vpas, name)
+		klog.V(4).Infof("%d VPA(s) use recommender %s", len(vpas), name)
+	}
+	return vpas, nil
+}
+
+// CheckAvailable checks that the cluster is reachable
+// and serves a supported version of the VPA API.
+func CheckAvailable(c client.Interface) error {
+	if err := c.IsClusterReachable(); err != nil {
+		return err
+	}
+	gv, err := c.VPAGroupVersion()
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("using VPA API version %s", gv)
+
+	return nil
+}
+
+// AnalyzeVPAs compares the recommendations of the VPA resources
+// in the list with the requests of their targets. The resources
+// whose target cannot be resolved are skipped, and counted in
+// the report. The listing options are ignored.
+func AnalyzeVPAs(ctx context.Context, c client.Interface, vpas []*vpav1.VerticalPodAutoscaler, opts Options) Report {
+	opts = opts.withDefaults()
+
+	r := Report{
+		Timestamp:          metav1.NewTime(time.Now()),
+		RecommendationType: opts.RecommendationType,
+		Items:              make([]Item, 0, len(vpas)),
+	}
+	// The checkpoints are listed once per namespace,
+	// as they are shared by all its VPA resources.
+	checkpoints := make(map[string][]*vpav1.VerticalPodAutoscalerCheckpoint)
+
+	for _, v := range vpas {
+		if v.Spec.TargetRef == nil {
+			klog.V(4).Infof("vpa %s/%s has no target", v.Namespace, v.Name)
+			r.Skipped++
+			continue
+		}
+		tc, err := vpa.NewTargetController(ctx, c, v.Spec.TargetRef, v.Namespace, opts.RequestsSource)
+		if err != nil {
+			klog.V(4).Infof("couldn't get target for vpa %s/%s", v.Namespace, v.Name)
+			r.Skipped++
+			continue
+		}
+		cps, ok := checkpoints[v.Namespace]
+		if !ok {
+			// The checkpoints are optional, as the age of the
+			// recommendations falls back to the VPA conditions.
+			cps, err = c.ListVPACheckpoints(ctx, v.Namespace)
+			if err != nil {
+				klog.V(2).Infof("couldn't list VPA checkpoints of namespace %s: %s", v.Namespace, err)
+			}
+			checkpoints[v.Namespace] = cps
+		}
+		r.Items = append(r.Items, newItem(v, tc, vpa.CheckpointsOf(v.Name, cps), opts))
+	}
+	return r
+}
+
+func newItem(v *vpav1.VerticalPodAutoscaler, tc *vpa.TargetController, cps []*vpav1.VerticalPodAutoscalerCheckpoint, opts Options) Item {
+	rt := opts.RecommendationType
+	rqs := tc.GetRequests()
+	rcs := tc.GetRecommendations(v, rt)
+
+	item := Item{
+		Namespace:        v.Namespace,
+		Name:             v.Name,
+		GroupVersionKind: v.GroupVersionKind(),
+		Labels:           v.Labels,
+		Annotations:      v.Annotations,
+		Conditions:       v.Status.Conditions,
+		Recommenders:     RecommenderNames(v),
+		Target: Target{
+			APIVersion: tc.GroupVersionKind.GroupVersion().String(),
+			Kind:       tc.GroupVersionKind.Kind,
+			Name:       tc.Name,
+		},
+		MixedRequests:   tc.HasMixedRequests(),
+		Requests:        rqs,
+		Recommendations: rcs,
+		Differences:     vpa.DiffResourcesAsPercent(rqs, rcs),
+		controller:      tc,
+	}
+	if p := v.Spec.UpdatePolicy; p != nil && p.UpdateMode != nil {
+		item.Mode = string(*p.UpdateMode)
+	}
+	if n, err := tc.ReplicasCount(); err == nil {
+		item.Replicas = &n
+	}
+	item.RequestsSource, item.SourcePod = tc.RequestsSource()
+	item.QOSClass, item.RecommendedQOSClass = tc.QOSClasses(v, rt)
+
+	if item.MixedRequests {
+		item.Variance = tc.RequestsVariance()
+	}
+	templateAt := tc.TemplateTime()
+	item.RecommendedAt, item.RecommendedAtSource = vpa.RecommendationTime(v, cps)
+	item.OutdatedTemplate = vpa.PredatesTemplate(item.RecommendedAt, item.RecommendedAtSource, templateAt)
+
+	if opts.Thresholder != nil {
+		item.Thresholds = opts.Thresholder.RuleFor(v.Namespace, v.Labels, tc.GroupVersionKind.Kind)
+	}
+	if opts.Rounder != nil {
+		item.Rounding = opts.Rounder.RuleFor(v.Namespace, v.Labels, tc.GroupVersionKind.Kind)
+		item.Suggested = tc.GetRoundedRecommendations(v, rt, item.Rounding)
+	}
+	item.Containers = newContainers(v, tc, rt)
+
+	for i := range item.Containers {
+		c := &item.Containers[i]
+		if item.Rounding != nil {
+			c.Suggested = item.Rounding.Round(c.Recommendations)
+		}
+		c.RecommendedAt, c.RecommendedAtSource = item.RecommendedAt, item.RecommendedAtSource
+		if ccps := containerCheckpoints(cps, c.Name); len(ccps) != 0 {
+			c.RecommendedAt, c.RecommendedAtSource = vpa.RecommendationTime(v, ccps)
+		}
+		c.OutdatedTemplate = vpa.PredatesTemplate(c.RecommendedAt, c.RecommendedAtSource, templateAt)
+	}
+	return item
+}
+
+// newContainers returns the containers of a VPA. The containers
+// with a recommendation come first, followed by the init and
+// sidecar containers of the target's pod spec that have none.
+func newContainers(v *vpav1.VerticalPodAutoscaler, tc *vpa.TargetController, rt vpa.RecommendationType) []Container {
+	var ret []Container
+
+	seen := make(map[string]bool)
+
+	if v.Status.Recommendation != nil {
+		for i := range v.Status.Recommendation.ContainerRecommendations {
+			cr := &v.Status.Recommendation.ContainerRecommendations[i]
+			seen[cr.ContainerName] = true
+			typ, _ := tc.GetContainerType(cr.ContainerName)
+
+			c := newContainer(cr.ContainerName, typ, tc.GetContainerRequests(cr.ContainerName), vpa.ContainerRecommendations(*cr, rt))
+			c.Recommendation = cr
+			ret = append(ret, c)
+		}
+	}
+	for _, c := range tc.Containers() {
+		if c.Type != vpa.ContainerRegular && !seen[c.Name] {
+			ret = append(ret, newContainer(c.Name, c.Type, c.Requests, vpa.ResourceQuantities{}))
+		}
+	}
+	return ret
+}
+
+func newContainer(name string, typ vpa.ContainerType, rqs, rcs vpa.ResourceQuantities) Container {
+	return Container{
+		Name:            name,
+		Type:            typ,
+		Requests:        rqs,
+		Recommendations: rcs,
+		Differences:     vpa.DiffResourcesAsPercent(rqs, rcs),
+	}
+}
+
+// containerCheckpoints returns the checkpoints of a container.
+func containerCheckpoints(checkpoints []*vpav1.VerticalPodAutoscalerCheckpoint, container string) []*vpav1.VerticalPodAutoscalerCheckpoint {
+	var ret []*vpav1.VerticalPodAutoscalerCheckpoint
+	for _, cp := range checkpoints {
+		if cp.Spec.ContainerName == container {
+			ret = append(ret, cp)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,200 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	unstructuredv1 "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+
+	"github.com/wI2L/kubectl-vpa-recommendation/client"
+	"github.com/wI2L/kubectl-vpa-recommendation/vpa"
+)
+
+// testClient is a client that serves the VPA resources, and
+// a Deployment without pods for each target. The methods that
+// the analysis doesn't call panic.
+type testClient struct {
+	client.Interface
+
+	vpas        []*vpav1.VerticalPodAutoscaler
+	checkpoints int
+}
+
+func (c *testClient) IsClusterReachable() error { return nil }
+
+func (c *testClient) VPAGroupVersion() (schema.GroupVersion, error) {
+	return vpav1.SchemeGroupVersion, nil
+}
+
+func (c *testClient) ListVPAResources(context.Context, client.ListOptions) ([]*vpav1.VerticalPodAutoscaler, error) {
+	return c.vpas, nil
+}
+
+func (c *testClient) GetVPATarget(ctx context.Context, ref *autoscalingv1.CrossVersionObjectReference, ns string) (*unstructuredv1.Unstructured, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	obj := &unstructuredv1.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      ref.Name,
+			"namespace": ns,
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"app": ref.Name},
+			},
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name": "app",
+							"resources": map[string]interface{}{
+								"requests": map[string]interface{}{"cpu": "500m", "memory": "256Mi"},
+							},
+						},
+					},
+					"initContainers": []interface{}{
+						map[string]interface{}{
+							"name": "migrate",
+							"resources": map[string]interface{}{
+								"requests": map[string]interface{}{"cpu": "100m", "memory": "64Mi"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}}
+	return obj, nil
+}
+
+func (c *testClient) ListDependentPods(ctx context.Context, _ metav1.ObjectMeta, _ string) ([]*corev1.Pod, error) {
+	return nil, ctx.Err()
+}
+
+func (c *testClient) ListVPACheckpoints(context.Context, string) ([]*vpav1.VerticalPodAutoscalerCheckpoint, error) {
+	c.checkpoints++
+	return nil, nil
+}
+
+func newTestVPA(name, target string, recommenders ...string) *vpav1.VerticalPodAutoscaler {
+	v := newVPAWithRecommenders(name, recommenders...)
+	v.Namespace = "default"
+	if target != "" {
+		v.Spec.TargetRef = &autoscalingv1.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Name:       target,
+		}
+	}
+	v.Status.Recommendation = &vpav1.RecommendedPodResources{
+		ContainerRecommendations: []vpav1.RecommendedContainerResources{{
+			ContainerName: "app",
+			Target: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			},
+		}},
+	}
+	return v
+}
+
+func TestAnalyze(t *testing.T) {
+	c := &testClient{vpas: []*vpav1.VerticalPodAutoscaler{
+		newTestVPA("web", "web"),
+		newTestVPA("api", "api", "custom"),
+		newTestVPA("orphan", ""),
+	}}
+	rounder := &vpa.Rounder{Default: vpa.DefaultRoundingRule()}
+	if err := rounder.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Analyze(context.Background(), c, Options{
+		Namespace: "default",
+		Rounder:   rounder,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecommendationType != vpa.RecommendationTarget {
+		t.Errorf("got recommendation type %q, want the default", r.RecommendationType)
+	}
+	if r.Skipped != 1 {
+		t.Errorf("got %d skipped, want 1", r.Skipped)
+	}
+	if len(r.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(r.Items))
+	}
+	if c.checkpoints != 1 {
+		t.Errorf("got %d checkpoints lists, want one per namespace", c.checkpoints)
+	}
+	item := r.Items[0]
+
+	if item.Target != (Target{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}) {
+		t.Errorf("got target %+v", item.Target)
+	}
+	if item.Replicas == nil || *item.Replicas != 3 {
+		t.Errorf("got replicas %v, want 3", item.Replicas)
+	}
+	// CPU: (500m - 250m) / 250m, memory: (256Mi - 512Mi) / 512Mi
+	if d := item.Differences[corev1.ResourceCPU]; d == nil || *d != 100 {
+		t.Errorf("got cpu difference %v, want +100", d)
+	}
+	if d := item.Differences[corev1.ResourceMemory]; d == nil || *d != -50 {
+		t.Errorf("got memory difference %v, want -50", d)
+	}
+	if item.Rounding == nil || item.Suggested.CPU == nil || item.Suggested.CPU.String() != "250m" {
+		t.Errorf("got suggested %v, want 250m", item.Suggested.CPU)
+	}
+	// The init container without a recommendation
+	// comes after the recommended container.
+	if len(item.Containers) != 2 {
+		t.Fatalf("got %d containers, want 2", len(item.Containers))
+	}
+	if c := item.Containers[0]; c.Name != "app" || c.Recommendation == nil {
+		t.Errorf("got container %s, want app with a recommendation", c.Name)
+	}
+	if c := item.Containers[1]; c.Name != "migrate" || c.Type != vpa.ContainerInit || c.Recommendation != nil {
+		t.Errorf("got container %s of type %q, want init container migrate", c.Name, c.Type)
+	}
+}
+
+func TestAnalyzeWithRecommender(t *testing.T) {
+	c := &testClient{vpas: []*vpav1.VerticalPodAutoscaler{
+		newTestVPA("web", "web"),
+		newTestVPA("api", "api", "custom"),
+	}}
+	r, err := Analyze(context.Background(), c, Options{Recommender: "custom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Items) != 1 || r.Items[0].Name != "api" {
+		t.Fatalf("got %d items, want api only", len(r.Items))
+	}
+	if got := r.Items[0].Recommenders; len(got) != 1 || got[0] != "custom" {
+		t.Errorf("got recommenders %v, want [custom]", got)
+	}
+}
+
+func TestAnalyzeVPAsCanceled(t *testing.T) {
+	c := &testClient{}
+	vpas := []*vpav1.VerticalPodAutoscaler{newTestVPA("web", "web")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The targets of a canceled analysis can't be resolved.
+	r := AnalyzeVPAs(ctx, c, vpas, Options{})
+	if len(r.Items) != 0 || r.Skipped != 1 {
+		t.Errorf("got %d items and %d skipped, want the VPA skipped", len(r.Items), r.Skipped)
+	}
+}
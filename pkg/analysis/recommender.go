@@ -0,0 +1,44 @@
+package analysis
+
+import (
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+// DefaultRecommender is the name of the recommender that
+// computes the recommendations of the VPA resources which
+// don't select any recommender in their spec.
+const DefaultRecommender = "default"
+
+// RecommenderNames returns the names of the recommenders
+// of the VPA, or "default" if it doesn't select any.
+func RecommenderNames(v *vpav1.VerticalPodAutoscaler) []string {
+	var names []string
+	for _, r := range v.Spec.Recommenders {
+		if r != nil && r.Name != "" {
+			names = append(names, r.Name)
+		}
+	}
+	if len(names) == 0 {
+		return []string{DefaultRecommender}
+	}
+	return names
+}
+
+// FilterByRecommender returns the VPA resources that select the
+// named recommender. An empty name returns all the resources.
+func FilterByRecommender(vpas []*vpav1.VerticalPodAutoscaler, name string) []*vpav1.VerticalPodAutoscaler {
+	if name == "" {
+		return vpas
+	}
+	ret := make([]*vpav1.VerticalPodAutoscaler, 0, len(vpas))
+
+	for _, v := range vpas {
+		for _, n := range RecommenderNames(v) {
+			if n == name {
+				ret = append(ret, v)
+				break
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+func newVPAWithRecommenders(name string, recommenders ...string) *vpav1.VerticalPodAutoscaler {
+	v := &vpav1.VerticalPodAutoscaler{}
+	v.Name = name
+	for _, r := range recommenders {
+		v.Spec.Recommenders = append(v.Spec.Recommenders, &vpav1.VerticalPodAutoscalerRecommenderSelector{Name: r})
+	}
+	return v
+}
+
+func TestRecommenderNames(t *testing.T) {
+	for _, tt := range []struct {
+		recommenders []string
+		want         []string
+	}{
+		{nil, []string{"default"}},
+		{[]string{""}, []string{"default"}},
+		{[]string{"custom"}, []string{"custom"}},
+		{[]string{"custom", "default"}, []string{"custom", "default"}},
+	} {
+		if got := RecommenderNames(newVPAWithRecommenders("app", tt.recommenders...)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("got %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestFilterByRecommender(t *testing.T) {
+	vpas := []*vpav1.VerticalPodAutoscaler{
+		newVPAWithRecommenders("a"),
+		newVPAWithRecommenders("b", "custom"),
+		newVPAWithRecommenders("c", "custom", "default"),
+	}
+	for _, tt := range []struct {
+		name string
+		want []string
+	}{
+		{"", []string{"a", "b", "c"}},
+		{"default", []string{"a", "c"}},
+		{"custom", []string{"b", "c"}},
+		{"other", []string{}},
+	} {
+		var got []string
+		for _, v := range FilterByRecommender(vpas, tt.name) {
+			got = append(got, v.Name)
+		}
+		if len(got) != len(tt.want) || (len(got) != 0 && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("%q: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Bands represents the count of percentage differences
+// between requests and recommendations per threshold band.
+// A positive difference means that the request is higher
+// than the recommendation, i.e. over-provisioned.
+type Bands struct {
+	OverCritical  int `json:"overCritical"`
+	OverWarning   int `json:"overWarning"`
+	Within        int `json:"within"`
+	UnderWarning  int `json:"underWarning"`
+	UnderCritical int `json:"underCritical"`
+}
+
+// CountBands returns the count of the differences per band,
+// delimited by the warning and critical thresholds. A value
+// that equals a threshold belongs to the upper band, as in
+// the colored output of the differences.
+func CountBands(diffs []float64, warning, critical float64) Bands {
+	var b Bands
+
+	for _, d := range diffs {
+		b.Add(d, warning, critical)
+	}
+	return b
+}
+
+// Add counts the difference in its band, delimited by the
+// warning and critical thresholds, which may differ from
+// one difference to another.
+func (b *Bands) Add(d, warning, critical float64) {
+	switch {
+	case d >= critical:
+		b.OverCritical++
+	case d >= warning:
+		b.OverWarning++
+	case d <= -critical:
+		b.UnderCritical++
+	case d <= -warning:
+		b.UnderWarning++
+	default:
+		b.Within++
+	}
+}
+
+// Over returns the count of over-provisioned workloads.
+func (b Bands) Over() int {
+	return b.OverWarning + b.OverCritical
+}
+
+// Under returns the count of under-provisioned workloads.
+func (b Bands) Under() int {
+	return b.UnderWarning + b.UnderCritical
+}
+
+// Bucket represents a range of values of a histogram,
+// which includes its lower bound and excludes its upper
+// bound. A nil bound is unbounded.
+type Bucket struct {
+	Lower *float64 `json:"lower,omitempty"`
+	Upper *float64 `json:"upper,omitempty"`
+	Count int      `json:"count"`
+}
+
+// Histogram is the distribution of a set of values,
+// ordered from the lowest bucket to the highest.
+type Histogram []Bucket
+
+// NewHistogram returns the distribution of the values into
+// the buckets delimited by the given bounds. The histogram
+// has a bucket for each interval between two consecutive
+// bounds, plus the buckets of the values below the lowest
+// bound and above the highest.
+func NewHistogram(values, bounds []float64) Histogram {
+	bs := make([]float64, len(bounds))
+	copy(bs, bounds)
+	sort.Float64s(bs)
+
+	h := make(Histogram, 0, len(bs)+1)
+	for i := 0; i <= len(bs); i++ {
+		var b Bucket
+		if i > 0 {
+			b.Lower = &bs[i-1]
+		}
+		if i < len(bs) {
+			b.Upper = &bs[i]
+		}
+		h = append(h, b)
+	}
+	for _, v := range values {
+		// Index of the first bound greater than the value.
+		i := sort.Search(len(bs), func(i int) bool { return bs[i] > v })
+		h[i].Count++
+	}
+	return h
+}
+
+// String returns the range of the bucket, such as "[-20, 0)".
+func (b Bucket) String() string {
+	switch {
+	case b.Lower == nil && b.Upper == nil:
+		return "all"
+	case b.Lower == nil:
+		return fmt.Sprintf("< %g", *b.Upper)
+	case b.Upper == nil:
+		return fmt.Sprintf(">= %g", *b.Lower)
+	default:
+		return fmt.Sprintf("[%g, %g)", *b.Lower, *b.Upper)
+	}
+}
+
+// Render writes an ASCII representation of the histogram
+// to w, with a bar for each bucket. The longest bar, which
+// is the bucket with the highest count, has the given width.
+func (h Histogram) Render(w io.Writer, width int) error {
+	return h.RenderWithLabels(w, width, Bucket.String)
+}
+
+// RenderWithLabels is like Render, but the bucket
+// ranges are formatted by the label function.
+func (h Histogram) RenderWithLabels(w io.Writer, width int, label func(Bucket) string) error {
+	var maxCount, labelWidth int
+	labels := make([]string, len(h))
+
+	for i, b := range h {
+		if b.Count > maxCount {
+			maxCount = b.Count
+		}
+		labels[i] = label(b)
+		if l := len(labels[i]); l > labelWidth {
+			labelWidth = l
+		}
+	}
+	for i, b := range h {
+		n := 0
+		if maxCount > 0 {
+			n = b.Count * width / maxCount
+		}
+		if n == 0 && b.Count > 0 {
+			n = 1
+		}
+		_, err := fmt.Fprintf(w, "%*s | %-*s %d\n", labelWidth, labels[i], width, strings.Repeat("#", n), b.Count)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
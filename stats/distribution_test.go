@@ -0,0 +1,94 @@
+package stats
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCountBands(t *testing.T) {
+	diffs := []float64{-75, -50, -30, -20, -5, 0, 19.99, 20, 49, 50, 300}
+
+	got := CountBands(diffs, 20, 50)
+	want := Bands{
+		OverCritical:  2,
+		OverWarning:   2,
+		Within:        3,
+		UnderWarning:  2,
+		UnderCritical: 2,
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got.Over() != 4 || got.Under() != 4 {
+		t.Errorf("got %d over and %d under, want 4 and 4", got.Over(), got.Under())
+	}
+}
+
+func TestBandsAdd(t *testing.T) {
+	var b Bands
+
+	// The same difference belongs to distinct
+	// bands depending on its thresholds.
+	b.Add(30, 20, 50)
+	b.Add(30, 10, 25)
+	b.Add(30, 40, 80)
+	b.Add(-30, 10, 25)
+
+	want := Bands{OverCritical: 1, OverWarning: 1, Within: 1, UnderCritical: 1}
+	if b != want {
+		t.Errorf("got %+v, want %+v", b, want)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{-60, -20, -10, 0, 5, 5, 150}, []float64{0, -20, 20})
+
+	want := []struct {
+		label string
+		count int
+	}{
+		{"< -20", 1},
+		{"[-20, 0)", 2},
+		{"[0, 20)", 3},
+		{">= 20", 1},
+	}
+	if len(h) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(h), len(want))
+	}
+	for i, w := range want {
+		if h[i].String() != w.label || h[i].Count != w.count {
+			t.Errorf("bucket #%d: got %s (%d), want %s (%d)", i, h[i], h[i].Count, w.label, w.count)
+		}
+	}
+	var buf bytes.Buffer
+	if err := h.Render(&buf, 6); err != nil {
+		t.Fatal(err)
+	}
+	const out = "   < -20 | ##     1\n" +
+		"[-20, 0) | ####   2\n" +
+		" [0, 20) | ###### 3\n" +
+		"   >= 20 | ##     1\n"
+	if buf.String() != out {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", buf.String(), out)
+	}
+}
+
+func TestHistogramRenderWithLabels(t *testing.T) {
+	h := NewHistogram([]float64{1, 2, 2}, []float64{2})
+
+	var buf bytes.Buffer
+	label := func(b Bucket) string {
+		if b.Lower == nil {
+			return "low"
+		}
+		return "high"
+	}
+	if err := h.RenderWithLabels(&buf, 4, label); err != nil {
+		t.Fatal(err)
+	}
+	const out = " low | ##   1\n" +
+		"high | #### 2\n"
+	if buf.String() != out {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", buf.String(), out)
+	}
+}
@@ -0,0 +1,168 @@
+// Package stats computes descriptive statistics of
+// resource quantities and percentage differences.
+package stats
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"gopkg.in/inf.v0"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// scale is the number of decimal digits kept by the
+// divisions, which is the precision of the quantities.
+const scale = 9
+
+// Sample is a sorted set of quantities. The quantities are
+// copied when the sample is created, so computing statistics
+// never mutates the values of the caller.
+type Sample struct {
+	values []*inf.Dec
+}
+
+// NewSample returns a sample of the given quantities.
+// The nil quantities are ignored.
+func NewSample(qs []*resource.Quantity) *Sample {
+	s := &Sample{values: make([]*inf.Dec, 0, len(qs))}
+
+	for _, q := range qs {
+		if q == nil {
+			continue
+		}
+		// AsDec converts the internal representation of the
+		// quantity in place, so it is called on a copy.
+		c := q.DeepCopy()
+		s.values = append(s.values, new(inf.Dec).Set(c.AsDec()))
+	}
+	sort.Slice(s.values, func(i, j int) bool {
+		return s.values[i].Cmp(s.values[j]) < 0
+	})
+	return s
+}
+
+// Len returns the number of values of the sample.
+func (s *Sample) Len() int {
+	return len(s.values)
+}
+
+// Sum returns the sum of the values, or zero
+// if the sample is empty.
+func (s *Sample) Sum() *resource.Quantity {
+	return toQuantity(s.sum())
+}
+
+// Min returns the lowest value, or nil
+// if the sample is empty.
+func (s *Sample) Min() *resource.Quantity {
+	if len(s.values) == 0 {
+		return nil
+	}
+	return toQuantity(s.values[0])
+}
+
+// Max returns the highest value, or nil
+// if the sample is empty.
+func (s *Sample) Max() *resource.Quantity {
+	if len(s.values) == 0 {
+		return nil
+	}
+	return toQuantity(s.values[len(s.values)-1])
+}
+
+// Mean returns the arithmetic mean of the values,
+// or nil if the sample is empty.
+func (s *Sample) Mean() *resource.Quantity {
+	m := s.mean()
+	if m == nil {
+		return nil
+	}
+	return toQuantity(m)
+}
+
+// Median returns the median of the values, which is the
+// mean of the two middle values if the sample has an even
+// length, or nil if the sample is empty.
+func (s *Sample) Median() *resource.Quantity {
+	return s.Percentile(50)
+}
+
+// Percentile returns the p-th percentile of the values,
+// linearly interpolated between the closest ranks. It
+// returns nil if the sample is empty, or if p isn't in
+// the range [0, 100].
+func (s *Sample) Percentile(p float64) *resource.Quantity {
+	n := len(s.values)
+	if n == 0 || p < 0 || p > 100 || math.IsNaN(p) {
+		return nil
+	}
+	rank := p / 100 * float64(n-1)
+	lo := int(math.Floor(rank))
+	if lo >= n-1 {
+		return toQuantity(s.values[n-1])
+	}
+	frac := rank - float64(lo)
+	if frac == 0 {
+		return toQuantity(s.values[lo])
+	}
+	// v[lo] + (v[lo+1] - v[lo]) * frac
+	d := new(inf.Dec).Sub(s.values[lo+1], s.values[lo])
+	d.Mul(d, floatToDec(frac))
+	d.Add(d, s.values[lo])
+
+	return toQuantity(d.Round(d, scale, inf.RoundHalfEven))
+}
+
+// StdDev returns the population standard deviation
+// of the values, or nil if the sample is empty.
+func (s *Sample) StdDev() *resource.Quantity {
+	m := s.mean()
+	if m == nil {
+		return nil
+	}
+	variance := new(inf.Dec)
+	for _, v := range s.values {
+		d := new(inf.Dec).Sub(v, m)
+		variance.Add(variance, d.Mul(d, d))
+	}
+	variance.QuoRound(variance, inf.NewDec(int64(len(s.values)), 0), scale, inf.RoundHalfEven)
+
+	f, err := strconv.ParseFloat(variance.String(), 64)
+	if err != nil {
+		return nil
+	}
+	return toQuantity(floatToDec(math.Sqrt(f)))
+}
+
+func (s *Sample) sum() *inf.Dec {
+	sum := new(inf.Dec)
+	for _, v := range s.values {
+		sum.Add(sum, v)
+	}
+	return sum
+}
+
+func (s *Sample) mean() *inf.Dec {
+	if len(s.values) == 0 {
+		return nil
+	}
+	sum := s.sum()
+
+	return sum.QuoRound(sum, inf.NewDec(int64(len(s.values)), 0), scale, inf.RoundHalfEven)
+}
+
+// floatToDec returns the decimal representation of f,
+// rounded to the precision of the quantities.
+func floatToDec(f float64) *inf.Dec {
+	d, ok := new(inf.Dec).SetString(strconv.FormatFloat(f, 'f', scale, 64))
+	if !ok {
+		return new(inf.Dec)
+	}
+	return d
+}
+
+// toQuantity returns a quantity of a copy of d.
+func toQuantity(d *inf.Dec) *resource.Quantity {
+	return resource.NewDecimalQuantity(*new(inf.Dec).Set(d), resource.DecimalSI)
+}
@@ -0,0 +1,90 @@
+package stats
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func quantities(ss ...string) []*resource.Quantity {
+	qs := make([]*resource.Quantity, 0, len(ss))
+	for _, s := range ss {
+		if s == "" {
+			qs = append(qs, nil)
+			continue
+		}
+		q := resource.MustParse(s)
+		qs = append(qs, &q)
+	}
+	return qs
+}
+
+func TestSample(t *testing.T) {
+	qs := quantities("400m", "100m", "", "200m", "300m")
+	s := NewSample(qs)
+
+	if s.Len() != 4 {
+		t.Fatalf("got %d values, want 4", s.Len())
+	}
+	for _, tt := range []struct {
+		name string
+		got  *resource.Quantity
+		want string
+	}{
+		{"sum", s.Sum(), "1"},
+		{"min", s.Min(), "100m"},
+		{"max", s.Max(), "400m"},
+		{"mean", s.Mean(), "250m"},
+		{"median", s.Median(), "250m"},
+		{"p0", s.Percentile(0), "100m"},
+		{"p75", s.Percentile(75), "325m"},
+		{"p90", s.Percentile(90), "370m"},
+		{"p100", s.Percentile(100), "400m"},
+		{"stddev", s.StdDev(), "111803399n"},
+	} {
+		if tt.got == nil {
+			t.Errorf("%s: got nil, want %s", tt.name, tt.want)
+			continue
+		}
+		if want := resource.MustParse(tt.want); tt.got.Cmp(want) != 0 {
+			t.Errorf("%s: got %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+	if s.Percentile(101) != nil || s.Percentile(-1) != nil {
+		t.Error("expected nil percentile out of range")
+	}
+	// The quantities of the caller must not be mutated,
+	// nor aliased by the values of the sample.
+	for i, want := range []string{"400m", "100m", "", "200m", "300m"} {
+		if want == "" {
+			continue
+		}
+		if qs[i].Cmp(resource.MustParse(want)) != 0 {
+			t.Errorf("quantity #%d mutated: got %s, want %s", i, qs[i], want)
+		}
+	}
+	sum := s.Sum()
+	sum.Add(resource.MustParse("1"))
+	if got := s.Sum(); got.Cmp(resource.MustParse("1")) != 0 {
+		t.Errorf("sample mutated: got sum %s, want 1", got)
+	}
+}
+
+func TestSampleOddMedian(t *testing.T) {
+	s := NewSample(quantities("1Gi", "64Mi", "256Mi"))
+
+	if got, want := s.Median(), resource.MustParse("256Mi"); got.Cmp(want) != 0 {
+		t.Errorf("got median %s, want %s", got, want.String())
+	}
+}
+
+func TestEmptySample(t *testing.T) {
+	s := NewSample(quantities("", ""))
+
+	if s.Min() != nil || s.Max() != nil || s.Mean() != nil || s.Median() != nil || s.StdDev() != nil {
+		t.Error("expected nil statistics for an empty sample")
+	}
+	if !s.Sum().IsZero() {
+		t.Errorf("got sum %s, want 0", s.Sum())
+	}
+}
@@ -0,0 +1,89 @@
+package vpa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/vertical-pod-autoscaler/pkg/utils/annotations"
+)
+
+// resourceUpdatesAnnotation is the annotation added by the
+// VPA admission controller to the pods whose resources it
+// updated. The constant isn't imported from the admission
+// controller package to avoid pulling its dependencies.
+const resourceUpdatesAnnotation = "vpaUpdates"
+
+const resourceUpdatesPrefix = "Pod resources updated by "
+
+// PodUpdates represents the changes made by the VPA
+// admission controller to the resources of a pod, as
+// recorded in the annotations of the pod.
+type PodUpdates struct {
+	// VPA is the name of the VPA resource whose
+	// recommendation was applied to the pod.
+	VPA string `json:"vpa"`
+	// ObservedContainers is the list of containers
+	// of the pod when the admission controller saw it.
+	ObservedContainers []string `json:"observedContainers,omitempty"`
+	// Containers maps the name of each container to the
+	// list of updates made to it, such as "cpu request"
+	// or "memory limit", followed by the annotations of
+	// the recommendation, such as a capping reason.
+	Containers map[string][]string `json:"containers,omitempty"`
+}
+
+// ParsePodUpdates decodes the annotations added by the VPA
+// admission controller to a pod. It returns nil if the pod
+// wasn't updated.
+func ParsePodUpdates(pod *corev1.Pod) (*PodUpdates, error) {
+	value, ok := pod.Annotations[resourceUpdatesAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	if !strings.HasPrefix(value, resourceUpdatesPrefix) {
+		return nil, fmt.Errorf("invalid %s annotation: %q", resourceUpdatesAnnotation, value)
+	}
+	value = strings.TrimPrefix(value, resourceUpdatesPrefix)
+
+	i := strings.Index(value, ": ")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid %s annotation: missing updates", resourceUpdatesAnnotation)
+	}
+	pu := &PodUpdates{
+		VPA:        value[:i],
+		Containers: make(map[string][]string),
+	}
+	if observed, ok := pod.Annotations[annotations.VpaObservedContainersLabel]; ok {
+		names, err := annotations.ParseVpaObservedContainersValue(observed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", annotations.VpaObservedContainersLabel, err)
+		}
+		pu.ObservedContainers = names
+	}
+	// The updates of each container are separated by semicolons,
+	// and formatted as "container <index>: <update>, <update>".
+	for _, s := range strings.Split(value[i+2:], "; ") {
+		var updates []string
+
+		j := strings.Index(s, ":")
+		if j < 0 || !strings.HasPrefix(s, "container ") {
+			return nil, fmt.Errorf("invalid container updates: %q", s)
+		}
+		idx, err := strconv.Atoi(strings.TrimPrefix(s[:j], "container "))
+		if err != nil {
+			return nil, fmt.Errorf("invalid container index: %w", err)
+		}
+		if idx < 0 || idx >= len(pod.Spec.Containers) {
+			return nil, fmt.Errorf("container index %d out of range", idx)
+		}
+		for _, u := range strings.Split(s[j+1:], ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				updates = append(updates, u)
+			}
+		}
+		pu.Containers[pod.Spec.Containers[idx].Name] = updates
+	}
+	return pu, nil
+}
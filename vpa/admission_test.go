@@ -0,0 +1,53 @@
+package vpa
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParsePodUpdates(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"vpaUpdates":            "Pod resources updated by my-vpa: container 0: cpu request, memory request; container 1: memory request, memory limit",
+				"vpaObservedContainers": "app, proxy",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app"}, {Name: "proxy"}},
+		},
+	}
+	pu, err := ParsePodUpdates(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &PodUpdates{
+		VPA:                "my-vpa",
+		ObservedContainers: []string{"app", "proxy"},
+		Containers: map[string][]string{
+			"app":   {"cpu request", "memory request"},
+			"proxy": {"memory request", "memory limit"},
+		},
+	}
+	if !reflect.DeepEqual(pu, want) {
+		t.Errorf("got %+v, want %+v", pu, want)
+	}
+	pod.Annotations = nil
+	if pu, err := ParsePodUpdates(pod); err != nil || pu != nil {
+		t.Errorf("expected no updates, got %+v (%v)", pu, err)
+	}
+	for _, v := range []string{
+		"resources updated",
+		"Pod resources updated by my-vpa",
+		"Pod resources updated by my-vpa: container 2: cpu request",
+		"Pod resources updated by my-vpa: app: cpu request",
+	} {
+		pod.Annotations = map[string]string{"vpaUpdates": v}
+		if _, err := ParsePodUpdates(pod); err == nil {
+			t.Errorf("expected error for annotation %q", v)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package vpa
+
+import (
+	"math"
+	"sort"
+
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+// HistogramOptions describes the exponential buckets of the
+// histograms aggregated by the VPA recommender. The size of
+// the first bucket is FirstBucketSize, and the size of each
+// following bucket is Ratio times the size of the previous.
+type HistogramOptions struct {
+	FirstBucketSize float64
+	Ratio           float64
+	MaxValue        float64
+}
+
+var (
+	// CPUHistogramOptions are the options of the CPU usage
+	// histograms of the recommender, in cores.
+	CPUHistogramOptions = HistogramOptions{FirstBucketSize: 0.01, Ratio: 1.05, MaxValue: 1000}
+	// MemoryHistogramOptions are the options of the memory
+	// peaks histograms of the recommender, in bytes.
+	MemoryHistogramOptions = HistogramOptions{FirstBucketSize: 1e7, Ratio: 1.05, MaxValue: 1e12}
+)
+
+// NumBuckets returns the number of buckets of the histograms.
+func (o HistogramOptions) NumBuckets() int {
+	n := math.Log(o.MaxValue*(o.Ratio-1)/o.FirstBucketSize+1) / math.Log(o.Ratio)
+	return int(math.Ceil(n)) + 1
+}
+
+// BucketStart returns the lower bound of the bucket with
+// the given index. The upper bound of a bucket is the lower
+// bound of the next one.
+func (o HistogramOptions) BucketStart(i int) float64 {
+	if i <= 0 {
+		return 0
+	}
+	return o.FirstBucketSize * (math.Pow(o.Ratio, float64(i)) - 1) / (o.Ratio - 1)
+}
+
+// HistogramBucket represents a bucket of a histogram checkpoint.
+type HistogramBucket struct {
+	Index  int     `json:"index"`
+	Start  float64 `json:"start"`
+	End    float64 `json:"end"`
+	Weight uint32  `json:"weight"`
+}
+
+// CheckpointBuckets returns the buckets of the histogram checkpoint
+// between the first and the last buckets that have a weight, ordered
+// by index. The weights stored in checkpoints are normalized by the
+// recommender, so that the heaviest bucket weighs 10000.
+func CheckpointBuckets(h vpav1.HistogramCheckpoint, o HistogramOptions) []HistogramBucket {
+	if len(h.BucketWeights) == 0 {
+		return nil
+	}
+	indexes := make([]int, 0, len(h.BucketWeights))
+	for i := range h.BucketWeights {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	first, last := indexes[0], indexes[len(indexes)-1]
+	buckets := make([]HistogramBucket, 0, last-first+1)
+
+	for i := first; i <= last; i++ {
+		buckets = append(buckets, HistogramBucket{
+			Index:  i,
+			Start:  o.BucketStart(i),
+			End:    o.BucketStart(i + 1),
+			Weight: h.BucketWeights[i],
+		})
+	}
+	return buckets
+}
+
+// CheckpointsOf returns the checkpoints of the named VPA resource,
+// sorted by container name.
+func CheckpointsOf(name string, checkpoints []*vpav1.VerticalPodAutoscalerCheckpoint) []*vpav1.VerticalPodAutoscalerCheckpoint {
+	var ret []*vpav1.VerticalPodAutoscalerCheckpoint
+	for _, c := range checkpoints {
+		if c.Spec.VPAObjectName == name {
+			ret = append(ret, c)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Spec.ContainerName < ret[j].Spec.ContainerName
+	})
+	return ret
+}
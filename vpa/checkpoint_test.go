@@ -0,0 +1,64 @@
+package vpa
+
+import (
+	"math"
+	"testing"
+
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+func TestHistogramOptions(t *testing.T) {
+	o := CPUHistogramOptions
+
+	if n := o.NumBuckets(); n != 176 {
+		t.Errorf("got %d CPU buckets, want 176", n)
+	}
+	if n := MemoryHistogramOptions.NumBuckets(); n != 176 {
+		t.Errorf("got %d memory buckets, want 176", n)
+	}
+	for i, want := range []float64{0, 0.01, 0.0205, 0.031525} {
+		if got := o.BucketStart(i); math.Abs(got-want) > 1e-9 {
+			t.Errorf("bucket #%d: got start %g, want %g", i, got, want)
+		}
+	}
+}
+
+func TestCheckpointBuckets(t *testing.T) {
+	h := vpav1.HistogramCheckpoint{
+		BucketWeights: map[int]uint32{5: 100, 2: 10000},
+	}
+	buckets := CheckpointBuckets(h, CPUHistogramOptions)
+	if len(buckets) != 4 {
+		t.Fatalf("got %d buckets, want 4", len(buckets))
+	}
+	for i, want := range []uint32{10000, 0, 0, 100} {
+		b := buckets[i]
+		if b.Index != i+2 || b.Weight != want {
+			t.Errorf("bucket #%d: got index %d and weight %d, want %d and %d", i, b.Index, b.Weight, i+2, want)
+		}
+		if b.Start >= b.End || (i > 0 && buckets[i-1].End != b.Start) {
+			t.Errorf("bucket #%d: invalid range [%g, %g)", i, b.Start, b.End)
+		}
+	}
+	if got := CheckpointBuckets(vpav1.HistogramCheckpoint{}, CPUHistogramOptions); got != nil {
+		t.Errorf("got %v, want no buckets", got)
+	}
+}
+
+func TestCheckpointsOf(t *testing.T) {
+	newCheckpoint := func(vpa, container string) *vpav1.VerticalPodAutoscalerCheckpoint {
+		c := &vpav1.VerticalPodAutoscalerCheckpoint{}
+		c.Spec.VPAObjectName = vpa
+		c.Spec.ContainerName = container
+		return c
+	}
+	list := []*vpav1.VerticalPodAutoscalerCheckpoint{
+		newCheckpoint("app", "sidecar"),
+		newCheckpoint("other", "app"),
+		newCheckpoint("app", "app"),
+	}
+	got := CheckpointsOf("app", list)
+	if len(got) != 2 || got[0].Spec.ContainerName != "app" || got[1].Spec.ContainerName != "sidecar" {
+		t.Errorf("unexpected checkpoints: %v", got)
+	}
+}
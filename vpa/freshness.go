@@ -0,0 +1,103 @@
+package vpa
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+// templateRevisionLabels are the labels set by the controllers
+// on their pods to identify the revision of the pod template.
+var templateRevisionLabels = []string{
+	"pod-template-hash",        // Deployment, ReplicaSet
+	"controller-revision-hash", // StatefulSet, DaemonSet
+}
+
+// RecommendationTimeSource is the source of the
+// update time of a recommendation.
+type RecommendationTimeSource string
+
+const (
+	// RecommendationTimeCheckpoint is the latest update time of the
+	// checkpoints, which the recommender refreshes while it runs.
+	RecommendationTimeCheckpoint RecommendationTimeSource = "checkpoint"
+	// RecommendationTimeCondition is the last transition time of the
+	// condition that reports that a recommendation is provided. The
+	// recommendation may have been updated many times since, so it is
+	// only a lower bound of the update time.
+	RecommendationTimeCondition RecommendationTimeSource = "condition"
+)
+
+// RecommendationTime returns the last time the recommendation of the
+// VPA is known to have been updated, and the source of that time. This
+// is the latest update time of the given checkpoints, or if there are
+// none, the last transition time of the condition that reports that a
+// recommendation is provided. It returns nil if none of these times is
+// known.
+func RecommendationTime(v *vpav1.VerticalPodAutoscaler, checkpoints []*vpav1.VerticalPodAutoscalerCheckpoint) (*metav1.Time, RecommendationTimeSource) {
+	var last *metav1.Time
+
+	for _, cp := range checkpoints {
+		t := cp.Status.LastUpdateTime
+		if t.IsZero() {
+			continue
+		}
+		if last == nil || last.Before(&t) {
+			last = &t
+		}
+	}
+	if last != nil {
+		return last, RecommendationTimeCheckpoint
+	}
+	for _, c := range v.Status.Conditions {
+		if c.Type == vpav1.RecommendationProvided && c.Status == corev1.ConditionTrue && !c.LastTransitionTime.IsZero() {
+			t := c.LastTransitionTime
+			return &t, RecommendationTimeCondition
+		}
+	}
+	return nil, ""
+}
+
+// TemplateTime returns the time from which the pods of the
+// controller run its current pod template. See templateTime.
+func (tc *TargetController) TemplateTime() *metav1.Time {
+	return templateTime(tc.pods)
+}
+
+// templateTime returns the creation time of the oldest pod that
+// has the same template revision as the newest pod, which is when
+// the rollout of the current template started. It returns nil if
+// the pods have no revision label.
+func templateTime(pods []*corev1.Pod) *metav1.Time {
+	var newest *corev1.Pod
+	for _, p := range pods {
+		if newest == nil || newest.CreationTimestamp.Before(&p.CreationTimestamp) {
+			newest = p
+		}
+	}
+	if newest == nil {
+		return nil
+	}
+	for _, key := range templateRevisionLabels {
+		rev, ok := newest.Labels[key]
+		if !ok {
+			continue
+		}
+		oldest := newest.CreationTimestamp
+		for _, p := range pods {
+			if p.Labels[key] == rev && p.CreationTimestamp.Before(&oldest) {
+				oldest = p.CreationTimestamp
+			}
+		}
+		return &oldest
+	}
+	return nil
+}
+
+// PredatesTemplate returns whether the recommendation was last
+// updated before the rollout of the current pod template. A time
+// from the condition is only a lower bound of the update time, so
+// the recommendation is never known to predate the template.
+func PredatesTemplate(recommendedAt *metav1.Time, source RecommendationTimeSource, templateAt *metav1.Time) bool {
+	return source == RecommendationTimeCheckpoint && recommendedAt != nil && templateAt != nil && recommendedAt.Before(templateAt)
+}
@@ -0,0 +1,104 @@
+package vpa
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+func TestRecommendationTime(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	v := &vpav1.VerticalPodAutoscaler{
+		Status: vpav1.VerticalPodAutoscalerStatus{
+			Conditions: []vpav1.VerticalPodAutoscalerCondition{{
+				Type:               vpav1.RecommendationProvided,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(now.Add(-48 * time.Hour)),
+			}},
+		},
+	}
+	if got, src := RecommendationTime(v, nil); got == nil || !got.Time.Equal(now.Add(-48*time.Hour)) || src != RecommendationTimeCondition {
+		t.Errorf("got %v from %q, want the condition transition time", got, src)
+	}
+	newCheckpoint := func(d time.Duration) *vpav1.VerticalPodAutoscalerCheckpoint {
+		cp := &vpav1.VerticalPodAutoscalerCheckpoint{}
+		cp.Status.LastUpdateTime = metav1.NewTime(now.Add(-d))
+		return cp
+	}
+	cps := []*vpav1.VerticalPodAutoscalerCheckpoint{
+		newCheckpoint(time.Hour),
+		newCheckpoint(time.Minute),
+		{},
+	}
+	if got, src := RecommendationTime(v, cps); got == nil || !got.Time.Equal(now.Add(-time.Minute)) || src != RecommendationTimeCheckpoint {
+		t.Errorf("got %v from %q, want the latest checkpoint update time", got, src)
+	}
+	v.Status.Conditions[0].Status = corev1.ConditionFalse
+	if got, src := RecommendationTime(v, nil); got != nil || src != "" {
+		t.Errorf("got %v from %q, want nil", got, src)
+	}
+}
+
+func TestTemplateTime(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	newPod := func(hash string, age time.Duration) *corev1.Pod {
+		p := &corev1.Pod{}
+		p.CreationTimestamp = metav1.NewTime(now.Add(-age))
+		if hash != "" {
+			p.Labels = map[string]string{"pod-template-hash": hash}
+		}
+		return p
+	}
+	pods := []*corev1.Pod{
+		newPod("old", 72*time.Hour),
+		newPod("new", 2*time.Hour),
+		newPod("new", time.Hour),
+	}
+	got := templateTime(pods)
+	if got == nil || !got.Time.Equal(now.Add(-2*time.Hour)) {
+		t.Errorf("got %v, want the creation time of the oldest pod of the new revision", got)
+	}
+	if got := templateTime([]*corev1.Pod{newPod("", time.Hour)}); got != nil {
+		t.Errorf("got %v, want nil for pods without revision", got)
+	}
+	if got := templateTime(nil); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+	rec := metav1.NewTime(now.Add(-3 * time.Hour))
+	if !PredatesTemplate(&rec, RecommendationTimeCheckpoint, templateTime(pods)) {
+		t.Error("expected the recommendation to predate the template")
+	}
+	if PredatesTemplate(nil, "", templateTime(pods)) {
+		t.Error("expected an unknown recommendation time not to predate the template")
+	}
+}
+
+func TestPredatesTemplateWithoutCheckpoints(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// The recommendation was first provided three days ago, and
+	// the template was rolled out since. Without checkpoints, the
+	// recommendation may have been updated after the rollout.
+	v := &vpav1.VerticalPodAutoscaler{
+		Status: vpav1.VerticalPodAutoscalerStatus{
+			Conditions: []vpav1.VerticalPodAutoscalerCondition{{
+				Type:               vpav1.RecommendationProvided,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(now.Add(-72 * time.Hour)),
+			}},
+		},
+	}
+	pod := &corev1.Pod{}
+	pod.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+	pod.Labels = map[string]string{"pod-template-hash": "new"}
+
+	at, src := RecommendationTime(v, nil)
+	if PredatesTemplate(at, src, templateTime([]*corev1.Pod{pod})) {
+		t.Error("expected a recommendation time from the condition not to predate the template")
+	}
+}
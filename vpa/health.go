@@ -0,0 +1,132 @@
+package vpa
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// oomKilledReason is the reason of the termination
+// of a container killed for exceeding its memory.
+const oomKilledReason = "OOMKilled"
+
+// ContainerHealth represents the restarts, OOM kills and
+// warning events of a container across the pods of a target.
+type ContainerHealth struct {
+	Container string `json:"container"`
+	// Restarts is the sum of the restart
+	// counts of the container in the pods.
+	Restarts int32 `json:"restarts"`
+	// OOMKills is the number of pods whose container was
+	// last terminated with an OOMKilled reason, since the
+	// start of the observation window.
+	OOMKills    int          `json:"oomKills"`
+	LastOOMKill *metav1.Time `json:"lastOOMKill,omitempty"`
+	// Events maps the reason of the warning events of the
+	// container, such as BackOff, to their count.
+	Events map[string]int32 `json:"events,omitempty"`
+}
+
+// Health returns the health of the containers of the pods of the
+// controller, sorted by name. The OOM kills and events older than
+// since are ignored. The events are the warning events of the pods
+// of the namespace of the controller.
+func (tc *TargetController) Health(events []*corev1.Event, since time.Time) []*ContainerHealth {
+	return containersHealth(tc.pods, events, since)
+}
+
+func containersHealth(pods []*corev1.Pod, events []*corev1.Event, since time.Time) []*ContainerHealth {
+	health := make(map[string]*ContainerHealth)
+
+	get := func(name string) *ContainerHealth {
+		h, ok := health[name]
+		if !ok {
+			h = &ContainerHealth{Container: name}
+			health[name] = h
+		}
+		return h
+	}
+	podNames := make(map[string]bool, len(pods))
+
+	for _, pod := range pods {
+		podNames[pod.Name] = true
+
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			h := get(cs.Name)
+			h.Restarts += cs.RestartCount
+
+			for _, t := range []*corev1.ContainerStateTerminated{cs.State.Terminated, cs.LastTerminationState.Terminated} {
+				if t == nil || t.Reason != oomKilledReason || t.FinishedAt.Time.Before(since) {
+					continue
+				}
+				h.OOMKills++
+				if h.LastOOMKill == nil || h.LastOOMKill.Before(&t.FinishedAt) {
+					ts := t.FinishedAt
+					h.LastOOMKill = &ts
+				}
+				// The current and last states may
+				// both report the same termination.
+				break
+			}
+		}
+	}
+	for _, e := range events {
+		if e.Type != corev1.EventTypeWarning || e.InvolvedObject.Kind != "Pod" || !podNames[e.InvolvedObject.Name] {
+			continue
+		}
+		if eventTime(e).Before(since) {
+			continue
+		}
+		name, ok := containerNameFromFieldPath(e.InvolvedObject.FieldPath)
+		if !ok {
+			continue
+		}
+		h := get(name)
+		if h.Events == nil {
+			h.Events = make(map[string]int32)
+		}
+		count := e.Count
+		if count == 0 {
+			count = 1
+		}
+		h.Events[e.Reason] += count
+	}
+	ret := make([]*ContainerHealth, 0, len(health))
+	for _, h := range health {
+		ret = append(ret, h)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Container < ret[j].Container
+	})
+	return ret
+}
+
+// eventTime returns the time of the last occurrence of the event.
+func eventTime(e *corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case e.Series != nil:
+		return e.Series.LastObservedTime.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	default:
+		return e.FirstTimestamp.Time
+	}
+}
+
+// containerNameFromFieldPath returns the name of the container
+// referenced by the field path of an event's involved object,
+// such as "spec.containers{app}".
+func containerNameFromFieldPath(path string) (string, bool) {
+	for _, prefix := range []string{"spec.containers{", "spec.initContainers{"} {
+		if strings.HasPrefix(path, prefix) && strings.HasSuffix(path, "}") {
+			return path[len(prefix) : len(path)-1], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,91 @@
+package vpa
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestContainersHealth(t *testing.T) {
+	now := time.Now()
+
+	oom := func(ago time.Duration) corev1.ContainerState {
+		return corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{
+				Reason:     oomKilledReason,
+				FinishedAt: metav1.NewTime(now.Add(-ago)),
+			},
+		}
+	}
+	a := newTestPod("a", time.Hour)
+	a.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{Name: "app", RestartCount: 3, LastTerminationState: oom(time.Hour)},
+		{Name: "proxy", RestartCount: 0},
+	}
+	b := newTestPod("b", time.Hour)
+	b.Status.ContainerStatuses = []corev1.ContainerStatus{
+		// Too old to be considered.
+		{Name: "app", RestartCount: 4, LastTerminationState: oom(48 * time.Hour)},
+	}
+	events := []*corev1.Event{
+		{
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "a", FieldPath: "spec.containers{app}"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Count:          7,
+			LastTimestamp:  metav1.NewTime(now.Add(-time.Minute)),
+		},
+		{
+			// Another pod.
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "z", FieldPath: "spec.containers{app}"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			LastTimestamp:  metav1.NewTime(now.Add(-time.Minute)),
+		},
+		{
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "b", FieldPath: "spec.containers{proxy}"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "Unhealthy",
+			LastTimestamp:  metav1.NewTime(now.Add(-time.Minute)),
+		},
+	}
+	health := containersHealth([]*corev1.Pod{a, b}, events, now.Add(-24*time.Hour))
+
+	if len(health) != 2 {
+		t.Fatalf("got %d containers, want 2", len(health))
+	}
+	app, proxy := health[0], health[1]
+
+	if app.Container != "app" || app.Restarts != 7 || app.OOMKills != 1 {
+		t.Errorf("unexpected health of app: %+v", app)
+	}
+	if app.LastOOMKill == nil || !app.LastOOMKill.Time.Equal(metav1.NewTime(now.Add(-time.Hour)).Time) {
+		t.Errorf("got last OOM kill %v, want %v", app.LastOOMKill, now.Add(-time.Hour))
+	}
+	if app.Events["BackOff"] != 7 {
+		t.Errorf("got %d back-off events, want 7", app.Events["BackOff"])
+	}
+	if proxy.OOMKills != 0 || proxy.Events["Unhealthy"] != 1 {
+		t.Errorf("unexpected health of proxy: %+v", proxy)
+	}
+}
+
+func TestContainerNameFromFieldPath(t *testing.T) {
+	for _, tt := range []struct {
+		path string
+		name string
+		ok   bool
+	}{
+		{"spec.containers{app}", "app", true},
+		{"spec.initContainers{init}", "init", true},
+		{"", "", false},
+		{"spec.volumes{data}", "", false},
+	} {
+		name, ok := containerNameFromFieldPath(tt.path)
+		if name != tt.name || ok != tt.ok {
+			t.Errorf("%q: got %q/%t, want %q/%t", tt.path, name, ok, tt.name, tt.ok)
+		}
+	}
+}
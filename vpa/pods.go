@@ -0,0 +1,210 @@
+package vpa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// RequestsSource represents the source of the requests
+// compared with the recommendations of a VPA.
+type RequestsSource string
+
+// Requests sources.
+const (
+	SourceTemplate  RequestsSource = "template"
+	SourceOldestPod RequestsSource = "oldest"
+	SourceNewestPod RequestsSource = "newest"
+	SourceMajority  RequestsSource = "majority"
+)
+
+// String implements the pflag.Value interface.
+func (rs RequestsSource) String() string { return string(rs) }
+
+// Type implements the pflag.Value interface.
+func (rs *RequestsSource) Type() string { return "string" }
+
+// Set implements the pflag.Value interface.
+func (rs *RequestsSource) Set(s string) error {
+	switch RequestsSource(s) {
+	case SourceTemplate, SourceOldestPod, SourceNewestPod, SourceMajority:
+		*rs = RequestsSource(s)
+		return nil
+	default:
+		return fmt.Errorf("must be one of: %s, %s, %s or %s",
+			SourceTemplate,
+			SourceOldestPod,
+			SourceNewestPod,
+			SourceMajority,
+		)
+	}
+}
+
+// RequestsVariance represents the distribution of the
+// requests of a container across the pods of a target.
+// A resource missing from the requests of some pods has
+// a zero minimum.
+type RequestsVariance struct {
+	Container string              `json:"container"`
+	Pods      int                 `json:"pods"`
+	Min       corev1.ResourceList `json:"min"`
+	Max       corev1.ResourceList `json:"max"`
+	Distinct  int                 `json:"distinct"` // number of distinct sets of requests
+}
+
+// selectPodSpec returns the pod spec used as the source of the
+// requests, and the pod it belongs to, if any. The template spec
+// is returned if the source is the template, or if there are no
+// pods.
+func selectPodSpec(template *corev1.PodSpec, pods []*corev1.Pod, source RequestsSource) (*corev1.PodSpec, *corev1.Pod) {
+	if len(pods) == 0 || source == SourceTemplate {
+		return template, nil
+	}
+	sorted := make([]*corev1.Pod, len(pods))
+	copy(sorted, pods)
+
+	// Sort the pods from the oldest to the newest.
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreationTimestamp.Before(&sorted[j].CreationTimestamp)
+	})
+	var p *corev1.Pod
+
+	switch source {
+	case SourceOldestPod:
+		p = sorted[0]
+	case SourceNewestPod:
+		p = sorted[len(sorted)-1]
+	default:
+		// Pick the requests shared by the most pods. In case
+		// of a tie, the newest pod of the groups is selected.
+		counts := make(map[string]int)
+		best := 0
+		for _, pod := range sorted {
+			k := podRequestsKey(pod)
+			counts[k]++
+			if counts[k] >= best {
+				best = counts[k]
+				p = pod
+			}
+		}
+	}
+	return &p.Spec, p
+}
+
+// podRequestsKey returns a string that represents
+// the requests of all containers of a pod.
+func podRequestsKey(pod *corev1.Pod) string {
+	var sb strings.Builder
+
+	write := func(containers []corev1.Container) {
+		for _, c := range containers {
+			fmt.Fprintf(&sb, "%s=%s;", c.Name, requestsKey(c.Resources.Requests))
+		}
+	}
+	write(pod.Spec.InitContainers)
+	write(pod.Spec.Containers)
+
+	return sb.String()
+}
+
+// requestsKey returns a string that represents the
+// requests of all resources of a container.
+func requestsKey(requests corev1.ResourceList) string {
+	names := make([]corev1.ResourceName, 0, len(requests))
+	for name := range requests {
+		names = append(names, name)
+	}
+	parts := make([]string, 0, len(names))
+	for _, name := range SortResourceNames(names) {
+		q := requests[name]
+		parts = append(parts, fmt.Sprintf("%s:%s", name, q.String()))
+	}
+	return strings.Join(parts, ",")
+}
+
+// RequestsVariance returns the distribution of the requests of
+// each container across the dependent pods of the controller.
+// The containers are sorted by name.
+func (tc *TargetController) RequestsVariance() []RequestsVariance {
+	byName := make(map[string]*RequestsVariance)
+	distinct := make(map[string]map[string]bool)
+
+	add := func(containers []corev1.Container) {
+		for _, c := range containers {
+			rv, ok := byName[c.Name]
+			if !ok {
+				rv = &RequestsVariance{
+					Container: c.Name,
+					Min:       make(corev1.ResourceList),
+					Max:       make(corev1.ResourceList),
+				}
+				byName[c.Name] = rv
+				distinct[c.Name] = make(map[string]bool)
+			}
+			requests := c.Resources.Requests
+
+			for name, q := range requests {
+				if _, ok := rv.Max[name]; !ok && rv.Pods != 0 {
+					// The previous pods don't request the resource.
+					rv.Min[name] = resource.Quantity{Format: q.Format}
+				}
+				if m, ok := rv.Min[name]; !ok || q.Cmp(m) < 0 {
+					rv.Min[name] = q.DeepCopy()
+				}
+				if m, ok := rv.Max[name]; !ok || q.Cmp(m) > 0 {
+					rv.Max[name] = q.DeepCopy()
+				}
+			}
+			for name, q := range rv.Max {
+				if _, ok := requests[name]; !ok {
+					rv.Min[name] = resource.Quantity{Format: q.Format}
+				}
+			}
+			rv.Pods++
+			distinct[c.Name][requestsKey(requests)] = true
+		}
+	}
+	for _, pod := range tc.pods {
+		add(pod.Spec.InitContainers)
+		add(pod.Spec.Containers)
+	}
+	ret := make([]RequestsVariance, 0, len(byName))
+	for name, rv := range byName {
+		rv.Distinct = len(distinct[name])
+		ret = append(ret, *rv)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Container < ret[j].Container
+	})
+	return ret
+}
+
+// HasMixedRequests returns whether the dependent pods of the
+// controller disagree on the requests of their containers,
+// such as during a rollout, or when some pods were updated
+// by the VPA admission controller.
+func (tc *TargetController) HasMixedRequests() bool {
+	for _, rv := range tc.RequestsVariance() {
+		if rv.Distinct > 1 || rv.Pods != len(tc.pods) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pods returns the dependent pods of the controller.
+func (tc *TargetController) Pods() []*corev1.Pod {
+	return tc.pods
+}
+
+// RequestsSource returns the source of the requests, and the
+// name of the pod whose spec is used, if any.
+func (tc *TargetController) RequestsSource() (RequestsSource, string) {
+	if tc.sourcePod == nil {
+		return SourceTemplate, ""
+	}
+	return tc.source, tc.sourcePod.Name
+}
@@ -0,0 +1,103 @@
+package vpa
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestPod(name string, age time.Duration, containers ...corev1.Container) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		},
+		Spec: corev1.PodSpec{Containers: containers},
+	}
+}
+
+func TestSelectPodSpec(t *testing.T) {
+	template := &corev1.PodSpec{
+		Containers: []corev1.Container{newTestContainer("app", "100m", "128Mi")},
+	}
+	pods := []*corev1.Pod{
+		newTestPod("b", 2*time.Hour, newTestContainer("app", "200m", "256Mi")),
+		newTestPod("a", 3*time.Hour, newTestContainer("app", "100m", "128Mi")),
+		newTestPod("c", 1*time.Hour, newTestContainer("app", "200m", "256Mi")),
+		newTestPod("d", 30*time.Minute, newTestContainer("app", "300m", "512Mi")),
+	}
+	for _, tt := range []struct {
+		source RequestsSource
+		pods   []*corev1.Pod
+		want   string
+	}{
+		{SourceTemplate, pods, ""},
+		{SourceOldestPod, pods, "a"},
+		{SourceNewestPod, pods, "d"},
+		{SourceMajority, pods, "c"},
+		{SourceMajority, pods[:2], "b"},
+		{SourceNewestPod, nil, ""},
+	} {
+		spec, pod := selectPodSpec(template, tt.pods, tt.source)
+
+		var got string
+		if pod != nil {
+			got = pod.Name
+			if spec != &pod.Spec {
+				t.Errorf("%s: spec doesn't belong to pod %s", tt.source, got)
+			}
+		} else if spec != template {
+			t.Errorf("%s: expected template spec", tt.source)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got pod %q, want %q", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestTargetControllerRequestsVariance(t *testing.T) {
+	// The proxy of the second pod also requests ephemeral storage.
+	proxy := newTestContainer("proxy", "10m", "32Mi")
+	proxy.Resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse("1Gi")
+
+	tc := &TargetController{
+		pods: []*corev1.Pod{
+			newTestPod("a", time.Hour,
+				newTestContainer("app", "100m", "128Mi"),
+				newTestContainer("proxy", "10m", "32Mi"),
+			),
+			newTestPod("b", time.Hour,
+				newTestContainer("app", "300m", "128Mi"),
+				proxy,
+			),
+		},
+	}
+	if !tc.HasMixedRequests() {
+		t.Error("expected mixed requests")
+	}
+	rvs := tc.RequestsVariance()
+	if len(rvs) != 2 {
+		t.Fatalf("got %d containers, want 2", len(rvs))
+	}
+	app := rvs[0]
+	if app.Container != "app" || app.Pods != 2 || app.Distinct != 2 {
+		t.Errorf("unexpected variance for app: %+v", app)
+	}
+	if app.Min.Cpu().Cmp(resource.MustParse("100m")) != 0 || app.Max.Cpu().Cmp(resource.MustParse("300m")) != 0 {
+		t.Errorf("got cpu min/max %s/%s, want 100m/300m", app.Min.Cpu(), app.Max.Cpu())
+	}
+	p := rvs[1]
+	if p.Distinct != 2 {
+		t.Errorf("got %d distinct requests for proxy, want 2", p.Distinct)
+	}
+	if min, max := p.Min[corev1.ResourceEphemeralStorage], p.Max[corev1.ResourceEphemeralStorage]; !min.IsZero() || max.Cmp(resource.MustParse("1Gi")) != 0 {
+		t.Errorf("got ephemeral-storage min/max %s/%s, want 0/1Gi", &min, &max)
+	}
+	tc.pods = tc.pods[:1]
+	if tc.HasMixedRequests() {
+		t.Error("expected uniform requests")
+	}
+}
@@ -0,0 +1,248 @@
+package vpa
+
+import (
+	"math/big"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+// qosComputeResources are the resources that
+// determine the QoS class of a pod.
+var qosComputeResources = []corev1.ResourceName{
+	corev1.ResourceCPU,
+	corev1.ResourceMemory,
+}
+
+// PodQOSClass returns the QoS class of a pod with the given spec,
+// computed the way the kubelet does. The requests of a container
+// that only declares a limit default to the limit, as they would
+// for a pod created from the spec.
+func PodQOSClass(spec *corev1.PodSpec) corev1.PodQOSClass {
+	requests := make(map[corev1.ResourceName]*resource.Quantity)
+	limits := make(map[corev1.ResourceName]*resource.Quantity)
+
+	guaranteed := true
+
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for _, c := range containers {
+			found := 0
+			for _, name := range qosComputeResources {
+				req, hasReq := c.Resources.Requests[name]
+				lim, hasLim := c.Resources.Limits[name]
+				if !hasReq && hasLim {
+					req, hasReq = lim, true
+				}
+				if hasReq && req.Sign() > 0 {
+					addTo(requests, name, req)
+				}
+				if hasLim && lim.Sign() > 0 {
+					addTo(limits, name, lim)
+					found++
+				}
+			}
+			if found != len(qosComputeResources) {
+				guaranteed = false
+			}
+		}
+	}
+	if len(requests) == 0 && len(limits) == 0 {
+		return corev1.PodQOSBestEffort
+	}
+	if guaranteed {
+		for name, req := range requests {
+			if lim, ok := limits[name]; !ok || lim.Cmp(*req) != 0 {
+				guaranteed = false
+				break
+			}
+		}
+	}
+	if guaranteed && len(requests) == len(limits) {
+		return corev1.PodQOSGuaranteed
+	}
+	return corev1.PodQOSBurstable
+}
+
+func addTo(m map[corev1.ResourceName]*resource.Quantity, name corev1.ResourceName, q resource.Quantity) {
+	if sum, ok := m[name]; ok {
+		sum.Add(q)
+		return
+	}
+	c := q.DeepCopy()
+	m[name] = &c
+}
+
+// QOSClassRank returns the rank of a QoS class, from the lowest
+// priority, BestEffort, to the highest, Guaranteed.
+func QOSClassRank(class corev1.PodQOSClass) int {
+	switch class {
+	case corev1.PodQOSGuaranteed:
+		return 2
+	case corev1.PodQOSBurstable:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// QOSClasses returns the QoS class of the pods of the controller,
+// and the class they would have once the recommendations of the
+// VPA are applied by the admission controller.
+func (tc *TargetController) QOSClasses(v *vpav1.VerticalPodAutoscaler, rt RecommendationType) (current, recommended corev1.PodQOSClass) {
+	if tc.podSpec == nil {
+		return "", ""
+	}
+	return PodQOSClass(tc.podSpec), PodQOSClass(ApplyRecommendations(tc.podSpec, v, rt))
+}
+
+// RecommendedLimits returns the limits of the containers of the
+// controller, by name, and the limits of its pods, once the
+// recommendations of the VPA are applied by the admission
+// controller. The limits of a pod are computed the way the
+// LimitRanger does: the sum of the limits of its containers,
+// if all of them set one, or the highest limit of its init
+// containers, if greater.
+func (tc *TargetController) RecommendedLimits(v *vpav1.VerticalPodAutoscaler, rt RecommendationType) (containers map[string]corev1.ResourceList, pod corev1.ResourceList) {
+	if tc.podSpec == nil {
+		return nil, nil
+	}
+	spec := ApplyRecommendations(tc.podSpec, v, rt)
+
+	containers = make(map[string]corev1.ResourceList)
+	for _, cs := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for _, c := range cs {
+			containers[c.Name] = c.Resources.Limits
+		}
+	}
+	return containers, podLimits(spec)
+}
+
+func podLimits(spec *corev1.PodSpec) corev1.ResourceList {
+	ret := corev1.ResourceList{}
+
+	for i, c := range spec.Containers {
+		for name, q := range c.Resources.Limits {
+			if i == 0 {
+				ret[name] = q.DeepCopy()
+				continue
+			}
+			if sum, ok := ret[name]; ok {
+				sum.Add(q)
+				ret[name] = sum
+			}
+		}
+		// A resource without a limit in one of the
+		// containers is unbounded for the pod.
+		for name := range ret {
+			if _, ok := c.Resources.Limits[name]; !ok {
+				delete(ret, name)
+			}
+		}
+	}
+	for _, c := range spec.InitContainers {
+		for name, q := range c.Resources.Limits {
+			if cur, ok := ret[name]; ok && q.Cmp(cur) > 0 {
+				ret[name] = q.DeepCopy()
+			}
+		}
+	}
+	return ret
+}
+
+// ApplyRecommendations returns a copy of the pod spec with the
+// recommendations of the VPA applied to the requests of the
+// containers, as the VPA admission controller would. The limits
+// are scaled proportionally to the requests, unless the policy
+// of the container only controls its requests.
+func ApplyRecommendations(spec *corev1.PodSpec, v *vpav1.VerticalPodAutoscaler, rt RecommendationType) *corev1.PodSpec {
+	ret := spec.DeepCopy()
+
+	if v == nil || v.Status.Recommendation == nil {
+		return ret
+	}
+	recs := make(map[string]corev1.ResourceList)
+	for _, cr := range v.Status.Recommendation.ContainerRecommendations {
+		recs[cr.ContainerName] = recommendationsByType(cr, rt)
+	}
+	for _, containers := range [][]corev1.Container{ret.InitContainers, ret.Containers} {
+		for i := range containers {
+			c := &containers[i]
+
+			rec, ok := recs[c.Name]
+			if !ok {
+				continue
+			}
+			policy := containerPolicy(v, c.Name)
+			if policy != nil && policy.Mode != nil && *policy.Mode == vpav1.ContainerScalingModeOff {
+				continue
+			}
+			requestsOnly := policy != nil && policy.ControlledValues != nil &&
+				*policy.ControlledValues == vpav1.ContainerControlledValuesRequestsOnly
+
+			for name, q := range rec {
+				if policy != nil && policy.ControlledResources != nil && !hasResourceName(*policy.ControlledResources, name) {
+					continue
+				}
+				req, hasReq := c.Resources.Requests[name]
+				lim, hasLim := c.Resources.Limits[name]
+				if !hasReq && hasLim {
+					req = lim
+				}
+				if c.Resources.Requests == nil {
+					c.Resources.Requests = corev1.ResourceList{}
+				}
+				c.Resources.Requests[name] = q.DeepCopy()
+
+				if hasLim && !requestsOnly && req.Sign() > 0 {
+					c.Resources.Limits[name] = scaleQuantity(lim, q, req)
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// containerPolicy returns the resource policy of the named
+// container, or the default policy of the VPA, if any.
+func containerPolicy(v *vpav1.VerticalPodAutoscaler, name string) *vpav1.ContainerResourcePolicy {
+	if v.Spec.ResourcePolicy == nil {
+		return nil
+	}
+	var def *vpav1.ContainerResourcePolicy
+
+	for i, p := range v.Spec.ResourcePolicy.ContainerPolicies {
+		switch p.ContainerName {
+		case name:
+			return &v.Spec.ResourcePolicy.ContainerPolicies[i]
+		case vpav1.DefaultContainerResourcePolicy:
+			def = &v.Spec.ResourcePolicy.ContainerPolicies[i]
+		}
+	}
+	return def
+}
+
+func hasResourceName(names []corev1.ResourceName, name corev1.ResourceName) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// scaleQuantity returns q multiplied by num/den, rounded up,
+// as the VPA admission controller scales the limits.
+func scaleQuantity(q, num, den resource.Quantity) resource.Quantity {
+	v := new(big.Int).Mul(big.NewInt(q.MilliValue()), big.NewInt(num.MilliValue()))
+	d := big.NewInt(den.MilliValue())
+
+	// Ceiling of the division.
+	v.Add(v, new(big.Int).Sub(d, big.NewInt(1)))
+	v.Quo(v, d)
+
+	if !v.IsInt64() {
+		return q.DeepCopy()
+	}
+	return *resource.NewMilliQuantity(v.Int64(), q.Format)
+}
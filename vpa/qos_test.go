@@ -0,0 +1,155 @@
+package vpa
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+func newTestLimitedContainer(name, cpu, mem, cpuLimit, memLimit string) corev1.Container {
+	c := newTestContainer(name, cpu, mem)
+	c.Resources.Limits = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpuLimit),
+		corev1.ResourceMemory: resource.MustParse(memLimit),
+	}
+	return c
+}
+
+func TestPodQOSClass(t *testing.T) {
+	limitsOnly := corev1.Container{
+		Name: "app",
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+	}
+	for _, tt := range []struct {
+		name string
+		spec corev1.PodSpec
+		want corev1.PodQOSClass
+	}{
+		{"best-effort", corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}, corev1.PodQOSBestEffort},
+		{"burstable", corev1.PodSpec{Containers: []corev1.Container{newTestContainer("app", "100m", "128Mi")}}, corev1.PodQOSBurstable},
+		{"guaranteed", corev1.PodSpec{Containers: []corev1.Container{newTestLimitedContainer("app", "1", "1Gi", "1", "1Gi")}}, corev1.PodQOSGuaranteed},
+		{"limits only", corev1.PodSpec{Containers: []corev1.Container{limitsOnly}}, corev1.PodQOSGuaranteed},
+		{
+			"init container without limits",
+			corev1.PodSpec{
+				InitContainers: []corev1.Container{newTestContainer("init", "100m", "128Mi")},
+				Containers:     []corev1.Container{newTestLimitedContainer("app", "1", "1Gi", "1", "1Gi")},
+			},
+			corev1.PodQOSBurstable,
+		},
+	} {
+		if got := PodQOSClass(&tt.spec); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTargetControllerQOSClasses(t *testing.T) {
+	newVPA := func(controlled *vpav1.ContainerControlledValues) *vpav1.VerticalPodAutoscaler {
+		v := &vpav1.VerticalPodAutoscaler{
+			Status: vpav1.VerticalPodAutoscalerStatus{
+				Recommendation: &vpav1.RecommendedPodResources{
+					ContainerRecommendations: []vpav1.RecommendedContainerResources{{
+						ContainerName: "app",
+						Target: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("250m"),
+							corev1.ResourceMemory: resource.MustParse("512Mi"),
+						},
+					}},
+				},
+			},
+		}
+		if controlled != nil {
+			v.Spec.ResourcePolicy = &vpav1.PodResourcePolicy{
+				ContainerPolicies: []vpav1.ContainerResourcePolicy{{
+					ContainerName:    vpav1.DefaultContainerResourcePolicy,
+					ControlledValues: controlled,
+				}},
+			}
+		}
+		return v
+	}
+	requestsOnly := vpav1.ContainerControlledValuesRequestsOnly
+
+	for _, tt := range []struct {
+		name        string
+		container   corev1.Container
+		v           *vpav1.VerticalPodAutoscaler
+		current     corev1.PodQOSClass
+		recommended corev1.PodQOSClass
+	}{
+		{
+			"limits scaled",
+			newTestLimitedContainer("app", "1", "1Gi", "1", "1Gi"),
+			newVPA(nil),
+			corev1.PodQOSGuaranteed, corev1.PodQOSGuaranteed,
+		},
+		{
+			"requests only",
+			newTestLimitedContainer("app", "1", "1Gi", "1", "1Gi"),
+			newVPA(&requestsOnly),
+			corev1.PodQOSGuaranteed, corev1.PodQOSBurstable,
+		},
+		{
+			"best-effort",
+			corev1.Container{Name: "app"},
+			newVPA(nil),
+			corev1.PodQOSBestEffort, corev1.PodQOSBurstable,
+		},
+	} {
+		tc := &TargetController{
+			podSpec: &corev1.PodSpec{Containers: []corev1.Container{tt.container}},
+		}
+		current, recommended := tc.QOSClasses(tt.v, RecommendationTarget)
+		if current != tt.current || recommended != tt.recommended {
+			t.Errorf("%s: got %s -> %s, want %s -> %s", tt.name, current, recommended, tt.current, tt.recommended)
+		}
+		// The pod spec of the controller must not be mutated.
+		if q := tc.podSpec.Containers[0].Resources.Requests.Cpu(); !tt.container.Resources.Requests.Cpu().Equal(*q) {
+			t.Errorf("%s: pod spec mutated", tt.name)
+		}
+	}
+}
+
+func TestPodLimits(t *testing.T) {
+	sidecar := newTestContainer("sidecar", "100m", "64Mi")
+	sidecar.Resources.Limits = corev1.ResourceList{
+		corev1.ResourceCPU: resource.MustParse("200m"),
+	}
+	spec := &corev1.PodSpec{
+		InitContainers: []corev1.Container{
+			newTestLimitedContainer("init", "1", "1Gi", "2", "2Gi"),
+		},
+		Containers: []corev1.Container{
+			newTestLimitedContainer("app", "500m", "512Mi", "1", "1Gi"),
+			sidecar,
+		},
+	}
+	got := podLimits(spec)
+
+	// The init container is higher than the sum of the containers
+	// for CPU, and the memory is unbounded, as the sidecar has no
+	// memory limit.
+	if len(got) != 1 || got.Cpu().Cmp(resource.MustParse("2")) != 0 {
+		t.Errorf("got %v, want cpu: 2", got)
+	}
+	spec.InitContainers[0].Resources.Limits[corev1.ResourceCPU] = resource.MustParse("1")
+
+	if got := podLimits(spec); got.Cpu().Cmp(resource.MustParse("1200m")) != 0 {
+		t.Errorf("got %v, want cpu: 1200m", got)
+	}
+}
+
+func TestScaleQuantity(t *testing.T) {
+	got := scaleQuantity(resource.MustParse("2Gi"), resource.MustParse("384Mi"), resource.MustParse("1Gi"))
+	if want := resource.MustParse("768Mi"); got.Cmp(want) != 0 {
+		t.Errorf("got %s, want %s", got.String(), want.String())
+	}
+}
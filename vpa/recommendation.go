@@ -50,20 +50,24 @@ func TotalRecommendations(vpa *vpav1.VerticalPodAutoscaler, rt RecommendationTyp
 		return ResourceQuantities{}
 	}
 	var cpu, mem resource.Quantity
+
+	total := ResourceQuantities{
+		CPU:    &cpu,
+		Memory: &mem,
+	}
 	for _, cr := range vpa.Status.Recommendation.ContainerRecommendations {
 		rec := recommendationsByType(cr, rt)
 
-		if c := rec.Cpu(); c != nil {
-			cpu.Add(*c)
-		}
-		if m := rec.Memory(); m != nil {
-			mem.Add(*m)
+		for name, q := range rec {
+			sum := total.Get(name)
+			if sum == nil {
+				sum = &resource.Quantity{}
+				total.Set(name, sum)
+			}
+			sum.Add(q)
 		}
 	}
-	return ResourceQuantities{
-		CPU:    &cpu,
-		Memory: &mem,
-	}
+	return total
 }
 
 func recommendationsByType(rec vpav1.RecommendedContainerResources, rt RecommendationType) v1.ResourceList {
@@ -79,3 +83,9 @@ func recommendationsByType(rec vpav1.RecommendedContainerResources, rt Recommend
 	}
 	return rec.Target
 }
+
+// ContainerRecommendations returns the resource recommendations
+// of the given type for a single container.
+func ContainerRecommendations(rec vpav1.RecommendedContainerResources, rt RecommendationType) ResourceQuantities {
+	return NewResourceQuantities(recommendationsByType(rec, rt))
+}
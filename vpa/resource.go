@@ -2,16 +2,122 @@ package vpa
 
 import (
 	"math"
+	"sort"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
 
-// ResourceQuantities is a pair of resource quantities
+// ResourceQuantities is a set of resource quantities
 // that can represent the recommendations of a VerticalPodAutoscaler
 // of the requests of a pod's container.
 type ResourceQuantities struct {
 	CPU    *resource.Quantity
 	Memory *resource.Quantity
+	// Others holds the quantities of the resources other than
+	// CPU and memory, such as ephemeral-storage, or extended
+	// resources emitted by custom recommenders.
+	Others map[corev1.ResourceName]*resource.Quantity
+}
+
+// NewResourceQuantities returns the quantities of the given list.
+// The CPU and memory quantities are always set, and are zero if
+// the list doesn't have them.
+func NewResourceQuantities(rl corev1.ResourceList) ResourceQuantities {
+	rq := ResourceQuantities{
+		CPU:    rl.Cpu(),
+		Memory: rl.Memory(),
+	}
+	for name, q := range rl {
+		if name == corev1.ResourceCPU || name == corev1.ResourceMemory {
+			continue
+		}
+		q := q.DeepCopy()
+		rq.Set(name, &q)
+	}
+	return rq
+}
+
+// Get returns the quantity of the named resource, if any.
+func (rq ResourceQuantities) Get(name corev1.ResourceName) *resource.Quantity {
+	switch name {
+	case corev1.ResourceCPU:
+		return rq.CPU
+	case corev1.ResourceMemory:
+		return rq.Memory
+	default:
+		return rq.Others[name]
+	}
+}
+
+// Set sets the quantity of the named resource.
+func (rq *ResourceQuantities) Set(name corev1.ResourceName, q *resource.Quantity) {
+	switch name {
+	case corev1.ResourceCPU:
+		rq.CPU = q
+	case corev1.ResourceMemory:
+		rq.Memory = q
+	default:
+		if rq.Others == nil {
+			rq.Others = make(map[corev1.ResourceName]*resource.Quantity)
+		}
+		rq.Others[name] = q
+	}
+}
+
+// Names returns the names of the resources that have a quantity.
+// CPU and memory come first, followed by the other resources in
+// lexical order.
+func (rq ResourceQuantities) Names() []corev1.ResourceName {
+	var names []corev1.ResourceName
+	if rq.CPU != nil {
+		names = append(names, corev1.ResourceCPU)
+	}
+	if rq.Memory != nil {
+		names = append(names, corev1.ResourceMemory)
+	}
+	return append(names, SortResourceNames(keys(rq.Others))...)
+}
+
+// SortResourceNames sorts the resource names in place, with
+// CPU and memory first, and returns the slice.
+func SortResourceNames(names []corev1.ResourceName) []corev1.ResourceName {
+	rank := func(n corev1.ResourceName) int {
+		switch n {
+		case corev1.ResourceCPU:
+			return 0
+		case corev1.ResourceMemory:
+			return 1
+		}
+		return 2
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := rank(names[i]), rank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// IsBytesResource returns whether the quantities of the named
+// resource represent a number of bytes.
+func IsBytesResource(name corev1.ResourceName) bool {
+	switch name {
+	case corev1.ResourceMemory, corev1.ResourceEphemeralStorage, corev1.ResourceStorage:
+		return true
+	}
+	return strings.HasPrefix(string(name), corev1.ResourceHugePagesPrefix)
+}
+
+func keys(m map[corev1.ResourceName]*resource.Quantity) []corev1.ResourceName {
+	ret := make([]corev1.ResourceName, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	return ret
 }
 
 // DiffQuantitiesAsPercent return the difference between two
@@ -29,3 +135,15 @@ func DiffQuantitiesAsPercent(req, rec *resource.Quantity) *float64 {
 
 	return &p
 }
+
+// DiffResourcesAsPercent returns the difference between the
+// requests and the recommendations of each requested resource,
+// as computed by DiffQuantitiesAsPercent.
+func DiffResourcesAsPercent(req, rec ResourceQuantities) map[corev1.ResourceName]*float64 {
+	ret := make(map[corev1.ResourceName]*float64)
+
+	for _, name := range req.Names() {
+		ret[name] = DiffQuantitiesAsPercent(req.Get(name), rec.Get(name))
+	}
+	return ret
+}
@@ -1,8 +1,10 @@
 package vpa
 
 import (
+	"reflect"
 	"testing"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/utils/pointer"
 )
@@ -74,3 +76,50 @@ func TestDiffQuantitiesAsPercent(t *testing.T) {
 		}
 	}
 }
+
+func TestResourceQuantities(t *testing.T) {
+	rq := NewResourceQuantities(corev1.ResourceList{
+		corev1.ResourceMemory:           resource.MustParse("128Mi"),
+		corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+		"example.com/gpu":               resource.MustParse("1"),
+	})
+	if rq.CPU == nil || !rq.CPU.IsZero() {
+		t.Errorf("expected zero cpu quantity, got %v", rq.CPU)
+	}
+	want := []corev1.ResourceName{
+		corev1.ResourceCPU,
+		corev1.ResourceMemory,
+		corev1.ResourceEphemeralStorage,
+		"example.com/gpu",
+	}
+	if got := rq.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got names %v, want %v", got, want)
+	}
+	if q := rq.Get(corev1.ResourceEphemeralStorage); q == nil || q.Cmp(resource.MustParse("1Gi")) != 0 {
+		t.Errorf("got ephemeral-storage %v, want 1Gi", q)
+	}
+	rec := ResourceQuantities{}
+	rec.Set(corev1.ResourceEphemeralStorage, resource.NewQuantity(512*1024*1024, resource.BinarySI))
+
+	diffs := DiffResourcesAsPercent(rq, rec)
+	if p := diffs[corev1.ResourceEphemeralStorage]; p == nil || *p != 100 {
+		t.Errorf("got ephemeral-storage difference %v, want 100", p)
+	}
+	if p, ok := diffs["example.com/gpu"]; !ok || p != nil {
+		t.Errorf("expected nil difference for resource without recommendation")
+	}
+	if p, ok := diffs[corev1.ResourceMemory]; !ok || p != nil {
+		t.Errorf("expected nil memory difference without recommendation")
+	}
+	for name, want := range map[corev1.ResourceName]bool{
+		corev1.ResourceCPU:              false,
+		corev1.ResourceMemory:           true,
+		corev1.ResourceEphemeralStorage: true,
+		"hugepages-2Mi":                 true,
+		"example.com/gpu":               false,
+	} {
+		if got := IsBytesResource(name); got != want {
+			t.Errorf("IsBytesResource(%s): got %t, want %t", name, got, want)
+		}
+	}
+}
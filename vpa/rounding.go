@@ -0,0 +1,163 @@
+package vpa
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ResourceRounding represents the rounding strategy of a resource.
+// If both are set, the quantity is rounded up to the next power of
+// two, and then to the step.
+type ResourceRounding struct {
+	// Step rounds up the quantity to the next multiple
+	// of the step, such as 50m of CPU or 64Mi of memory.
+	Step *resource.Quantity `json:"step,omitempty"`
+	// PowerOfTwo rounds up the quantity to the next power of two,
+	// expressed in bytes for the resources measured in bytes, and
+	// in millis otherwise.
+	PowerOfTwo bool `json:"powerOfTwo,omitempty"`
+}
+
+// RoundingRule represents the rounding strategies applied
+// to the recommendations of the VPA resources it matches.
+type RoundingRule struct {
+	// RuleMatcher selects the VPA resources
+	// matched by the rule.
+	RuleMatcher `json:",inline"`
+	// Headroom is a percentage added on top of the
+	// recommendations before they are rounded.
+	Headroom float64 `json:"headroom,omitempty"`
+	// Resources maps the name of a resource
+	// to its rounding strategy.
+	Resources map[corev1.ResourceName]ResourceRounding `json:"resources,omitempty"`
+}
+
+// DefaultRoundingRule returns the rounding rule used when
+// no rule is configured, which rounds up CPU to 50m and
+// memory to 64Mi.
+func DefaultRoundingRule() RoundingRule {
+	cpu, mem := resource.MustParse("50m"), resource.MustParse("64Mi")
+
+	return RoundingRule{
+		Resources: map[corev1.ResourceName]ResourceRounding{
+			corev1.ResourceCPU:    {Step: &cpu},
+			corev1.ResourceMemory: {Step: &mem},
+		},
+	}
+}
+
+// Rounder rounds the recommendations of VPA resources
+// according to the first rule that matches them.
+type Rounder struct {
+	Default RoundingRule   `json:"default"`
+	Rules   []RoundingRule `json:"rules,omitempty"`
+}
+
+// Validate checks the rules of the rounder and
+// parses their label selectors.
+func (r *Rounder) Validate() error {
+	if err := r.Default.validate(); err != nil {
+		return fmt.Errorf("invalid default rule: %w", err)
+	}
+	for i := range r.Rules {
+		if err := r.Rules[i].validate(); err != nil {
+			return fmt.Errorf("invalid rule #%d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (rr *RoundingRule) validate() error {
+	if rr.Headroom < 0 {
+		return fmt.Errorf("headroom must be positive")
+	}
+	if err := rr.RuleMatcher.validate(); err != nil {
+		return err
+	}
+	for name, rnd := range rr.Resources {
+		if rnd.Step != nil && rnd.Step.Sign() <= 0 {
+			return fmt.Errorf("step of resource %s must be strictly positive", name)
+		}
+	}
+	return nil
+}
+
+// RuleFor returns the first rule that matches a VPA resource with
+// the given namespace and labels, and whose target has the given
+// kind, or the default rule. The rounder must have been validated
+// beforehand.
+func (r *Rounder) RuleFor(namespace string, lbls map[string]string, kind string) *RoundingRule {
+	for i := range r.Rules {
+		if r.Rules[i].matches(namespace, lbls, kind) {
+			return &r.Rules[i]
+		}
+	}
+	return &r.Default
+}
+
+// Round returns the quantities with the headroom of the
+// rule added, and rounded according to the strategy of
+// each resource.
+func (rr *RoundingRule) Round(rq ResourceQuantities) ResourceQuantities {
+	var ret ResourceQuantities
+
+	for _, name := range rq.Names() {
+		ret.Set(name, rr.RoundQuantity(name, rq.Get(name)))
+	}
+	return ret
+}
+
+// RoundQuantity returns the quantity of the named resource
+// with the headroom of the rule added, and rounded according
+// to the strategy of the resource.
+func (rr *RoundingRule) RoundQuantity(name corev1.ResourceName, q *resource.Quantity) *resource.Quantity {
+	if q == nil || q.IsZero() {
+		return q
+	}
+	// The computations are done in millis, to
+	// keep the precision of CPU quantities.
+	v := q.MilliValue()
+	if rr.Headroom > 0 {
+		v = int64(math.Ceil(float64(v) * (1 + rr.Headroom/100)))
+	}
+	format := q.Format
+	rnd := rr.Resources[name]
+
+	if rnd.PowerOfTwo {
+		unit := int64(1)
+		if IsBytesResource(name) {
+			unit = 1000
+			format = resource.BinarySI
+		}
+		v = nextPowerOfTwo(ceilDiv(v, unit)) * unit
+	}
+	if rnd.Step != nil {
+		step := rnd.Step.MilliValue()
+		v = ceilDiv(v, step) * step
+		format = rnd.Step.Format
+	}
+	// Bytes can't be fractional, so the quantities of
+	// byte resources are rounded up to a whole byte.
+	if IsBytesResource(name) {
+		v = ceilDiv(v, 1000) * 1000
+	}
+	if v%1000 == 0 {
+		return resource.NewQuantity(v/1000, format)
+	}
+	return resource.NewMilliQuantity(v, resource.DecimalSI)
+}
+
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+func nextPowerOfTwo(v int64) int64 {
+	if v <= 1 {
+		return 1
+	}
+	return 1 << (64 - bits.LeadingZeros64(uint64(v-1)))
+}
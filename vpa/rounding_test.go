@@ -0,0 +1,79 @@
+package vpa
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRoundingRuleRoundQuantity(t *testing.T) {
+	step := func(s string) ResourceRounding {
+		q := resource.MustParse(s)
+		return ResourceRounding{Step: &q}
+	}
+	for _, tt := range []struct {
+		rule RoundingRule
+		name corev1.ResourceName
+		in   string
+		want string
+	}{
+		{DefaultRoundingRule(), corev1.ResourceCPU, "587m", "600m"},
+		{DefaultRoundingRule(), corev1.ResourceCPU, "960m", "1"},
+		{DefaultRoundingRule(), corev1.ResourceMemory, "1471026299", "1408Mi"},
+		{DefaultRoundingRule(), corev1.ResourceMemory, "262144k", "256Mi"},
+		{DefaultRoundingRule(), corev1.ResourceEphemeralStorage, "1Gi", "1Gi"},
+		{RoundingRule{Headroom: 20}, corev1.ResourceCPU, "500m", "600m"},
+		{RoundingRule{Headroom: 15}, corev1.ResourceMemory, "1Gi", "1234803098"},
+		{RoundingRule{Headroom: 50}, corev1.ResourceMemory, "1Gi", "1536Mi"},
+		{RoundingRule{Headroom: 10, Resources: map[corev1.ResourceName]ResourceRounding{
+			corev1.ResourceCPU: step("100m"),
+		}}, corev1.ResourceCPU, "587m", "700m"},
+		{RoundingRule{Resources: map[corev1.ResourceName]ResourceRounding{
+			corev1.ResourceMemory: {PowerOfTwo: true},
+		}}, corev1.ResourceMemory, "1.37Gi", "2Gi"},
+		{RoundingRule{Resources: map[corev1.ResourceName]ResourceRounding{
+			corev1.ResourceCPU: {PowerOfTwo: true},
+		}}, corev1.ResourceCPU, "300m", "512m"},
+	} {
+		q := resource.MustParse(tt.in)
+		got := tt.rule.RoundQuantity(tt.name, &q)
+
+		if want := resource.MustParse(tt.want); got.Cmp(want) != 0 || got.String() != tt.want {
+			t.Errorf("%s %s: got %s, want %s", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRounderRuleFor(t *testing.T) {
+	r := &Rounder{
+		Default: DefaultRoundingRule(),
+		Rules: []RoundingRule{
+			{RuleMatcher: RuleMatcher{Namespaces: []string{"batch-*"}}, Headroom: 25},
+			{RuleMatcher: RuleMatcher{Selector: "tier=critical"}, Headroom: 50},
+			{RuleMatcher: RuleMatcher{Kinds: []string{"statefulset"}}, Headroom: 10},
+		},
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		namespace string
+		labels    map[string]string
+		kind      string
+		want      float64
+	}{
+		{"batch-etl", nil, "Deployment", 25},
+		{"payments", map[string]string{"tier": "critical"}, "Deployment", 50},
+		{"payments", nil, "StatefulSet", 10},
+		{"payments", nil, "Deployment", 0},
+	} {
+		if got := r.RuleFor(tt.namespace, tt.labels, tt.kind).Headroom; got != tt.want {
+			t.Errorf("%s %v: got headroom %v, want %v", tt.namespace, tt.labels, got, tt.want)
+		}
+	}
+	r.Rules = append(r.Rules, RoundingRule{RuleMatcher: RuleMatcher{Selector: "tier in (a"}})
+	if err := r.Validate(); err == nil {
+		t.Error("expected invalid selector error")
+	}
+}
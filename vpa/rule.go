@@ -0,0 +1,77 @@
+package vpa
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// RuleMatcher represents the VPA resources matched by a
+// rule, from their namespace, their labels and the kind
+// of their target.
+type RuleMatcher struct {
+	// Namespaces is a list of glob patterns of the namespaces
+	// of the VPA resources matched by the rule. An empty list
+	// matches all namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Selector is a label selector of the VPA resources
+	// matched by the rule.
+	Selector string `json:"selector,omitempty"`
+	// Kinds is a list of the kinds of the targets of the VPA
+	// resources matched by the rule, such as Deployment. An
+	// empty list matches all kinds.
+	Kinds []string `json:"kinds,omitempty"`
+
+	selector labels.Selector
+}
+
+// validate checks the namespace patterns of
+// the matcher and parses its label selector.
+func (rm *RuleMatcher) validate() error {
+	for _, ns := range rm.Namespaces {
+		if _, err := path.Match(ns, ""); err != nil {
+			return fmt.Errorf("invalid namespace pattern %q: %w", ns, err)
+		}
+	}
+	rm.selector = labels.Everything()
+	if rm.Selector != "" {
+		sel, err := labels.Parse(rm.Selector)
+		if err != nil {
+			return fmt.Errorf("invalid selector: %w", err)
+		}
+		rm.selector = sel
+	}
+	return nil
+}
+
+// matches returns whether a VPA resource with the given
+// namespace and labels, and whose target has the given
+// kind, is matched. The kinds are compared without case.
+func (rm *RuleMatcher) matches(namespace string, lbls map[string]string, kind string) bool {
+	if rm.selector != nil && !rm.selector.Matches(labels.Set(lbls)) {
+		return false
+	}
+	if len(rm.Kinds) != 0 {
+		found := false
+		for _, k := range rm.Kinds {
+			if strings.EqualFold(k, kind) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(rm.Namespaces) == 0 {
+		return true
+	}
+	for _, pattern := range rm.Namespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -3,7 +3,6 @@ package vpa
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"strings"
 
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
@@ -13,6 +12,7 @@ import (
 	unstructuredv1 "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
 
 	"github.com/wI2L/kubectl-vpa-recommendation/client"
 )
@@ -32,6 +32,23 @@ const (
 	ro     wellKnownControllerKind = "Rollout"
 )
 
+// ContainerType represents the type of a container of a pod.
+type ContainerType string
+
+// Container types.
+const (
+	ContainerRegular ContainerType = "container"
+	ContainerInit    ContainerType = "init"
+	ContainerSidecar ContainerType = "sidecar" // restartable init container
+)
+
+// PodContainer represents a container of a pod and its requests.
+type PodContainer struct {
+	Name     string
+	Type     ContainerType
+	Requests ResourceQuantities
+}
+
 // TargetController abstract a scalable controller
 // resource targeted by a VerticalPodAutoscaler.
 type TargetController struct {
@@ -41,12 +58,18 @@ type TargetController struct {
 	controllerKind   wellKnownControllerKind
 	controllerObj    *unstructuredv1.Unstructured
 	podSpec          *corev1.PodSpec
+	templateSpec     *corev1.PodSpec
+	sidecars         map[string]bool
+	pods             []*corev1.Pod
+	source           RequestsSource
+	sourcePod        *corev1.Pod
+	selector         string
 }
 
 // NewTargetController resolves the target of a VPA resource.
-func NewTargetController(c client.Interface, ref *autoscalingv1.CrossVersionObjectReference, namespace string) (*TargetController, error) {
-	ctx := context.Background()
-
+// The source determines which pod spec the requests are read
+// from when the dependent pods of the target disagree.
+func NewTargetController(ctx context.Context, c client.Interface, ref *autoscalingv1.CrossVersionObjectReference, namespace string, source RequestsSource) (*TargetController, error) {
 	obj, err := c.GetVPATarget(ctx, ref, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("cannot fetch VPa target: %w", err)
@@ -69,11 +92,18 @@ func NewTargetController(c client.Interface, ref *autoscalingv1.CrossVersionObje
 		GroupVersionKind: obj.GetObjectKind().GroupVersionKind(),
 		controllerKind:   wellKnownControllerKind(kind),
 		controllerObj:    obj,
+		source:           source,
 	}
 	tc.podSpec, err = resolvePodSpec(obj)
 	if err != nil {
 		return nil, err
 	}
+	tc.templateSpec = tc.podSpec
+
+	tc.sidecars, err = resolveSidecarNames(obj)
+	if err != nil {
+		return nil, err
+	}
 	labelSelector, err := resolveLabelSelector(obj)
 	if err != nil {
 		return nil, err
@@ -87,7 +117,10 @@ func NewTargetController(c client.Interface, ref *autoscalingv1.CrossVersionObje
 	// could change the spec of pods to set default resource requests and
 	// limits. To ensure that we have a reliable comparison source, we have
 	// no choice but to list the pods and find those who are dependents of
-	// the controller to get the most up-to-date spec.
+	// the controller to get the most up-to-date spec. Pods of the same
+	// controller may disagree, for example during a rollout, or when some
+	// of them were updated by the VPA admission controller, so the spec
+	// is chosen according to the requests source.
 	m, _, err := unstructuredv1.NestedMap(obj.Object, "metadata")
 	if err != nil {
 		return nil, err
@@ -98,48 +131,199 @@ func NewTargetController(c client.Interface, ref *autoscalingv1.CrossVersionObje
 	if err := conv.FromUnstructured(m, &meta); err != nil {
 		return nil, err
 	}
-	pods, err := c.ListDependentPods(context.Background(), meta, selector.String())
+	tc.selector = selector.String()
+
+	pods, err := c.ListDependentPods(ctx, meta, tc.selector)
 	if err != nil {
 		return nil, err
 	}
-	if len(pods) != 0 {
-		p := pods[0]
-		if !reflect.DeepEqual(p.Spec.Containers, tc.podSpec.Containers) {
-			tc.podSpec = &p.Spec
+	tc.pods = pods
+	tc.podSpec, tc.sourcePod = selectPodSpec(tc.podSpec, pods, source)
+
+	return tc, nil
+}
+
+// Containers returns the containers of the pod spec of the
+// controller. The init and sidecar containers are returned
+// first, in their declaration order, followed by the regular
+// containers.
+func (tc *TargetController) Containers() []PodContainer {
+	ret := make([]PodContainer, 0, len(tc.podSpec.InitContainers)+len(tc.podSpec.Containers))
+
+	for _, c := range tc.podSpec.InitContainers {
+		typ := ContainerInit
+		if tc.sidecars[c.Name] {
+			typ = ContainerSidecar
 		}
+		ret = append(ret, newPodContainer(c, typ))
+	}
+	for _, c := range tc.podSpec.Containers {
+		ret = append(ret, newPodContainer(c, ContainerRegular))
+	}
+	return ret
+}
+
+func newPodContainer(c corev1.Container, typ ContainerType) PodContainer {
+	return PodContainer{
+		Name:     c.Name,
+		Type:     typ,
+		Requests: NewResourceQuantities(c.Resources.Requests),
 	}
-	return tc, nil
 }
 
 // GetContainerRequests returns the resource requests of a container.
 func (tc *TargetController) GetContainerRequests(name string) ResourceQuantities {
-	for _, c := range tc.podSpec.Containers {
+	for _, c := range tc.Containers() {
 		if c.Name == name {
-			return ResourceQuantities{
-				CPU:    c.Resources.Requests.Cpu(),
-				Memory: c.Resources.Requests.Memory(),
+			return c.Requests
+		}
+	}
+	return ResourceQuantities{}
+}
+
+// GetTemplateContainerRequests returns the resource requests of
+// a container as declared in the pod template of the controller,
+// before any mutation by admission controllers.
+func (tc *TargetController) GetTemplateContainerRequests(name string) ResourceQuantities {
+	if tc.templateSpec == nil {
+		return ResourceQuantities{}
+	}
+	for _, containers := range [][]corev1.Container{tc.templateSpec.InitContainers, tc.templateSpec.Containers} {
+		for _, c := range containers {
+			if c.Name == name {
+				return newPodContainer(c, ContainerRegular).Requests
 			}
 		}
 	}
 	return ResourceQuantities{}
 }
 
-// GetRequests returns the resource requests defined by the
-// pod spec of the controller, which is the sum of all resource
-// quantities for each container declared by the spec.
+// GetContainerType returns the type of container, and
+// whether a container with this name exists in the spec.
+func (tc *TargetController) GetContainerType(name string) (ContainerType, bool) {
+	for _, c := range tc.Containers() {
+		if c.Name == name {
+			return c.Type, true
+		}
+	}
+	return "", false
+}
+
+// GetRequests returns the effective resource requests of the
+// pod spec of the controller, computed the way the scheduler
+// does: the sum of the regular and sidecar containers requests,
+// or the highest request of an init container, plus the sidecar
+// containers started before it, if greater. The pod overhead is
+// added to the result.
 func (tc *TargetController) GetRequests() ResourceQuantities {
-	var cpu, mem resource.Quantity
+	return tc.effectiveQuantities(func(c PodContainer) ResourceQuantities {
+		return c.Requests
+	})
+}
+
+// GetRecommendations returns the effective resource requests
+// of the pod spec of the controller if the recommendations of
+// the given VPA were applied. The init and sidecar containers
+// without a recommendation keep their current requests.
+func (tc *TargetController) GetRecommendations(vpa *vpav1.VerticalPodAutoscaler, rt RecommendationType) ResourceQuantities {
+	return tc.GetRoundedRecommendations(vpa, rt, nil)
+}
 
-	for _, ctr := range tc.podSpec.Containers {
-		requests := ctr.Resources.Requests
-		if c := requests.Cpu(); c != nil {
-			cpu.Add(*c)
+// GetRoundedRecommendations is like GetRecommendations, but the
+// recommendation of each container is rounded with the rule. A
+// nil rule leaves the recommendations unchanged.
+func (tc *TargetController) GetRoundedRecommendations(vpa *vpav1.VerticalPodAutoscaler, rt RecommendationType, rule *RoundingRule) ResourceQuantities {
+	if vpa == nil || vpa.Status.Recommendation == nil {
+		return ResourceQuantities{}
+	}
+	recs := make(map[string]ResourceQuantities, len(vpa.Status.Recommendation.ContainerRecommendations))
+	for _, cr := range vpa.Status.Recommendation.ContainerRecommendations {
+		rec := ContainerRecommendations(cr, rt)
+		if rule != nil {
+			rec = rule.Round(rec)
+		}
+		recs[cr.ContainerName] = rec
+	}
+	return tc.effectiveQuantities(func(c PodContainer) ResourceQuantities {
+		if rec, ok := recs[c.Name]; ok {
+			return rec
 		}
-		if m := requests.Memory(); m != nil {
-			mem.Add(*m)
+		if c.Type != ContainerRegular {
+			return c.Requests
+		}
+		return ResourceQuantities{}
+	})
+}
+
+func (tc *TargetController) effectiveQuantities(fn func(PodContainer) ResourceQuantities) ResourceQuantities {
+	containers := tc.Containers()
+
+	names := map[corev1.ResourceName]bool{
+		corev1.ResourceCPU:    true,
+		corev1.ResourceMemory: true,
+	}
+	for _, c := range containers {
+		for name := range fn(c).Others {
+			names[name] = true
 		}
 	}
-	return ResourceQuantities{CPU: &cpu, Memory: &mem}
+	var rq ResourceQuantities
+
+	for name := range names {
+		name := name
+		q := effectiveQuantity(containers, func(c PodContainer) *resource.Quantity {
+			return fn(c).Get(name)
+		})
+		if overhead := tc.podSpec.Overhead; overhead != nil {
+			if o, ok := overhead[name]; ok {
+				q.Add(o)
+			}
+		}
+		rq.Set(name, q)
+	}
+	return rq
+}
+
+// effectiveQuantity returns the effective quantity of a resource
+// for a pod, given the quantity of each of its containers.
+// See https://kubernetes.io/docs/concepts/workloads/pods/init-containers/#resource-sharing-within-containers
+func effectiveQuantity(containers []PodContainer, fn func(PodContainer) *resource.Quantity) *resource.Quantity {
+	var sum, sidecars, maxInit resource.Quantity
+
+	for _, c := range containers {
+		q := fn(c)
+
+		switch c.Type {
+		case ContainerRegular:
+			if q != nil {
+				sum.Add(*q)
+			}
+		case ContainerSidecar:
+			// A sidecar keeps running alongside the init
+			// containers declared after it, and the regular
+			// containers of the pod.
+			if q != nil {
+				sidecars.Add(*q)
+			}
+			if sidecars.Cmp(maxInit) > 0 {
+				maxInit = sidecars.DeepCopy()
+			}
+		case ContainerInit:
+			tmp := sidecars.DeepCopy()
+			if q != nil {
+				tmp.Add(*q)
+			}
+			if tmp.Cmp(maxInit) > 0 {
+				maxInit = tmp
+			}
+		}
+	}
+	sum.Add(sidecars)
+
+	if maxInit.Cmp(sum) > 0 {
+		return &maxInit
+	}
+	return &sum
 }
 
 // resolvePodSpec returns the corev1.PodSpec field of a controller.
@@ -161,6 +345,45 @@ func resolvePodSpec(obj *unstructuredv1.Unstructured) (*corev1.PodSpec, error) {
 	return spec, nil
 }
 
+// resolveSidecarNames returns the names of the restartable init
+// containers of a controller's pod spec, also known as sidecar
+// containers. The field isn't part of the corev1.Container type
+// of the API version used by the client, so it is read from the
+// unstructured object directly.
+func resolveSidecarNames(obj *unstructuredv1.Unstructured) (map[string]bool, error) {
+	fields := []string{
+		"spec",
+		"template",
+		"spec",
+		"initContainers",
+	}
+	var err error
+	fields, err = genericControllerSpecPath(obj.GetKind(), fields)
+	if err != nil {
+		return nil, err
+	}
+	containers, ok, err := unstructuredv1.NestedSlice(obj.Object, fields...)
+	if err != nil {
+		return nil, fmt.Errorf("nested field has invalid type: %w", err)
+	}
+	names := make(map[string]bool)
+	if !ok {
+		return names, nil
+	}
+	for _, c := range containers {
+		m, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if m["restartPolicy"] == string(corev1.RestartPolicyAlways) {
+			if name, ok := m["name"].(string); ok {
+				names[name] = true
+			}
+		}
+	}
+	return names, nil
+}
+
 // resolveLabelSelector returns the metav1.LabelSelector field of a controller spec.
 func resolveLabelSelector(obj *unstructuredv1.Unstructured) (*metav1.LabelSelector, error) {
 	fields := []string{
@@ -179,6 +402,31 @@ func resolveLabelSelector(obj *unstructuredv1.Unstructured) (*metav1.LabelSelect
 	return selector, nil
 }
 
+// Selector returns the label selector used
+// to list the dependent pods of the controller.
+func (tc *TargetController) Selector() string {
+	return tc.selector
+}
+
+// Overhead returns the pod overhead of the pod spec
+// the requests of the controller are read from.
+func (tc *TargetController) Overhead() corev1.ResourceList {
+	if tc.podSpec == nil {
+		return nil
+	}
+	return tc.podSpec.Overhead
+}
+
+// Labels returns the labels of the controller.
+func (tc *TargetController) Labels() map[string]string {
+	return tc.controllerObj.GetLabels()
+}
+
+// Annotations returns the annotations of the controller.
+func (tc *TargetController) Annotations() map[string]string {
+	return tc.controllerObj.GetAnnotations()
+}
+
 // ReplicasCount returns the number of replicas of the controller.
 // It is used to scale the resource/recommendation statistics to get
 // real usage values that reflect the number of pods schedules for
@@ -0,0 +1,140 @@
+package vpa
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	unstructuredv1 "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	vpav1 "k8s.io/autoscaler/vertical-pod-autoscaler/pkg/apis/autoscaling.k8s.io/v1"
+)
+
+func newTestContainer(name, cpu, mem string) corev1.Container {
+	return corev1.Container{
+		Name: name,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(mem),
+			},
+		},
+	}
+}
+
+func TestTargetControllerEffectiveRequests(t *testing.T) {
+	tc := &TargetController{
+		podSpec: &corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				newTestContainer("migrate", "2", "256Mi"),
+				newTestContainer("proxy", "100m", "64Mi"),
+				newTestContainer("warmup", "500m", "2Gi"),
+			},
+			Containers: []corev1.Container{
+				newTestContainer("app", "500m", "512Mi"),
+				newTestContainer("logger", "50m", "32Mi"),
+			},
+			Overhead: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("10m"),
+			},
+		},
+		sidecars: map[string]bool{"proxy": true},
+	}
+	for name, want := range map[string]ContainerType{
+		"migrate": ContainerInit,
+		"proxy":   ContainerSidecar,
+		"app":     ContainerRegular,
+	} {
+		if got, ok := tc.GetContainerType(name); !ok || got != want {
+			t.Errorf("got type %q for container %s, want %q", got, name, want)
+		}
+	}
+	rqs := tc.GetRequests()
+
+	// CPU: max(500m + 50m + 100m, 2, 100m + 500m) + 10m
+	if want := resource.MustParse("2010m"); rqs.CPU.Cmp(want) != 0 {
+		t.Errorf("got cpu=%s, want %s", rqs.CPU, want.String())
+	}
+	// Memory: max(512Mi + 32Mi + 64Mi, 256Mi, 64Mi + 2Gi)
+	if want := resource.MustParse("2112Mi"); rqs.Memory.Cmp(want) != 0 {
+		t.Errorf("got mem=%s, want %s", rqs.Memory, want.String())
+	}
+	v := &vpav1.VerticalPodAutoscaler{
+		Status: vpav1.VerticalPodAutoscalerStatus{
+			Recommendation: &vpav1.RecommendedPodResources{
+				ContainerRecommendations: []vpav1.RecommendedContainerResources{
+					{
+						ContainerName: "app",
+						Target: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("250m"),
+							corev1.ResourceMemory: resource.MustParse("256Mi"),
+						},
+					},
+					{
+						ContainerName: "proxy",
+						Target: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("20m"),
+							corev1.ResourceMemory: resource.MustParse("32Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+	rcs := tc.GetRecommendations(v, RecommendationTarget)
+
+	// CPU: max(250m + 20m, 2, 20m + 500m) + 10m
+	if want := resource.MustParse("2010m"); rcs.CPU.Cmp(want) != 0 {
+		t.Errorf("got cpu=%s, want %s", rcs.CPU, want.String())
+	}
+	// Memory: max(256Mi + 32Mi, 256Mi, 32Mi + 2Gi)
+	if want := resource.MustParse("2080Mi"); rcs.Memory.Cmp(want) != 0 {
+		t.Errorf("got mem=%s, want %s", rcs.Memory, want.String())
+	}
+}
+
+func TestResolveSidecarNames(t *testing.T) {
+	obj := &unstructuredv1.Unstructured{Object: map[string]interface{}{
+		"kind": "Deployment",
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"initContainers": []interface{}{
+						map[string]interface{}{"name": "migrate"},
+						map[string]interface{}{"name": "proxy", "restartPolicy": "Always"},
+					},
+				},
+			},
+		},
+	}}
+	names, err := resolveSidecarNames(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || !names["proxy"] {
+		t.Errorf("got sidecars %v, want [proxy]", names)
+	}
+}
+
+func TestTargetControllerEffectiveOtherResources(t *testing.T) {
+	app := newTestContainer("app", "500m", "512Mi")
+	app.Resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse("1Gi")
+
+	migrate := newTestContainer("migrate", "100m", "64Mi")
+	migrate.Resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse("4Gi")
+
+	tc := &TargetController{
+		podSpec: &corev1.PodSpec{
+			InitContainers: []corev1.Container{migrate},
+			Containers:     []corev1.Container{app, newTestContainer("logger", "50m", "32Mi")},
+		},
+	}
+	rqs := tc.GetRequests()
+
+	// Ephemeral storage: max(1Gi, 4Gi)
+	if q := rqs.Get(corev1.ResourceEphemeralStorage); q == nil || q.Cmp(resource.MustParse("4Gi")) != 0 {
+		t.Errorf("got ephemeral-storage=%v, want 4Gi", q)
+	}
+	if want := resource.MustParse("550m"); rqs.CPU.Cmp(want) != 0 {
+		t.Errorf("got cpu=%s, want %s", rqs.CPU, want.String())
+	}
+}
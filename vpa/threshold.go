@@ -0,0 +1,160 @@
+package vpa
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Thresholds represents the warning and critical thresholds
+// of the percentage difference between the requests and the
+// recommendations, in absolute value.
+type Thresholds struct {
+	Warning  float64 `json:"warning"`
+	Critical float64 `json:"critical"`
+}
+
+func (t Thresholds) validate() error {
+	if t.Warning < 0 {
+		return fmt.Errorf("warning threshold must be positive")
+	}
+	if t.Critical <= t.Warning {
+		return fmt.Errorf("critical threshold must be strictly greater than warning")
+	}
+	return nil
+}
+
+// DirectionalThresholds represents the thresholds of the
+// over-provisioned requests, which are higher than the
+// recommendations, and of the under-provisioned ones.
+type DirectionalThresholds struct {
+	Over  *Thresholds `json:"over,omitempty"`
+	Under *Thresholds `json:"under,omitempty"`
+}
+
+func (dt DirectionalThresholds) get(over bool) *Thresholds {
+	if over {
+		return dt.Over
+	}
+	return dt.Under
+}
+
+func (dt DirectionalThresholds) validate() error {
+	for _, t := range []*Thresholds{dt.Over, dt.Under} {
+		if t == nil {
+			continue
+		}
+		if err := t.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ThresholdRule represents the thresholds of the differences
+// of the VPA resources it matches.
+type ThresholdRule struct {
+	// RuleMatcher selects the VPA resources
+	// matched by the rule.
+	RuleMatcher `json:",inline"`
+	// DirectionalThresholds are the thresholds of
+	// all the resources that have none of their own.
+	DirectionalThresholds `json:",inline"`
+	// Resources maps the name of a resource to its thresholds.
+	Resources map[corev1.ResourceName]DirectionalThresholds `json:"resources,omitempty"`
+
+	parent *ThresholdRule
+}
+
+// Thresholder selects the thresholds of the VPA resources
+// according to the first rule that matches them.
+type Thresholder struct {
+	Default ThresholdRule   `json:"default"`
+	Rules   []ThresholdRule `json:"rules,omitempty"`
+}
+
+// NewThresholder returns a thresholder whose default rule
+// uses the same thresholds for all the resources, in both
+// directions.
+func NewThresholder(warning, critical float64) *Thresholder {
+	return &Thresholder{
+		Default: ThresholdRule{
+			DirectionalThresholds: DirectionalThresholds{
+				Over:  &Thresholds{Warning: warning, Critical: critical},
+				Under: &Thresholds{Warning: warning, Critical: critical},
+			},
+		},
+	}
+}
+
+// Validate checks the rules of the thresholder, parses their
+// label selectors, and links them to the default rule, from
+// which they inherit the thresholds they don't set.
+func (t *Thresholder) Validate() error {
+	if t.Default.Over == nil || t.Default.Under == nil {
+		return fmt.Errorf("invalid default rule: over and under thresholds are required")
+	}
+	if err := t.Default.validate(); err != nil {
+		return fmt.Errorf("invalid default rule: %w", err)
+	}
+	for i := range t.Rules {
+		if err := t.Rules[i].validate(); err != nil {
+			return fmt.Errorf("invalid rule #%d: %w", i, err)
+		}
+		t.Rules[i].parent = &t.Default
+	}
+	return nil
+}
+
+func (tr *ThresholdRule) validate() error {
+	if err := tr.RuleMatcher.validate(); err != nil {
+		return err
+	}
+	if err := tr.DirectionalThresholds.validate(); err != nil {
+		return err
+	}
+	for name, dt := range tr.Resources {
+		if err := dt.validate(); err != nil {
+			return fmt.Errorf("resource %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// RuleFor returns the first rule that matches a VPA resource with
+// the given namespace and labels, and whose target has the given
+// kind, or the default rule. The thresholder must have been
+// validated beforehand.
+func (t *Thresholder) RuleFor(namespace string, lbls map[string]string, kind string) *ThresholdRule {
+	for i := range t.Rules {
+		if t.Rules[i].matches(namespace, lbls, kind) {
+			return &t.Rules[i]
+		}
+	}
+	return &t.Default
+}
+
+// Get returns the thresholds of the named resource for the
+// over-provisioned or under-provisioned direction. They are,
+// by order of preference, the thresholds of the resource in
+// the rule, the thresholds of the rule, and the thresholds
+// of the default rule, in the same order.
+func (tr *ThresholdRule) Get(name corev1.ResourceName, over bool) Thresholds {
+	if t := tr.Resources[name].get(over); t != nil {
+		return *t
+	}
+	if t := tr.get(over); t != nil {
+		return *t
+	}
+	if tr.parent != nil {
+		return tr.parent.Get(name, over)
+	}
+	return Thresholds{}
+}
+
+// ForDifference returns the thresholds of the named resource for
+// the direction of the difference, which is over-provisioned if
+// positive.
+func (tr *ThresholdRule) ForDifference(name corev1.ResourceName, diff float64) Thresholds {
+	return tr.Get(name, diff >= 0)
+}
@@ -0,0 +1,91 @@
+package vpa
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestThresholder(t *testing.T) {
+	th := NewThresholder(20, 50)
+	th.Default.Resources = map[corev1.ResourceName]DirectionalThresholds{
+		corev1.ResourceMemory: {Under: &Thresholds{Warning: 5, Critical: 10}},
+	}
+	th.Rules = []ThresholdRule{
+		{
+			RuleMatcher: RuleMatcher{Namespaces: []string{"batch-*"}},
+			DirectionalThresholds: DirectionalThresholds{
+				Over: &Thresholds{Warning: 100, Critical: 300},
+			},
+		},
+		{
+			RuleMatcher: RuleMatcher{
+				Selector: "tier=frontend",
+				Kinds:    []string{"deployment"},
+			},
+			Resources: map[corev1.ResourceName]DirectionalThresholds{
+				corev1.ResourceCPU: {Under: &Thresholds{Warning: 10, Critical: 15}},
+			},
+		},
+	}
+	if err := th.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	frontend := map[string]string{"tier": "frontend"}
+
+	for _, tt := range []struct {
+		namespace string
+		labels    map[string]string
+		kind      string
+		resource  corev1.ResourceName
+		over      bool
+		want      Thresholds
+	}{
+		// Default rule.
+		{"default", nil, "Deployment", corev1.ResourceCPU, true, Thresholds{20, 50}},
+		{"default", nil, "Deployment", corev1.ResourceMemory, false, Thresholds{5, 10}},
+		{"default", nil, "Deployment", corev1.ResourceMemory, true, Thresholds{20, 50}},
+		// Namespace rule, which inherits the memory thresholds.
+		{"batch-jobs", nil, "Job", corev1.ResourceCPU, true, Thresholds{100, 300}},
+		{"batch-jobs", nil, "Job", corev1.ResourceCPU, false, Thresholds{20, 50}},
+		{"batch-jobs", nil, "Job", corev1.ResourceMemory, true, Thresholds{100, 300}},
+		{"batch-jobs", nil, "Job", corev1.ResourceMemory, false, Thresholds{5, 10}},
+		// Selector and kind rule.
+		{"web", frontend, "Deployment", corev1.ResourceCPU, false, Thresholds{10, 15}},
+		{"web", frontend, "Deployment", corev1.ResourceCPU, true, Thresholds{20, 50}},
+		{"web", frontend, "StatefulSet", corev1.ResourceCPU, false, Thresholds{20, 50}},
+	} {
+		rule := th.RuleFor(tt.namespace, tt.labels, tt.kind)
+		if got := rule.Get(tt.resource, tt.over); got != tt.want {
+			t.Errorf("%s/%s %s (over: %t): got %v, want %v", tt.namespace, tt.kind, tt.resource, tt.over, got, tt.want)
+		}
+	}
+	if got := th.Default.ForDifference(corev1.ResourceMemory, -30); got != (Thresholds{5, 10}) {
+		t.Errorf("got %v, want the under thresholds", got)
+	}
+}
+
+func TestThresholderValidate(t *testing.T) {
+	for _, th := range []*Thresholder{
+		{},
+		func() *Thresholder {
+			th := NewThresholder(20, 50)
+			th.Rules = []ThresholdRule{{DirectionalThresholds: DirectionalThresholds{Over: &Thresholds{Warning: 50, Critical: 20}}}}
+			return th
+		}(),
+		func() *Thresholder {
+			th := NewThresholder(20, 50)
+			th.Rules = []ThresholdRule{{RuleMatcher: RuleMatcher{Selector: "a in (b"}}}
+			return th
+		}(),
+		func() *Thresholder {
+			th := NewThresholder(20, 50)
+			th.Rules = []ThresholdRule{{RuleMatcher: RuleMatcher{Namespaces: []string{"[a-"}}}}
+			return th
+		}(),
+	} {
+		if err := th.Validate(); err == nil {
+			t.Errorf("expected error for %+v", th)
+		}
+	}
+}